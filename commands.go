@@ -0,0 +1,336 @@
+package main
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hankmor/mymedia/tools/wechat-preview/config"
+	"github.com/hankmor/mymedia/tools/wechat-preview/epub"
+	"github.com/hankmor/mymedia/tools/wechat-preview/markdown"
+	"github.com/hankmor/mymedia/tools/wechat-preview/newsletter"
+	"github.com/hankmor/mymedia/tools/wechat-preview/profile"
+	"github.com/hankmor/mymedia/tools/wechat-preview/render"
+	"github.com/hankmor/mymedia/tools/wechat-preview/sensitive"
+	"github.com/hankmor/mymedia/tools/wechat-preview/theme"
+)
+
+// commands 子命令，例如 wechat-preview pangu posts/
+// 参数不含命令名；未匹配到子命令时启动预览服务
+var commands = map[string]func(args []string) error{
+	"pangu":     runPangu,
+	"sensitive": runSensitive,
+	"epub":      runEPUB,
+	"send":      runSend,
+}
+
+// runPangu 将中文排版修正写回源文件
+func runPangu(args []string) error {
+	flags := flag.NewFlagSet("pangu", flag.ExitOnError)
+	check := flags.Bool("check", false, "只列出需要修正的文件，不写回 (存在时返回错误)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: wechat-preview pangu [-check] <文件或目录>...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("缺少文件或目录参数")
+	}
+
+	files, err := markdownFiles(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	changed := 0
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		// Frontmatter 原样保留，设置了 pangu: false 的文章跳过
+		front, body := render.SplitFrontmatter(string(content))
+		if enabled, err := strconv.ParseBool(render.ParseFrontmatter(front)["pangu"]); err == nil && !enabled {
+			continue
+		}
+		fixed := front + string(markdown.FixPangu([]byte(body)))
+		if fixed == string(content) {
+			continue
+		}
+
+		changed++
+		if *check {
+			fmt.Printf("需要修正: %s\n", file)
+			continue
+		}
+		if err := os.WriteFile(file, []byte(fixed), 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("已修正: %s\n", file)
+	}
+
+	if *check && changed > 0 {
+		return fmt.Errorf("%d 个文件需要修正", changed)
+	}
+	fmt.Printf("检查 %d 个文件，修正 %d 个\n", len(files), changed)
+	return nil
+}
+
+// runSensitive 扫描文章中的敏感词，命中 error 级别的词时返回错误
+func runSensitive(args []string) error {
+	flags := flag.NewFlagSet("sensitive", flag.ExitOnError)
+	words := flags.String("words", strings.Join(config.AppConfig.SensitiveWords, ";"), "词表文件，多个用 ; 分隔 (默认读取 SENSITIVE_WORDS)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: wechat-preview sensitive [-words 词表] <文件或目录>...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("缺少文件或目录参数")
+	}
+
+	list, err := sensitive.LoadFiles(strings.FieldsFunc(*words, func(r rune) bool { return r == ';' })...)
+	if err != nil {
+		return err
+	}
+	s := sensitive.NewScanner(list)
+	if s.Empty() {
+		return fmt.Errorf("未配置敏感词表，请设置 SENSITIVE_WORDS 或使用 -words")
+	}
+
+	files, err := markdownFiles(flags.Args())
+	if err != nil {
+		return err
+	}
+
+	total, errors := 0, 0
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		for _, m := range s.Scan(string(content)) {
+			total++
+			if m.Severity == sensitive.Error {
+				errors++
+			}
+			fmt.Printf("%s:%d:%d: [%s] %s (%s) %s\n", file, m.Line, m.Column, m.Severity, m.Word, m.Category, m.Context)
+		}
+	}
+
+	fmt.Printf("检查 %d 个文件，命中 %d 处\n", len(files), total)
+	if errors > 0 {
+		return fmt.Errorf("%d 处 error 级别的敏感词", errors)
+	}
+	return nil
+}
+
+// runEPUB 将系列导出为 EPUB 电子书，未指定系列时导出全部系列
+func runEPUB(args []string) error {
+	flags := flag.NewFlagSet("epub", flag.ExitOnError)
+	dir := flags.String("dir", config.AppConfig.PostsDir, "文章目录 (默认读取 POSTS_DIR，未设置时为当前目录)")
+	out := flags.String("o", ".", "输出目录")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: wechat-preview epub [-dir 文章目录] [-o 输出目录] [系列]...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if err := loadArticles(*dir); err != nil {
+		return err
+	}
+
+	series := flags.Args()
+	if len(series) == 0 {
+		for _, s := range seriesStats(articles) {
+			if s.Name != "其他" { // 文章目录下直接存放的文章不属于任何系列
+				series = append(series, s.Name)
+			}
+		}
+	}
+	if err := os.MkdirAll(*out, 0o755); err != nil {
+		return err
+	}
+	for _, name := range series {
+		book, err := seriesBook(name)
+		if err != nil {
+			return err
+		}
+		file := filepath.Join(*out, name+".epub")
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		err = epub.Write(f, book)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		fmt.Printf("已生成: %s (%d 章)\n", file, len(book.Chapters))
+	}
+	return nil
+}
+
+// runSend 将文章生成为 Newsletter 邮件 (HTML 与纯文本两个版本)，发送给测试收件人或写入文件
+// 本地图片与发布时一样上传到图床，邮件中使用图床的绝对地址。
+func runSend(args []string) error {
+	flags := flag.NewFlagSet("send", flag.ExitOnError)
+	dir := flags.String("dir", config.AppConfig.PostsDir, "文章目录 (默认读取 POSTS_DIR，未设置时为当前目录)")
+	to := flags.String("to", strings.Join(config.AppConfig.NewsletterTo, ";"), "收件人，多个用 ; 分隔 (默认读取 NEWSLETTER_TO)")
+	out := flags.String("o", "", "不发送，写入文件：.html 为 HTML 版本，.txt 为纯文本版本，其他为完整邮件 (.eml)")
+	force := flags.Bool("force", false, "存在未上传到图床的图片时仍然发送")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: wechat-preview send [-dir 文章目录] [-to 收件人] [-o 文件] [-force] <文章文件或 ID>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("需要指定一篇文章")
+	}
+	recipients := strings.FieldsFunc(*to, func(r rune) bool { return r == ';' })
+	if *out == "" {
+		if len(recipients) == 0 {
+			return fmt.Errorf("未配置收件人，请设置 NEWSLETTER_TO 或使用 -to")
+		}
+		if config.AppConfig.SMTPHost == "" || config.AppConfig.SMTPFrom == "" {
+			return fmt.Errorf("未配置 SMTP 服务器，请设置 SMTP_HOST 与 SMTP_FROM")
+		}
+	}
+
+	if err := loadArticles(*dir); err != nil {
+		return err
+	}
+	article, err := findArticle(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	doc, err := renderProfile(article, render.Publish, profile.Email)
+	if err != nil {
+		return err
+	}
+	for _, e := range doc.Publish.Errors {
+		fmt.Fprintf(os.Stderr, "警告: %s\n", e)
+	}
+
+	css, err := fs.ReadFile(embedFS, "web/static/css/wechat.css")
+	if err != nil {
+		return err
+	}
+	msg, err := newsletter.Build(doc, newsletter.Options{
+		CSS:     string(css),
+		URL:     onlineURL(article),
+		Summary: cmp.Or(doc.Frontmatter["description"], doc.Frontmatter["summary"]),
+		Color:   theme.Get(config.AppConfig.Theme).Primary,
+	})
+	if err != nil {
+		return err
+	}
+	if len(msg.Local) > 0 {
+		problem := fmt.Sprintf("%d 张图片未上传到图床，邮件中无法显示: %s", len(msg.Local), strings.Join(msg.Local, ", "))
+		if *out == "" && !*force {
+			return fmt.Errorf("%s (检查 GITHUB_TOKEN，或使用 -force 仍然发送)", problem)
+		}
+		fmt.Fprintf(os.Stderr, "警告: %s\n", problem)
+	}
+
+	if *out != "" {
+		var data []byte
+		switch strings.ToLower(filepath.Ext(*out)) {
+		case ".html", ".htm":
+			data = []byte(msg.HTML)
+		case ".txt":
+			data = []byte(msg.Text)
+		default:
+			if data, err = msg.Bytes(config.AppConfig.SMTPFrom, strings.Join(recipients, ", ")); err != nil {
+				return err
+			}
+		}
+		if err := os.WriteFile(*out, data, 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("已生成: %s\n", *out)
+		return nil
+	}
+
+	server := newsletter.SMTP{
+		Host:     config.AppConfig.SMTPHost,
+		Port:     config.AppConfig.SMTPPort,
+		Username: config.AppConfig.SMTPUsername,
+		Password: config.AppConfig.SMTPPassword,
+		From:     config.AppConfig.SMTPFrom,
+	}
+	if err := server.Send(msg, recipients); err != nil {
+		return err
+	}
+	fmt.Printf("已发送「%s」到 %d 个收件人: %s\n", doc.Title, len(recipients), strings.Join(recipients, ", "))
+	return nil
+}
+
+// loadArticles 子命令中扫描文章目录并初始化渲染管线
+func loadArticles(dir string) error {
+	var err error
+	if postsDir, err = filepath.Abs(cmp.Or(dir, ".")); err != nil {
+		return err
+	}
+	if err := scanArticles(); err != nil {
+		return err
+	}
+	if projectRoot = findProjectRoot(postsDir); projectRoot == "" {
+		projectRoot = postsDir
+	}
+	initMarkdown()
+	initPipeline()
+	return nil
+}
+
+// findArticle 按文件路径或文章 ID 查找文章
+func findArticle(arg string) (*Article, error) {
+	file, err := filepath.Abs(arg)
+	if err != nil {
+		return nil, err
+	}
+	for i := range articles {
+		if articles[i].ID == arg || articles[i].Path == file {
+			return &articles[i], nil
+		}
+	}
+	return nil, fmt.Errorf("文章不存在: %s", arg)
+}
+
+// markdownFiles 展开参数中的目录，返回其中的 .md 文件
+func markdownFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		err = filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && filepath.Ext(path) == ".md" {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
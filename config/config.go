@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/joho/godotenv"
@@ -14,7 +16,33 @@ type Config struct {
 	GitHubBranch     string // default "main"
 	GitHubPathPrefix string
 	PostsDir         string
-	BaseURL          string // e.g., "https://hankmo.com"
+	BaseURL          string            // e.g., "https://hankmo.com"
+	CodeStyle        string            // Chroma 代码高亮主题, default "monokai"
+	CodeLineNumbers  bool              // 代码块是否显示行号
+	CodeCollapse     int               // 超过该行数的代码块自动折叠, 0 表示关闭
+	CacheDir         string            // 公式等生成图片的缓存目录
+	DiagramCommands  map[string]string // 图表语言 -> 本地渲染命令
+	DiagramTimeout   int               // 图表命令超时时间 (秒)
+	Theme            string            // 排版主题, default "default"
+	TOCMinLevel      int               // 目录收录的最小标题级别, default 2
+	TOCMaxLevel      int               // 目录收录的最大标题级别, default 3
+	TOCNumbering     bool              // 目录项是否编号
+	Pangu            bool              // 是否进行中文排版修正 (中英文空格、全角标点), default true
+	HeadingNumbering string            // 标题编号方案: decimal (1.1) / chinese (一、) / padded (01), 为空不编号
+	FigureNumbering  bool              // 图片说明是否添加 "图 1：" 编号
+	LinkPolicy       string            // 发布时的链接处理规则, 例如 "github.com=qrcode;*.example.com=plain"
+	SanitizeHTML     string            // 原始 HTML 清理模式: strip (默认) / warn / off
+	LintMaxImages    int               // 兼容性检查: 图片数量上限, default 50
+	LintMaxCodeLines int               // 兼容性检查: 单个代码块行数上限, default 100
+	SensitiveWords   []string          // 敏感词表文件 (.csv / .txt)
+	SMTPHost         string            // 发送 Newsletter 的 SMTP 服务器
+	SMTPPort         int               // SMTP 端口, default 587 (465 为隐式 TLS)
+	SMTPUsername     string            // SMTP 用户名, 为空时不认证
+	SMTPPassword     string            // SMTP 密码
+	SMTPFrom         string            // 发件人, 例如 "Hank <hank@example.com>"
+	NewsletterTo     []string          // Newsletter 测试收件人列表
+	CoverTemplate    string            // 封面模板 (JSON) 文件, 为空使用内置模板
+	CoverFonts       []string          // 封面字体文件, 优先于内置的 Noto Sans SC
 }
 
 var AppConfig *Config
@@ -30,7 +58,32 @@ func Load() {
 		GitHubPathPrefix: os.Getenv("GITHUB_PATH_PREFIX"),
 		PostsDir:         os.Getenv("POSTS_DIR"),
 		BaseURL:          os.Getenv("POSTS_BASE_URL"),
+		CodeStyle:        os.Getenv("CODE_STYLE"),
+		CodeLineNumbers:  parseBool(os.Getenv("CODE_LINE_NUMBERS")),
+		CodeCollapse:     parseInt(os.Getenv("CODE_COLLAPSE_LINES")),
+		CacheDir:         os.Getenv("CACHE_DIR"),
+		DiagramCommands:  parseDiagramCommands(os.Getenv("DIAGRAM_COMMANDS")),
+		DiagramTimeout:   parseInt(os.Getenv("DIAGRAM_TIMEOUT")),
+		Theme:            os.Getenv("THEME"),
+		TOCNumbering:     parseBool(os.Getenv("TOC_NUMBERING")),
+		HeadingNumbering: os.Getenv("HEADING_NUMBERING"),
+		FigureNumbering:  parseBool(os.Getenv("FIGURE_NUMBERING")),
+		LinkPolicy:       os.Getenv("LINK_POLICY"),
+		SanitizeHTML:     os.Getenv("SANITIZE_HTML"),
+		LintMaxImages:    parseInt(os.Getenv("LINT_MAX_IMAGES")),
+		LintMaxCodeLines: parseInt(os.Getenv("LINT_MAX_CODE_LINES")),
+		SensitiveWords:   parseList(os.Getenv("SENSITIVE_WORDS")),
+		SMTPHost:         os.Getenv("SMTP_HOST"),
+		SMTPPort:         parseInt(os.Getenv("SMTP_PORT")),
+		SMTPUsername:     os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:     os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:         os.Getenv("SMTP_FROM"),
+		NewsletterTo:     parseList(os.Getenv("NEWSLETTER_TO")),
+		CoverTemplate:    os.Getenv("COVER_TEMPLATE"),
+		CoverFonts:       parseList(os.Getenv("COVER_FONT")),
 	}
+	AppConfig.TOCMinLevel, AppConfig.TOCMaxLevel = parseLevels(os.Getenv("TOC_LEVELS"), 2, 3)
+	AppConfig.Pangu = os.Getenv("PANGU") == "" || parseBool(os.Getenv("PANGU"))
 
 	// 自动去除 .git 后缀
 	if before, ok := strings.CutSuffix(AppConfig.GitHubRepo, ".git"); ok {
@@ -41,7 +94,109 @@ func Load() {
 		AppConfig.GitHubBranch = "main"
 	}
 
+	if AppConfig.CodeStyle == "" {
+		AppConfig.CodeStyle = "monokai"
+	}
+
+	if AppConfig.CacheDir == "" {
+		AppConfig.CacheDir = defaultCacheDir()
+	}
+
+	if AppConfig.DiagramTimeout <= 0 {
+		AppConfig.DiagramTimeout = 30
+	}
+
+	if AppConfig.LintMaxImages <= 0 {
+		AppConfig.LintMaxImages = 50
+	}
+
+	if AppConfig.LintMaxCodeLines <= 0 {
+		AppConfig.LintMaxCodeLines = 100
+	}
+
+	if AppConfig.SMTPPort <= 0 {
+		AppConfig.SMTPPort = 587
+	}
+
+	if AppConfig.Theme == "" {
+		AppConfig.Theme = "default"
+	}
+
 	if AppConfig.GitHubToken == "" {
 		log.Println("⚠️  Warning: GITHUB_TOKEN not found. Upload feature will be disabled.")
 	}
 }
+
+// parseBool 解析布尔型环境变量，无法解析时视为 false
+func parseBool(s string) bool {
+	v, _ := strconv.ParseBool(strings.TrimSpace(s))
+	return v
+}
+
+// parseInt 解析整型环境变量，无法解析时视为 0
+func parseInt(s string) int {
+	v, _ := strconv.Atoi(strings.TrimSpace(s))
+	return v
+}
+
+// parseList 解析以 ; 分隔的列表，忽略空项
+func parseList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ";") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseLevels 解析 "2-4" 形式的标题级别区间，格式错误时使用默认值
+func parseLevels(s string, defMin, defMax int) (int, int) {
+	from, to, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		to = from
+	}
+	minLevel, err1 := strconv.Atoi(strings.TrimSpace(from))
+	maxLevel, err2 := strconv.Atoi(strings.TrimSpace(to))
+	if err1 != nil || err2 != nil || minLevel < 1 || maxLevel > 6 || minLevel > maxLevel {
+		return defMin, defMax
+	}
+	return minLevel, maxLevel
+}
+
+// defaultCacheDir 默认缓存目录：用户缓存目录下的 wechat-preview
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "wechat-preview")
+}
+
+// defaultDiagramCommands 默认图表命令，命令需从 stdin 读取源码并向 stdout 输出图片
+var defaultDiagramCommands = map[string]string{
+	"mermaid":  "mmdc -i - -o - -e png -b white",
+	"plantuml": "plantuml -tpng -pipe",
+	"dot":      "dot -Tpng",
+}
+
+// parseDiagramCommands 解析 "mermaid=mmdc ...;dot=dot -Tpng" 形式的配置，未配置的语言使用默认命令
+func parseDiagramCommands(s string) map[string]string {
+	commands := make(map[string]string, len(defaultDiagramCommands))
+	for lang, cmd := range defaultDiagramCommands {
+		commands[lang] = cmd
+	}
+	for _, item := range strings.Split(s, ";") {
+		lang, cmd, ok := strings.Cut(item, "=")
+		lang = strings.TrimSpace(lang)
+		if !ok || lang == "" {
+			continue
+		}
+		if cmd = strings.TrimSpace(cmd); cmd == "" {
+			delete(commands, lang) // "lang=" 表示禁用
+			continue
+		}
+		commands[lang] = cmd
+	}
+	return commands
+}
@@ -0,0 +1,399 @@
+// Package cover 根据文章标题与系列名生成封面图片
+// 公众号图文需要 900×383 的封面与 1:1 的缩略图，按模板绘制背景 (纯色、渐变或图片)、系列名、标题与 Logo，
+// 标题按宽度自动换行并缩小字号，中文逐字换行、西文按单词换行，句末标点不出现在行首。
+// frontmatter 指定了封面图片时按焦点 (cover_focus) 裁剪为同样的尺寸。
+package cover
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	_ "golang.org/x/image/webp"
+
+	"github.com/hankmor/mymedia/tools/wechat-preview/markdown"
+)
+
+// Size 封面尺寸
+type Size struct {
+	Name   string `json:"name"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+var (
+	Wide   = Size{Name: "wide", Width: 900, Height: 383}   // 图文封面，消息列表中显示为 2.35:1
+	Square = Size{Name: "square", Width: 500, Height: 500} // 1:1 缩略图，用于分享到聊天与次条图文
+	Sizes  = []Size{Wide, Square}
+)
+
+// ParseSize 按名称获取尺寸，空名称为 wide
+func ParseSize(name string) (Size, error) {
+	if name == "" {
+		return Wide, nil
+	}
+	for _, s := range Sizes {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return Size{}, fmt.Errorf("未知的封面尺寸: %s (可选 wide、square)", name)
+}
+
+// Info 封面上的文字
+type Info struct {
+	Title  string
+	Series string // 系列名，为空时不显示
+}
+
+// layoutVersion 排版方式变化时修改，使缓存的封面失效
+const layoutVersion = "1"
+
+// Generator 封面生成器，模板中的颜色与图片在创建时解析
+type Generator struct {
+	fonts                 *Fonts
+	background            []color.NRGBA
+	angle                 float64
+	overlay               color.NRGBA
+	title, series, accent color.NRGBA
+	image, logo           image.Image
+	key                   string
+	err                   error // 不可用的原因，见 Unavailable
+}
+
+// New 创建生成器，模板中的颜色无效或图片无法读取时返回错误
+func New(t Template, fonts *Fonts) (*Generator, error) {
+	g := &Generator{fonts: fonts, angle: t.Angle}
+	for _, c := range t.Background {
+		bg, err := parseColor(c)
+		if err != nil {
+			return nil, err
+		}
+		g.background = append(g.background, bg)
+	}
+	if len(g.background) == 0 {
+		return nil, fmt.Errorf("封面模板缺少背景颜色")
+	}
+	colors := []struct {
+		dst *color.NRGBA
+		s   string
+	}{{&g.title, t.Title}, {&g.series, t.Series}, {&g.accent, t.Accent}, {&g.overlay, t.Overlay}}
+	for _, c := range colors {
+		if c.s == "" {
+			continue
+		}
+		v, err := parseColor(c.s)
+		if err != nil {
+			return nil, err
+		}
+		*c.dst = v
+	}
+	var err error
+	if g.image, err = loadImage(t.Image); err != nil {
+		return nil, err
+	}
+	if g.logo, err = loadImage(t.Logo); err != nil {
+		return nil, err
+	}
+
+	// 缓存键：模板内容 (含图片文件的修改时间)、字体与排版版本
+	data, _ := json.Marshal(t)
+	h := sha1.New()
+	h.Write(data)
+	for _, file := range []string{t.Image, t.Logo} {
+		if info, err := os.Stat(file); err == nil {
+			fmt.Fprint(h, info.ModTime().UnixNano())
+		}
+	}
+	fmt.Fprint(h, fonts.Names(), layoutVersion)
+	g.key = hex.EncodeToString(h.Sum(nil))
+	return g, nil
+}
+
+// Unavailable 无法生成封面时使用的生成器 (例如字体加载失败)，生成时返回 err
+// 按 frontmatter 封面图片裁剪不受影响。
+func Unavailable(err error) *Generator {
+	return &Generator{err: fmt.Errorf("封面生成不可用: %w", err)}
+}
+
+func loadImage(file string) (image.Image, error) {
+	if file == "" {
+		return nil, nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return img, nil
+}
+
+// Save 生成封面写入资源缓存并返回相对路径，相同的文字、尺寸与模板只生成一次
+func (g *Generator) Save(store *markdown.AssetStore, info Info, size Size) (string, error) {
+	if g.err != nil {
+		return "", g.err
+	}
+	name := store.Name("cover", strings.Join([]string{g.key, info.Title, info.Series, size.Name}, "\x00"), ".png")
+	if store.Exists(name) {
+		return name, nil
+	}
+	data, err := g.PNG(info, size)
+	if err != nil {
+		return "", err
+	}
+	return name, store.Save(name, data)
+}
+
+// PNG 生成 PNG 格式的封面
+func (g *Generator) PNG(info Info, size Size) ([]byte, error) {
+	img, err := g.Render(info, size)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Render 绘制封面
+func (g *Generator) Render(info Info, size Size) (*image.RGBA, error) {
+	if g.err != nil {
+		return nil, g.err
+	}
+	if missing := g.fonts.Missing(info.Title + info.Series); len(missing) > 0 {
+		return nil, fmt.Errorf("字体缺少字符 %q，请在 cover 目录执行 go generate 打包内置中文字体后重新编译，或通过 COVER_FONT 指定字体", string(missing))
+	}
+
+	w, h := size.Width, size.Height
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	g.drawBackground(img)
+
+	// 尺寸按短边计算，两种比例的留白与字号一致
+	unit := float64(min(w, h))
+	pad := unit * 0.125
+	top, bottom := pad, float64(h)-pad
+
+	if info.Series != "" {
+		fontSize := unit * 0.065
+		faces, err := g.fonts.faces(fontSize)
+		if err != nil {
+			return nil, err
+		}
+		bar := unit * 0.014
+		fill(img, image.Rect(int(pad), int(pad), int(pad+bar), int(pad+fontSize)), g.accent)
+		baseline := pad + centerBaseline(faces[0], fontSize)
+		g.drawText(img, faces, info.Series, fixed.Int26_6((pad+bar+unit*0.03)*64), fixed.Int26_6(baseline*64), g.series)
+		top += fontSize * 1.8
+	}
+
+	if g.logo != nil {
+		b := g.logo.Bounds()
+		lh := unit * 0.12
+		lw := lh * float64(b.Dx()) / float64(b.Dy())
+		if maxWidth := float64(w) * 0.3; lw > maxWidth {
+			lw, lh = maxWidth, maxWidth*float64(b.Dy())/float64(b.Dx())
+		}
+		dst := image.Rect(int(float64(w)-pad-lw), int(float64(h)-pad-lh), int(float64(w)-pad), int(float64(h)-pad))
+		xdraw.CatmullRom.Scale(img, dst, g.logo, b, draw.Over, nil)
+		bottom -= lh + unit*0.04
+	}
+
+	// 标题：从最大字号开始缩小，直到行数与高度都放得下，最小字号仍放不下时截断并加省略号
+	maxLines := 3
+	if h >= w {
+		maxLines = 4
+	}
+	width := fixed.Int26_6((float64(w) - 2*pad) * 64)
+	var lines []string
+	var faces []font.Face
+	var fontSize float64
+	for fontSize = unit * 0.15; ; fontSize-- {
+		var err error
+		if faces, err = g.fonts.faces(fontSize); err != nil {
+			return nil, err
+		}
+		lines = g.wrap(faces, info.Title, width)
+		fit := min(maxLines, int((bottom-top)/(fontSize*1.35)))
+		if len(lines) <= fit {
+			break
+		}
+		if fontSize <= unit*0.075 {
+			lines = g.truncate(faces, lines[:max(fit, 1)], width)
+			break
+		}
+	}
+	lineHeight := fontSize * 1.35
+	y := top + (bottom-top-lineHeight*float64(len(lines)))/2
+	offset := centerBaseline(faces[0], lineHeight)
+	for i, line := range lines {
+		baseline := y + float64(i)*lineHeight + offset
+		g.drawText(img, faces, line, fixed.Int26_6(pad*64), fixed.Int26_6(baseline*64), g.title)
+	}
+	return img, nil
+}
+
+// drawBackground 背景图片 (可叠加遮罩) 或纯色、渐变
+func (g *Generator) drawBackground(img *image.RGBA) {
+	b := img.Bounds()
+	if g.image != nil {
+		// 等比缩放后居中裁剪，铺满画布
+		crop := CropRect(g.image.Bounds(), Size{Width: b.Dx(), Height: b.Dy()}, Center)
+		xdraw.CatmullRom.Scale(img, b, g.image, crop, draw.Src, nil)
+		if g.overlay.A > 0 {
+			draw.Draw(img, b, image.NewUniform(g.overlay), image.Point{}, draw.Over)
+		}
+		return
+	}
+	if len(g.background) == 1 {
+		fill(img, b, g.background[0])
+		return
+	}
+
+	// 线性渐变：像素在渐变方向上的投影归一化到 [0, 1]
+	rad := g.angle * math.Pi / 180
+	dx, dy := math.Cos(rad), math.Sin(rad)
+	var lo, hi float64 = math.Inf(1), math.Inf(-1)
+	for _, p := range [][2]float64{{0, 0}, {float64(b.Dx()), 0}, {0, float64(b.Dy())}, {float64(b.Dx()), float64(b.Dy())}} {
+		v := p[0]*dx + p[1]*dy
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	stops := len(g.background) - 1
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			t := ((float64(x)+0.5)*dx + (float64(y)+0.5)*dy - lo) / (hi - lo)
+			i := min(int(t*float64(stops)), stops-1)
+			img.Set(x, y, mix(g.background[i], g.background[i+1], t*float64(stops)-float64(i)))
+		}
+	}
+}
+
+// centerBaseline 文字在高度为 height 的行内垂直居中时，基线到行顶部的距离
+func centerBaseline(face font.Face, height float64) float64 {
+	m := face.Metrics()
+	return (height + float64(m.Ascent-m.Descent)/64) / 2
+}
+
+func fill(img *image.RGBA, r image.Rectangle, c color.NRGBA) {
+	draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Over)
+}
+
+// mix 按比例 t 混合两种颜色
+func mix(a, b color.NRGBA, t float64) color.NRGBA {
+	t = math.Max(0, math.Min(1, t))
+	lerp := func(x, y uint8) uint8 { return uint8(math.Round(float64(x) + (float64(y)-float64(x))*t)) }
+	return color.NRGBA{R: lerp(a.R, b.R), G: lerp(a.G, b.G), B: lerp(a.B, b.B), A: lerp(a.A, b.A)}
+}
+
+// face 绘制字符 r 使用的字体
+func (g *Generator) face(faces []font.Face, r rune) font.Face {
+	if i := g.fonts.index(r); i >= 0 {
+		return faces[i]
+	}
+	return faces[len(faces)-1]
+}
+
+// measure 文字宽度
+func (g *Generator) measure(faces []font.Face, s string) fixed.Int26_6 {
+	var w fixed.Int26_6
+	for _, r := range s {
+		adv, _ := g.face(faces, r).GlyphAdvance(r)
+		w += adv
+	}
+	return w
+}
+
+// drawText 从基线位置 (x, y) 开始逐字绘制，每个字符使用包含它的第一个字体
+func (g *Generator) drawText(img *image.RGBA, faces []font.Face, s string, x, y fixed.Int26_6, c color.NRGBA) {
+	d := &font.Drawer{Dst: img, Src: image.NewUniform(c), Dot: fixed.Point26_6{X: x, Y: y}}
+	for _, r := range s {
+		d.Face = g.face(faces, r)
+		d.DrawString(string(r))
+	}
+}
+
+// noLineStart 不能出现在行首的标点
+const noLineStart = "，。、；：？！）》」』】〉,.;:?!)]}%…—”’"
+
+// wrap 按宽度换行：连续的字母与数字作为一个整体，其他字符逐个换行，过长的单词按字符拆开
+func (g *Generator) wrap(faces []font.Face, text string, width fixed.Int26_6) []string {
+	var tokens []string
+	for _, t := range tokenize(text) {
+		if g.measure(faces, t) > width {
+			for _, r := range t {
+				tokens = append(tokens, string(r))
+			}
+			continue
+		}
+		tokens = append(tokens, t)
+	}
+
+	var lines []string
+	line := ""
+	for _, t := range tokens {
+		if line == "" && t == " " {
+			continue
+		}
+		if line != "" && g.measure(faces, strings.TrimRight(line+t, " ")) > width && !strings.Contains(noLineStart, t) {
+			lines = append(lines, strings.TrimRight(line, " "))
+			line = strings.TrimLeft(t, " ")
+			continue
+		}
+		line += t
+	}
+	if line = strings.TrimRight(line, " "); line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// truncate 最后一行末尾加省略号，超出宽度时去掉末尾的字符
+func (g *Generator) truncate(faces []font.Face, lines []string, width fixed.Int26_6) []string {
+	last := []rune(lines[len(lines)-1])
+	for len(last) > 0 && g.measure(faces, string(last)+"…") > width {
+		last = last[:len(last)-1]
+	}
+	lines[len(lines)-1] = strings.TrimRight(string(last), " ") + "…"
+	return lines
+}
+
+// tokenize 切分为换行单位：连续的字母、数字与连接符为一个单位，其余每个字符为一个单位
+func tokenize(text string) []string {
+	var tokens []string
+	word := ""
+	for _, r := range strings.Join(strings.Fields(text), " ") {
+		if r < 0x2e80 && (unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_'.+#/", r)) {
+			word += string(r)
+			continue
+		}
+		if word != "" {
+			tokens = append(tokens, word)
+			word = ""
+		}
+		tokens = append(tokens, string(r))
+	}
+	if word != "" {
+		tokens = append(tokens, word)
+	}
+	return tokens
+}
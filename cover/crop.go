@@ -0,0 +1,150 @@
+package cover
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+
+	"github.com/hankmor/mymedia/tools/wechat-preview/markdown"
+)
+
+// Focus 裁剪时保留的焦点，按图片宽高的比例表示，(0, 0) 为左上角
+// 对应 frontmatter cover_focus: 0.3,0.6，裁剪区域尽量以焦点为中心。
+type Focus struct {
+	X, Y float64
+}
+
+// Center 默认焦点：图片中心
+var Center = Focus{X: 0.5, Y: 0.5}
+
+// ParseFocus 解析 "0.3,0.6" 形式的焦点，空字符串为中心
+func ParseFocus(s string) (Focus, error) {
+	if strings.TrimSpace(s) == "" {
+		return Center, nil
+	}
+	xs, ys, ok := strings.Cut(s, ",")
+	x, err1 := strconv.ParseFloat(strings.TrimSpace(xs), 64)
+	y, err2 := strconv.ParseFloat(strings.TrimSpace(ys), 64)
+	if !ok || err1 != nil || err2 != nil || x < 0 || x > 1 || y < 0 || y > 1 {
+		return Center, fmt.Errorf("无效的焦点 %q，应为 0 到 1 之间的 x,y，例如 0.3,0.6", s)
+	}
+	return Focus{X: x, Y: y}, nil
+}
+
+// String 格式化为 "x,y"
+func (f Focus) String() string {
+	return strconv.FormatFloat(f.X, 'f', -1, 64) + "," + strconv.FormatFloat(f.Y, 'f', -1, 64)
+}
+
+// CropRect 图片 b 中宽高比为 size 的最大区域，中心尽量落在焦点上，超出图片时向内平移
+func CropRect(b image.Rectangle, size Size, f Focus) image.Rectangle {
+	w, h := b.Dx(), b.Dy()
+	if w*size.Height > h*size.Width {
+		w = h * size.Width / size.Height // 图片更宽，裁掉左右
+	} else {
+		h = w * size.Height / size.Width // 图片更高，裁掉上下
+	}
+	x := int(f.X*float64(b.Dx())) - w/2
+	y := int(f.Y*float64(b.Dy())) - h/2
+	x = max(0, min(x, b.Dx()-w))
+	y = max(0, min(y, b.Dy()-h))
+	return image.Rect(0, 0, w, h).Add(b.Min).Add(image.Pt(x, y))
+}
+
+// Crop 按焦点裁剪图片并缩放到 size
+func Crop(img image.Image, size Size, f Focus) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, size.Width, size.Height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, CropRect(img.Bounds(), size, f), draw.Src, nil)
+	return dst
+}
+
+// SaveCrop 按焦点裁剪本地封面图片写入资源缓存并返回相对路径，图片未修改时只裁剪一次
+func SaveCrop(store *markdown.AssetStore, file string, size Size, f Focus) (string, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return "", err
+	}
+	key := strings.Join([]string{file, strconv.FormatInt(info.ModTime().UnixNano(), 10), size.Name, f.String()}, "\x00")
+	name := store.Name("cover", key, ".png")
+	if store.Exists(name) {
+		return name, nil
+	}
+	img, err := loadImage(file)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, Crop(img, size, f)); err != nil {
+		return "", err
+	}
+	return name, store.Save(name, buf.Bytes())
+}
+
+// Resolve 解析 frontmatter 中的本地封面图片：以 / 开头时相对 root 下的 static 目录或 root，否则相对 dir
+// 网络图片或文件不存在时返回错误。
+func Resolve(dir, root, src string) (string, error) {
+	if strings.HasPrefix(src, "http") || strings.HasPrefix(src, "//") {
+		return "", fmt.Errorf("封面为网络图片: %s", src)
+	}
+	candidates := []string{filepath.Join(dir, filepath.FromSlash(src))}
+	if strings.HasPrefix(src, "/") {
+		candidates = []string{
+			filepath.Join(root, "static", filepath.FromSlash(src)),
+			filepath.Join(root, filepath.FromSlash(src)),
+		}
+	}
+	for _, file := range candidates {
+		if _, err := os.Stat(file); err == nil {
+			return file, nil
+		}
+	}
+	return "", fmt.Errorf("找不到封面图片: %s", src)
+}
+
+// Source 文章的封面来源
+type Source struct {
+	Image  string // frontmatter cover 指定的本地图片，为空时由标题与系列名生成
+	Remote string // frontmatter cover 为网络图片时的地址，不做裁剪
+	Focus  Focus  // frontmatter cover_focus，裁剪本地图片时使用
+	Info   Info
+}
+
+// FromFrontmatter 按 frontmatter 的 cover、cover_focus 确定封面来源，dir 为文章所在目录，root 为项目根目录
+// 封面图片不存在或焦点格式错误时返回错误。
+func FromFrontmatter(front map[string]string, dir, root string, info Info) (Source, error) {
+	s := Source{Focus: Center, Info: info}
+	src := front["cover"]
+	if src == "" {
+		return s, nil
+	}
+	focus, err := ParseFocus(front["cover_focus"])
+	if err != nil {
+		return s, err
+	}
+	s.Focus = focus
+	if strings.HasPrefix(src, "http") || strings.HasPrefix(src, "//") {
+		s.Remote = src
+		return s, nil
+	}
+	s.Image, err = Resolve(dir, root, src)
+	return s, err
+}
+
+// Save 生成指定尺寸的封面写入资源缓存并返回相对路径：裁剪本地封面图片或由标题生成
+func (s Source) Save(g *Generator, store *markdown.AssetStore, size Size) (string, error) {
+	switch {
+	case s.Image != "":
+		return SaveCrop(store, s.Image, size, s.Focus)
+	case s.Remote != "":
+		return "", fmt.Errorf("封面为网络图片，无法裁剪: %s", s.Remote)
+	}
+	return g.Save(store, s.Info, size)
+}
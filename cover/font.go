@@ -0,0 +1,119 @@
+package cover
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"unicode"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+)
+
+//go:generate go run fonts/fetch.go
+
+// bundled 内置字体目录，默认为 Noto Sans SC Bold，见 fonts/README.md
+//
+//go:embed fonts
+var bundled embed.FS
+
+// Fonts 按顺序查找字形的字体列表，前面的字体缺少某个字符时使用后面的字体
+// 顺序为 COVER_FONT 指定的字体、内置中文字体、内置 Go Bold (只包含西文字符)，封面效果不依赖系统安装的字体。
+type Fonts struct {
+	fonts []*opentype.Font
+	names []string
+}
+
+// LoadFonts 加载字体文件 (TTF、OTF 或 TTC，TTC 取第一个字体)，其后为内置字体
+func LoadFonts(files ...string) (*Fonts, error) {
+	f := &Fonts{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err == nil {
+			err = f.add(file, data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("加载字体 %s 失败: %w", file, err)
+		}
+	}
+	entries, _ := fs.ReadDir(bundled, "fonts")
+	for _, e := range entries {
+		switch strings.ToLower(path.Ext(e.Name())) {
+		case ".otf", ".ttf", ".ttc":
+		default:
+			continue
+		}
+		data, _ := bundled.ReadFile(path.Join("fonts", e.Name()))
+		if err := f.add(e.Name(), data); err != nil {
+			return nil, fmt.Errorf("加载内置字体 %s 失败: %w", e.Name(), err)
+		}
+	}
+	if err := f.add("Go Bold", gobold.TTF); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *Fonts) add(name string, data []byte) error {
+	c, err := opentype.ParseCollection(data)
+	if err != nil {
+		return err
+	}
+	ft, err := c.Font(0)
+	if err != nil {
+		return err
+	}
+	f.fonts = append(f.fonts, ft)
+	f.names = append(f.names, name)
+	return nil
+}
+
+// Names 使用的字体：字体文件路径或内置字体的名称
+func (f *Fonts) Names() []string {
+	return f.names
+}
+
+// index 包含字符 r 的第一个字体，都不包含时返回 -1
+func (f *Fonts) index(r rune) int {
+	var buf sfnt.Buffer
+	for i, ft := range f.fonts {
+		if g, err := ft.GlyphIndex(&buf, r); err == nil && g != 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// Missing 所有字体都不包含的字符 (不含空白)
+func (f *Fonts) Missing(text string) []rune {
+	var missing []rune
+	seen := make(map[rune]bool)
+	for _, r := range text {
+		if unicode.IsSpace(r) || seen[r] {
+			continue
+		}
+		seen[r] = true
+		if f.index(r) < 0 {
+			missing = append(missing, r)
+		}
+	}
+	return missing
+}
+
+// faces 指定字号的各字体
+func (f *Fonts) faces(size float64) ([]font.Face, error) {
+	faces := make([]font.Face, len(f.fonts))
+	for i, ft := range f.fonts {
+		face, err := opentype.NewFace(ft, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingNone})
+		if err != nil {
+			return nil, err
+		}
+		faces[i] = face
+	}
+	return faces, nil
+}
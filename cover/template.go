@@ -0,0 +1,70 @@
+package cover
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Template 封面模板：背景 (纯色、渐变或图片)、文字颜色与 Logo
+// 可从 JSON 文件加载，例如：
+//
+//	{"background": ["#0f2027", "#2c5364"], "angle": 30, "logo": "logo.png"}
+type Template struct {
+	Background []string `json:"background"` // 背景颜色，两个及以上时为均匀分布的线性渐变
+	Angle      float64  `json:"angle"`      // 渐变方向 (度)，0 为从左到右，90 为从上到下
+	Image      string   `json:"image"`      // 背景图片，等比缩放并居中裁剪铺满画布
+	Overlay    string   `json:"overlay"`    // 叠加在背景图片上的半透明颜色，保证文字清晰，例如 #00000080
+	Title      string   `json:"title"`      // 标题颜色
+	Series     string   `json:"series"`     // 系列名颜色
+	Accent     string   `json:"accent"`     // 系列名前装饰条的颜色，为空时使用主题主色
+	Logo       string   `json:"logo"`       // Logo 图片，绘制在右下角
+}
+
+// Default 默认模板：深色渐变背景、白色标题
+var Default = Template{
+	Background: []string{"#0f2027", "#203a43", "#2c5364"},
+	Angle:      30,
+	Title:      "#ffffff",
+	Series:     "#d0dde6",
+}
+
+// LoadTemplate 从 JSON 文件加载模板，未设置的字段使用默认模板的值
+// 背景图片与 Logo 的相对路径相对模板文件所在目录
+func LoadTemplate(file string) (Template, error) {
+	t := Default
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return t, err
+	}
+	if err := json.Unmarshal(data, &t); err != nil {
+		return t, fmt.Errorf("%s: %w", file, err)
+	}
+	dir := filepath.Dir(file)
+	for _, p := range []*string{&t.Image, &t.Logo} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(dir, *p)
+		}
+	}
+	return t, nil
+}
+
+// parseColor 解析 #rgb、#rrggbb 与带透明度的 #rrggbbaa
+func parseColor(s string) (color.NRGBA, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 8 || err != nil {
+		return color.NRGBA{}, fmt.Errorf("无效的颜色: %q", s)
+	}
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
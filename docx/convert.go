@@ -0,0 +1,549 @@
+package docx
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// blockAtoms 按块级处理的元素，其余元素按行内处理
+var blockAtoms = map[atom.Atom]bool{
+	atom.P: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+	atom.Table: true, atom.Blockquote: true, atom.Pre: true, atom.Hr: true,
+	atom.Div: true, atom.Section: true, atom.Article: true, atom.Aside: true, atom.Header: true, atom.Footer: true,
+	atom.Nav: true, atom.Figure: true, atom.Figcaption: true, atom.Details: true, atom.Summary: true,
+}
+
+var (
+	reTextAlign = regexp.MustCompile(`text-align:\s*(center|right)`)
+	reBold      = regexp.MustCompile(`font-weight:\s*(bold|[6-9]00)`)
+	reNoBullet  = regexp.MustCompile(`list-style:\s*none`)
+)
+
+// context 块级元素的排版上下文
+type context struct {
+	style string    // 段落样式，为空时为正文 (引用块中为 Quote)
+	quote bool      // 位于引用块或提示框中
+	level int       // 列表层级，-1 表示不在列表中
+	item  *listItem // 当前列表项
+	align string    // 段落对齐
+	bold  bool      // 表头单元格
+}
+
+// listItem 列表项，只有第一个段落带编号，其余段落缩进对齐
+type listItem struct {
+	numID    int
+	numbered bool
+}
+
+// paragraph 正在输出的段落
+type paragraph struct {
+	ctx   context
+	runs  strings.Builder
+	space bool // 末尾是空白或段首，用于合并空白
+	empty bool
+}
+
+// run 行内格式
+type run struct {
+	bold, italic, strike, underline bool
+	code, link, sup, sub            bool
+}
+
+func (r run) props() string {
+	var b strings.Builder
+	switch {
+	case r.link:
+		b.WriteString(`<w:rStyle w:val="Hyperlink"/>`)
+	case r.code:
+		b.WriteString(`<w:rStyle w:val="InlineCode"/>`)
+	}
+	if r.bold {
+		b.WriteString(`<w:b/>`)
+	}
+	if r.italic {
+		b.WriteString(`<w:i/>`)
+	}
+	if r.strike {
+		b.WriteString(`<w:strike/>`)
+	}
+	if r.underline && !r.link {
+		b.WriteString(`<w:u w:val="single"/>`)
+	}
+	switch {
+	case r.sup:
+		b.WriteString(`<w:vertAlign w:val="superscript"/>`)
+	case r.sub:
+		b.WriteString(`<w:vertAlign w:val="subscript"/>`)
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "<w:rPr>" + b.String() + "</w:rPr>"
+}
+
+// blocks 输出元素的子节点：块级元素单独成段，相邻的行内内容合并为一个段落
+func (c *converter) blocks(n *html.Node, ctx context) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && blockAtoms[child.DataAtom] {
+			c.flush()
+			c.block(child, ctx)
+			continue
+		}
+		if child.Type == html.TextNode && c.p == nil && strings.TrimSpace(child.Data) == "" {
+			continue
+		}
+		c.inline(child, ctx, run{bold: ctx.bold})
+	}
+	c.flush()
+}
+
+func (c *converter) block(n *html.Node, ctx context) {
+	if align := reTextAlign.FindStringSubmatch(attr(n, "style")); align != nil {
+		ctx.align = align[1]
+	} else if align := attr(n, "align"); align == "center" || align == "right" {
+		ctx.align = align
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		ctx.style = "Heading" + n.Data[1:]
+		c.blocks(n, ctx)
+	case atom.P:
+		if hasClass(n, "callout-title") || reBold.MatchString(attr(n, "style")) {
+			ctx.bold = true
+		}
+		c.blocks(n, ctx)
+	case atom.Blockquote:
+		ctx.quote = true
+		c.blocks(n, ctx)
+	case atom.Ul, atom.Ol:
+		c.list(n, ctx)
+	case atom.Table:
+		c.table(n, ctx)
+	case atom.Pre:
+		c.code(n)
+	case atom.Hr:
+		c.out.WriteString(`<w:p><w:pPr><w:pBdr><w:bottom w:val="single" w:sz="6" w:space="1" w:color="E0E0E0"/></w:pBdr></w:pPr></w:p>`)
+	case atom.Figure:
+		ctx.align = "center"
+		c.blocks(n, ctx)
+	case atom.Figcaption:
+		ctx.style = "Caption"
+		c.blocks(n, ctx)
+	case atom.Dt:
+		ctx.bold = true
+		c.blocks(n, ctx)
+	default:
+		switch {
+		case hasClass(n, "code-block"):
+			c.code(n)
+		case hasClass(n, "callout"):
+			ctx.quote = true
+			c.blocks(n, ctx)
+		default:
+			c.blocks(n, ctx)
+		}
+	}
+}
+
+// list 输出列表，每个列表使用独立的编号实例，有序列表从 start 开始
+func (c *converter) list(n *html.Node, ctx context) {
+	if reNoBullet.MatchString(attr(n, "style")) { // 脚注等自带序号的列表
+		ctx.item = nil
+		c.blocks(n, ctx)
+		return
+	}
+	ctx.level = min(ctx.level+1, maxListLevel)
+	numID := c.numbering(n.DataAtom == atom.Ol, ctx.level, atoi(attr(n, "start"), 1))
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode {
+			continue
+		}
+		item := ctx
+		item.item = &listItem{numID: numID}
+		if li.DataAtom == atom.Li {
+			c.blocks(li, item)
+		} else {
+			c.block(li, item)
+		}
+	}
+}
+
+// paragraph 返回当前段落，没有时按上下文新建
+func (c *converter) paragraph(ctx context) *paragraph {
+	if c.p == nil {
+		c.p = &paragraph{ctx: ctx, space: true, empty: true}
+	}
+	return c.p
+}
+
+// flush 结束当前段落，没有内容的段落不输出
+// 编号在输出时才分配，避免只有空白的段落占用列表项的编号
+func (c *converter) flush() {
+	p := c.p
+	c.p = nil
+	if p == nil || p.empty {
+		return
+	}
+	ctx := p.ctx
+	var props strings.Builder
+	style := ctx.style
+	if style == "" && ctx.quote {
+		style = "Quote"
+	}
+	if style == "" && ctx.level >= 0 {
+		style = "ListParagraph"
+	}
+	if style != "" {
+		fmt.Fprintf(&props, `<w:pStyle w:val="%s"/>`, style)
+	}
+	if ctx.item != nil && !ctx.item.numbered {
+		ctx.item.numbered = true
+		fmt.Fprintf(&props, `<w:numPr><w:ilvl w:val="%d"/><w:numId w:val="%d"/></w:numPr>`, ctx.level, ctx.item.numID)
+	} else if ctx.level >= 0 {
+		fmt.Fprintf(&props, `<w:ind w:left="%d"/>`, 420*(ctx.level+1))
+	}
+	if ctx.align != "" {
+		fmt.Fprintf(&props, `<w:jc w:val="%s"/>`, ctx.align)
+	}
+
+	c.out.WriteString("<w:p>")
+	if props.Len() > 0 {
+		c.out.WriteString("<w:pPr>" + props.String() + "</w:pPr>")
+	}
+	c.out.WriteString(p.runs.String())
+	c.out.WriteString("</w:p>")
+}
+
+func (c *converter) inline(n *html.Node, ctx context, r run) {
+	switch n.Type {
+	case html.TextNode:
+		c.text(ctx, n.Data, r)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		p := c.paragraph(ctx)
+		p.runs.WriteString("<w:r><w:br/></w:r>")
+		p.space, p.empty = true, false
+		return
+	case atom.Img:
+		c.image(n, ctx, r)
+		return
+	case atom.A:
+		c.link(n, ctx, r)
+		return
+	case atom.Script, atom.Style:
+		return
+	case atom.Strong, atom.B:
+		r.bold = true
+	case atom.Em, atom.I, atom.Cite:
+		r.italic = true
+	case atom.Del, atom.S, atom.Strike:
+		r.strike = true
+	case atom.U, atom.Ins:
+		r.underline = true
+	case atom.Code, atom.Kbd, atom.Samp:
+		r.code = true
+	case atom.Sup:
+		r.sup = true
+	case atom.Sub:
+		r.sub = true
+	default:
+		if hasClass(n, "li-bold") || reBold.MatchString(attr(n, "style")) {
+			r.bold = true
+		}
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.inline(child, ctx, r)
+	}
+	if hasClass(n, "task-checkbox") { // 预览中以外边距隔开
+		c.text(ctx, " ", run{})
+	}
+}
+
+// text 输出文字，与浏览器一样将连续空白合并为一个空格，段首空白忽略
+func (c *converter) text(ctx context, s string, r run) {
+	p := c.paragraph(ctx)
+	var b strings.Builder
+	for _, ch := range s {
+		if unicode.IsSpace(ch) && ch != '\u00a0' {
+			if !p.space {
+				b.WriteByte(' ')
+				p.space = true
+			}
+			continue
+		}
+		b.WriteRune(ch)
+		p.space = false
+	}
+	if b.Len() == 0 {
+		return
+	}
+	p.empty = false
+	p.runs.WriteString("<w:r>" + r.props() + `<w:t xml:space="preserve">` + escape(b.String()) + "</w:t></w:r>")
+}
+
+// link 网络链接输出为超链接，并在脚注中写出完整地址，便于打印后查看；
+// 页内锚点与相对路径在文档中无法打开，只保留文字
+func (c *converter) link(n *html.Node, ctx context, r run) {
+	href := attr(n, "href")
+	if !strings.HasPrefix(href, "http://") && !strings.HasPrefix(href, "https://") || r.link {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			c.inline(child, ctx, r)
+		}
+		return
+	}
+	c.hyperlink(ctx, href, strings.TrimSpace(textContent(n)), func() {
+		r.link = true
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			c.inline(child, ctx, r)
+		}
+	})
+}
+
+// hyperlink 输出超链接，链接文字不是地址本身时追加脚注
+func (c *converter) hyperlink(ctx context, href, text string, body func()) {
+	p := c.paragraph(ctx)
+	p.runs.WriteString(fmt.Sprintf(`<w:hyperlink r:id="%s">`, c.relate(relHyperlink, href, true)))
+	body()
+	p = c.paragraph(ctx)
+	p.runs.WriteString("</w:hyperlink>")
+	if text != href {
+		p.runs.WriteString(fmt.Sprintf(`<w:r><w:rPr><w:rStyle w:val="FootnoteReference"/></w:rPr><w:footnoteReference w:id="%d"/></w:r>`, c.footnote(href)))
+		p.space, p.empty = false, false
+	}
+}
+
+// image 嵌入本地图片，网络图片与无法识别的图片改为文字
+func (c *converter) image(n *html.Node, ctx context, r run) {
+	src, alt := attr(n, "src"), strings.TrimSpace(attr(n, "alt"))
+	text := "[图片]"
+	if alt != "" {
+		text = "[图片: " + alt + "]"
+	}
+	file, ok := c.images[src]
+	if !ok {
+		if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") || r.link {
+			c.text(ctx, text, r)
+			return
+		}
+		log.Printf("Warning: Word 文档不嵌入网络图片，已改为链接: %s\n", src)
+		c.hyperlink(ctx, src, text, func() {
+			r.link = true
+			c.text(ctx, text, r)
+		})
+		return
+	}
+	img, err := c.embed(file)
+	if err != nil {
+		log.Printf("Warning: 无法嵌入图片 %s: %v\n", file, err)
+		c.text(ctx, text, r)
+		return
+	}
+	c.drawings++
+	p := c.paragraph(ctx)
+	p.runs.WriteString(drawing(img, c.drawings, alt))
+	p.space, p.empty = false, false
+}
+
+// code 代码块按行输出为等宽、带底色的段落，第一行之前是代码块标题 (文件名)
+func (c *converter) code(n *html.Node) {
+	if header := findClass(n, "code-header"); header != nil {
+		for span := header.FirstChild; span != nil; span = span.NextSibling {
+			if span.Type == html.ElementNode {
+				if title := strings.TrimSpace(textContent(span)); title != "" {
+					c.out.WriteString(`<w:p><w:pPr><w:pStyle w:val="CodeTitle"/></w:pPr><w:r><w:t xml:space="preserve">` + escape(title) + `</w:t></w:r></w:p>`)
+				}
+				break
+			}
+		}
+		header.Parent.RemoveChild(header)
+	}
+
+	lines := []string{""}
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			parts := strings.Split(strings.NewReplacer("\u00a0", " ", "\t", "    ", "\r", "").Replace(n.Data), "\n")
+			lines[len(lines)-1] += parts[0]
+			lines = append(lines, parts[1:]...)
+		case n.DataAtom == atom.Br:
+			lines = append(lines, "")
+		default:
+			for child := n.FirstChild; child != nil; child = child.NextSibling {
+				walk(child)
+			}
+		}
+	}
+	walk(n)
+	for len(lines) > 1 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	for i, line := range lines {
+		c.out.WriteString(`<w:p><w:pPr><w:pStyle w:val="Code"/>`)
+		if i == len(lines)-1 {
+			c.out.WriteString(`<w:spacing w:after="200"/>`)
+		}
+		c.out.WriteString(`</w:pPr><w:r><w:t xml:space="preserve">` + escape(line) + `</w:t></w:r></w:p>`)
+	}
+}
+
+// table 输出表格，列宽平均分配，表头行在跨页时重复
+func (c *converter) table(n *html.Node, ctx context) {
+	type cell struct {
+		node *html.Node
+		span int
+	}
+	var rows [][]cell
+	var header []bool
+	var collect func(*html.Node, bool)
+	collect = func(n *html.Node, head bool) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			switch child.DataAtom {
+			case atom.Thead:
+				collect(child, true)
+			case atom.Tbody, atom.Tfoot:
+				collect(child, false)
+			case atom.Tr:
+				var row []cell
+				isHead := head
+				for td := child.FirstChild; td != nil; td = td.NextSibling {
+					if td.DataAtom == atom.Td || td.DataAtom == atom.Th {
+						row = append(row, cell{td, max(atoi(attr(td, "colspan"), 1), 1)})
+					}
+				}
+				if len(row) > 0 {
+					rows = append(rows, row)
+					header = append(header, isHead)
+				}
+			}
+		}
+	}
+	collect(n, false)
+
+	cols := 0
+	for _, row := range rows {
+		width := 0
+		for _, cell := range row {
+			width += cell.span
+		}
+		cols = max(cols, width)
+	}
+	if cols == 0 {
+		return
+	}
+	colWidth := tableWidth / cols
+
+	c.out.WriteString(`<w:tbl><w:tblPr><w:tblStyle w:val="TableGrid"/><w:tblW w:w="5000" w:type="pct"/><w:tblLook w:val="04A0" w:firstRow="1" w:lastRow="0" w:firstColumn="0" w:lastColumn="0" w:noHBand="0" w:noVBand="1"/></w:tblPr><w:tblGrid>`)
+	for range cols {
+		fmt.Fprintf(c.out, `<w:gridCol w:w="%d"/>`, colWidth)
+	}
+	c.out.WriteString(`</w:tblGrid>`)
+	for i, row := range rows {
+		c.out.WriteString("<w:tr>")
+		if header[i] {
+			c.out.WriteString("<w:trPr><w:tblHeader/></w:trPr>")
+		}
+		width := 0
+		for _, cell := range row {
+			width += cell.span
+			head := header[i] || cell.node.DataAtom == atom.Th
+			fmt.Fprintf(c.out, `<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/>`, colWidth*cell.span)
+			if cell.span > 1 {
+				fmt.Fprintf(c.out, `<w:gridSpan w:val="%d"/>`, cell.span)
+			}
+			if head {
+				c.out.WriteString(`<w:shd w:val="clear" w:color="auto" w:fill="F6F8FA"/>`)
+			}
+			c.out.WriteString("</w:tcPr>")
+			c.cell(cell.node, context{level: -1, bold: head})
+			c.out.WriteString("</w:tc>")
+		}
+		for ; width < cols; width++ {
+			fmt.Fprintf(c.out, `<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/></w:tcPr><w:p/></w:tc>`, colWidth)
+		}
+		c.out.WriteString("</w:tr>")
+	}
+	c.out.WriteString("</w:tbl>")
+	// 表格后紧跟表格时 Word 会将两者合并，用空段落隔开
+	c.out.WriteString("<w:p/>")
+}
+
+// cell 输出单元格内容，单元格至少需要一个段落
+func (c *converter) cell(n *html.Node, ctx context) {
+	out := c.out
+	var b strings.Builder
+	c.out = &b
+	c.block(n, ctx)
+	c.out = out
+	if b.Len() == 0 {
+		b.WriteString("<w:p/>")
+	}
+	c.out.WriteString(b.String())
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func findClass(n *html.Node, class string) *html.Node {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode {
+			continue
+		}
+		if hasClass(child, class) {
+			return child
+		}
+		if found := findClass(child, class); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(textContent(child))
+	}
+	return b.String()
+}
+
+func atoi(s string, def int) int {
+	var n int
+	if _, err := fmt.Sscanf(s, "%d", &n); err != nil {
+		return def
+	}
+	return n
+}
@@ -0,0 +1,243 @@
+// Package docx 将渲染后的文章转换为 Word 文档 (.docx)，便于不使用 Markdown 的编辑审阅与批注
+// 标题、段落、列表、表格、引用按 Word 内置样式输出，本地图片嵌入文档，
+// 代码块为等宽字体、带底色的段落，网络链接保留为超链接并在脚注中写出完整地址。
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	_ "golang.org/x/image/webp"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/hankmor/mymedia/tools/wechat-preview/render"
+)
+
+const (
+	emuPerPixel = 9525    // 按 96 DPI 换算图片尺寸
+	maxWidth    = 5486400 // 图片最大宽度 6 英寸，超出时等比缩小
+	tableWidth  = 9000    // 表格总宽度 (twip)，与 A4 页面正文宽度相当
+)
+
+// converter 转换状态：正文、脚注、关系与嵌入的图片
+type converter struct {
+	images map[string]string // 正文中的图片地址 -> 本地文件
+
+	out *strings.Builder
+	p   *paragraph
+
+	rels      []relationship
+	media     []*media
+	embedded  map[string]*media // 本地文件 -> 已嵌入的图片
+	lists     []list
+	footnotes strings.Builder
+	notes     int
+	drawings  int
+}
+
+// relationship 文档的一条关系，图片与超链接各占一个编号
+type relationship struct {
+	ID       string
+	Type     string
+	Target   string
+	External bool
+}
+
+// media 嵌入文档的图片
+type media struct {
+	name          string
+	rel           string
+	data          []byte
+	width, height int // EMU
+}
+
+// list 一个列表的编号实例
+type list struct {
+	ordered bool
+	level   int
+	start   int
+}
+
+// Write 将导出目标渲染的文章写为 Word 文档
+func Write(w io.Writer, doc *render.Document) error {
+	nodes, err := html.ParseFragment(strings.NewReader(doc.HTML), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		return err
+	}
+
+	c := &converter{images: make(map[string]string), embedded: make(map[string]*media), out: new(strings.Builder)}
+	for _, asset := range doc.Assets {
+		c.images[asset.URL()] = asset.File
+	}
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	for _, n := range nodes {
+		body.AppendChild(n)
+	}
+	if doc.Title != "" {
+		c.out.WriteString(`<w:p><w:pPr><w:pStyle w:val="Title"/></w:pPr><w:r><w:t xml:space="preserve">` + escape(doc.Title) + `</w:t></w:r></w:p>`)
+	}
+	c.blocks(body, context{level: -1})
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	parts := []struct {
+		name string
+		data []byte
+	}{
+		{"[Content_Types].xml", []byte(contentTypes)},
+		{"_rels/.rels", []byte(packageRels)},
+		{"docProps/core.xml", []byte(fmt.Sprintf(coreProperties, escape(doc.Title), now, now))},
+		{"word/document.xml", []byte(fmt.Sprintf(document, c.out.String()))},
+		{"word/styles.xml", []byte(styles)},
+		{"word/numbering.xml", c.numberingXML()},
+		{"word/footnotes.xml", []byte(fmt.Sprintf(footnotes, footnoteSeparators+c.footnotes.String()))},
+		{"word/settings.xml", []byte(settings)},
+		{"word/_rels/document.xml.rels", c.relationshipsXML()},
+	}
+	for _, m := range c.media {
+		parts = append(parts, struct {
+			name string
+			data []byte
+		}{"word/media/" + m.name, m.data})
+	}
+
+	zw := zip.NewWriter(w)
+	for _, part := range parts {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: part.name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(part.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// relate 添加一条关系并返回编号，rId1-rId4 为样式、编号、脚注与设置
+func (c *converter) relate(typ, target string, external bool) string {
+	id := fmt.Sprintf("rId%d", len(c.rels)+5)
+	c.rels = append(c.rels, relationship{ID: id, Type: typ, Target: target, External: external})
+	return id
+}
+
+func (c *converter) relationshipsXML() []byte {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	fixed := []relationship{
+		{"rId1", relStyles, "styles.xml", false},
+		{"rId2", relNumbering, "numbering.xml", false},
+		{"rId3", relFootnotes, "footnotes.xml", false},
+		{"rId4", relSettings, "settings.xml", false},
+	}
+	for _, rel := range append(fixed, c.rels...) {
+		fmt.Fprintf(&b, `<Relationship Id="%s" Type="%s" Target="%s"`, rel.ID, rel.Type, escape(rel.Target))
+		if rel.External {
+			b.WriteString(` TargetMode="External"`)
+		}
+		b.WriteString("/>")
+	}
+	b.WriteString("</Relationships>")
+	return []byte(b.String())
+}
+
+// footnote 添加一条内容为链接地址的脚注并返回编号
+func (c *converter) footnote(href string) int {
+	c.notes++
+	fmt.Fprintf(&c.footnotes, `<w:footnote w:id="%d"><w:p><w:pPr><w:pStyle w:val="FootnoteText"/></w:pPr>`+
+		`<w:r><w:rPr><w:rStyle w:val="FootnoteReference"/></w:rPr><w:footnoteRef/></w:r>`+
+		`<w:r><w:t xml:space="preserve"> %s</w:t></w:r></w:p></w:footnote>`+"\n", c.notes, escape(href))
+	return c.notes
+}
+
+// numbering 为一个列表创建编号实例并返回编号，有序列表从 start 开始计数
+func (c *converter) numbering(ordered bool, level, start int) int {
+	c.lists = append(c.lists, list{ordered: ordered, level: level, start: start})
+	return len(c.lists)
+}
+
+func (c *converter) numberingXML() []byte {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	b.WriteString(`<w:numbering xmlns:w="` + nsW + `">` + "\n")
+	b.WriteString(abstractNumbering)
+	for i, l := range c.lists {
+		if !l.ordered {
+			fmt.Fprintf(&b, `<w:num w:numId="%d"><w:abstractNumId w:val="%d"/></w:num>`+"\n", i+1, bulletList)
+			continue
+		}
+		fmt.Fprintf(&b, `<w:num w:numId="%d"><w:abstractNumId w:val="%d"/><w:lvlOverride w:ilvl="%d"><w:startOverride w:val="%d"/></w:lvlOverride></w:num>`+"\n",
+			i+1, decimalList, l.level, l.start)
+	}
+	b.WriteString("</w:numbering>")
+	return []byte(b.String())
+}
+
+// embed 读取图片并加入文档，同一文件只嵌入一次
+// Word 不支持 WebP，解码后转为 PNG；其他无法识别的格式返回错误
+func (c *converter) embed(file string) (*media, error) {
+	if m, ok := c.embedded[file]; ok {
+		return m, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if format == "webp" {
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+		data, format = buf.Bytes(), "png"
+	}
+
+	width, height := config.Width*emuPerPixel, config.Height*emuPerPixel
+	if width > maxWidth {
+		height = height * maxWidth / width
+		width = maxWidth
+	}
+	m := &media{name: fmt.Sprintf("image%d.%s", len(c.media)+1, format), data: data, width: width, height: height}
+	m.rel = c.relate(relImage, "media/"+m.name, false)
+	c.media = append(c.media, m)
+	c.embedded[file] = m
+	return m, nil
+}
+
+// drawing 行内图片
+func drawing(m *media, id int, alt string) string {
+	return fmt.Sprintf(`<w:r><w:drawing><wp:inline distT="0" distB="0" distL="0" distR="0">`+
+		`<wp:extent cx="%[1]d" cy="%[2]d"/><wp:docPr id="%[3]d" name="图片 %[3]d" descr="%[4]s"/>`+
+		`<wp:cNvGraphicFramePr><a:graphicFrameLocks noChangeAspect="1"/></wp:cNvGraphicFramePr>`+
+		`<a:graphic><a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture"><pic:pic>`+
+		`<pic:nvPicPr><pic:cNvPr id="%[3]d" name="%[5]s"/><pic:cNvPicPr/></pic:nvPicPr>`+
+		`<pic:blipFill><a:blip r:embed="%[6]s"/><a:stretch><a:fillRect/></a:stretch></pic:blipFill>`+
+		`<pic:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="%[1]d" cy="%[2]d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></pic:spPr>`+
+		`</pic:pic></a:graphicData></a:graphic></wp:inline></w:drawing></w:r>`,
+		m.width, m.height, id, escape(alt), m.name, m.rel)
+}
+
+// escape 转义 XML 文本与属性值
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,220 @@
+// Package epub 将一个系列的文章打包为 EPUB 3 电子书
+// 每篇文章为一章，目录 (nav.xhtml) 由文章标题与各章的标题树生成；
+// 图片随书打包，代码高亮的内联样式提取为样式表中的类，封面取自系列元数据。
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/hankmor/mymedia/tools/wechat-preview/markdown"
+	"github.com/hankmor/mymedia/tools/wechat-preview/render"
+)
+
+// Book 电子书
+type Book struct {
+	Identifier  string    // 唯一标识，为空时按标题生成
+	Title       string    // 书名
+	Author      string    // 作者，可为空
+	Language    string    // 语言，默认 zh-CN
+	Description string    // 简介，可为空
+	Cover       string    // 封面图片文件，可为空
+	Modified    time.Time // 修改时间，默认当前时间
+	NavLevel    int       // 目录收录的最大标题级别，0 表示不限
+	Chapters    []Chapter
+}
+
+// Chapter 一章，对应系列中的一篇文章
+type Chapter struct {
+	Title    string
+	HTML     string              // 以导出目标渲染的正文，图片地址为 images/ 下的相对路径
+	Headings []*markdown.Heading // 标题树，用于生成目录
+	Assets   []render.Asset      // 正文引用的图片
+	Links    []string            // 正文中指向本篇文章的地址 (本地预览与线上地址)，其他章节中的这些链接改为指向本章
+}
+
+// item 清单中的一个文件
+type item struct {
+	ID         string
+	Href       string
+	MediaType  string
+	Properties string
+	data       []byte
+}
+
+// Write 生成 EPUB 文件
+func Write(w io.Writer, book *Book) error {
+	b := *book
+	if b.Language == "" {
+		b.Language = "zh-CN"
+	}
+	if b.Modified.IsZero() {
+		b.Modified = time.Now()
+	}
+	if b.Identifier == "" {
+		b.Identifier = identifier(b.Title)
+	}
+
+	var items []item
+	var spine []string
+	links := make(map[string]string) // 文章地址 -> 章节文件
+	for i, chapter := range b.Chapters {
+		for _, link := range chapter.Links {
+			links[link] = fmt.Sprintf("chapter-%03d.xhtml", i+1)
+		}
+	}
+	conv := newConverter(links)
+
+	if b.Cover != "" {
+		data, err := os.ReadFile(b.Cover)
+		if err != nil {
+			return fmt.Errorf("读取封面: %w", err)
+		}
+		href := "images/cover" + strings.ToLower(filepath.Ext(b.Cover))
+		items = append(items, item{ID: "cover-image", Href: href, MediaType: mediaType(href, data), Properties: "cover-image", data: data})
+		page, err := execute(coverTemplate, map[string]any{"Book": &b, "PageTitle": b.Title, "Href": href})
+		if err != nil {
+			return err
+		}
+		items = append(items, item{ID: "cover", Href: "cover.xhtml", MediaType: xhtmlType, data: page})
+	}
+
+	nav := make([]navPoint, len(b.Chapters))
+	for i, chapter := range b.Chapters {
+		name := fmt.Sprintf("chapter-%03d", i+1)
+		images := make(map[string]string) // 正文中的地址 -> 书中的地址
+		for j, asset := range chapter.Assets {
+			data, err := os.ReadFile(asset.File)
+			if err != nil {
+				return err
+			}
+			href := fmt.Sprintf("images/c%03d-%02d%s", i+1, j+1, strings.ToLower(filepath.Ext(asset.File)))
+			images[asset.URL()] = href
+			items = append(items, item{ID: fmt.Sprintf("img-%03d-%02d", i+1, j+1), Href: href, MediaType: mediaType(href, data), data: data})
+		}
+
+		content, err := conv.convert(chapter.HTML, images)
+		if err != nil {
+			return fmt.Errorf("%s: %w", chapter.Title, err)
+		}
+		page, err := execute(chapterTemplate, map[string]any{"Book": &b, "PageTitle": chapter.Title, "Title": chapter.Title, "Content": content})
+		if err != nil {
+			return err
+		}
+		items = append(items, item{ID: name, Href: name + ".xhtml", MediaType: xhtmlType, data: page})
+		spine = append(spine, name)
+		nav[i] = navPoint{Title: chapter.Title, Href: name + ".xhtml", Children: navPoints(chapter.Headings, name+".xhtml", b.NavLevel)}
+	}
+
+	page, err := execute(navTemplate, map[string]any{"Book": &b, "PageTitle": "目录", "Points": nav})
+	if err != nil {
+		return err
+	}
+	items = append(items, item{ID: "nav", Href: "nav.xhtml", MediaType: xhtmlType, Properties: "nav", data: page})
+	items = append(items, item{ID: "style", Href: "style.css", MediaType: "text/css", data: []byte(stylesheet + conv.css())})
+	// 封面之后是目录，再依次为各章
+	spine = append([]string{"nav"}, spine...)
+	if b.Cover != "" {
+		spine = append([]string{"cover"}, spine...)
+	}
+
+	opf, err := execute(packageTemplate, map[string]any{"Book": &b, "Items": items, "Spine": spine,
+		"Modified": b.Modified.UTC().Format("2006-01-02T15:04:05Z")})
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	// mimetype 必须是第一个文件且不压缩
+	mimetype := []byte("application/epub+zip")
+	f, err := zw.CreateRaw(&zip.FileHeader{Name: "mimetype", Method: zip.Store, CRC32: crc32.ChecksumIEEE(mimetype),
+		CompressedSize64: uint64(len(mimetype)), UncompressedSize64: uint64(len(mimetype))})
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(mimetype); err != nil {
+		return err
+	}
+
+	add := func(name string, data []byte) error {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: b.Modified})
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		return err
+	}
+	if err := add("META-INF/container.xml", []byte(container)); err != nil {
+		return err
+	}
+	if err := add("OEBPS/content.opf", opf); err != nil {
+		return err
+	}
+	for _, it := range items {
+		if err := add("OEBPS/"+it.Href, it.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// navPoint 目录项
+type navPoint struct {
+	Title    string
+	Href     string
+	Children []navPoint
+}
+
+// navPoints 由标题树生成目录项，超过 maxLevel 的标题不收录
+func navPoints(headings []*markdown.Heading, href string, maxLevel int) []navPoint {
+	var points []navPoint
+	for _, h := range headings {
+		if maxLevel > 0 && h.Level > maxLevel {
+			continue
+		}
+		title := h.Text
+		if h.Number != "" {
+			title = strings.TrimSpace(h.Number + " " + h.Text)
+		}
+		p := navPoint{Title: title, Href: href, Children: navPoints(h.Children, href, maxLevel)}
+		if h.ID != "" {
+			p.Href += "#" + h.ID
+		}
+		points = append(points, p)
+	}
+	return points
+}
+
+// identifier 按书名生成稳定的 UUID，重复导出同一系列时阅读器能识别为同一本书
+func identifier(title string) string {
+	sum := sha1.Sum([]byte("wechat-preview/epub/" + title))
+	sum[6] = sum[6]&0x0f | 0x50 // version 5
+	sum[8] = sum[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// mediaType 图片类型，按扩展名判断，未知时按内容识别
+func mediaType(name string, data []byte) string {
+	if typ := mime.TypeByExtension(filepath.Ext(name)); typ != "" {
+		typ, _, _ = strings.Cut(typ, ";")
+		return typ
+	}
+	return http.DetectContentType(data)
+}
+
+func execute(t *template.Template, data any) ([]byte, error) {
+	var buf bytes.Buffer
+	err := t.Execute(&buf, data)
+	return buf.Bytes(), err
+}
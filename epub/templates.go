@@ -0,0 +1,161 @@
+package epub
+
+import (
+	"encoding/xml"
+	"strings"
+	"text/template"
+)
+
+const xhtmlType = "application/xhtml+xml"
+
+const container = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+// escape 转义 XML 文本与属性值
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func parse(name, text string) *template.Template {
+	return template.Must(template.New(name).Funcs(template.FuncMap{"x": escape}).Parse(text))
+}
+
+var packageTemplate = parse("content.opf", `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="{{ x .Book.Language }}">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">{{ x .Book.Identifier }}</dc:identifier>
+    <dc:title>{{ x .Book.Title }}</dc:title>
+    <dc:language>{{ x .Book.Language }}</dc:language>
+    {{- with .Book.Author }}
+    <dc:creator>{{ x . }}</dc:creator>
+    {{- end }}
+    {{- with .Book.Description }}
+    <dc:description>{{ x . }}</dc:description>
+    {{- end }}
+    <meta property="dcterms:modified">{{ .Modified }}</meta>
+    {{- if .Book.Cover }}
+    <meta name="cover" content="cover-image"/>
+    {{- end }}
+  </metadata>
+  <manifest>
+    {{- range .Items }}
+    <item id="{{ .ID }}" href="{{ x .Href }}" media-type="{{ .MediaType }}"{{ with .Properties }} properties="{{ . }}"{{ end }}/>
+    {{- end }}
+  </manifest>
+  <spine>
+    {{- range .Spine }}
+    <itemref idref="{{ . }}"/>
+    {{- end }}
+  </spine>
+</package>
+`)
+
+const head = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="{{ x .Book.Language }}" xml:lang="{{ x .Book.Language }}">
+<head>
+  <meta charset="UTF-8"/>
+  <title>{{ x .PageTitle }}</title>
+  <link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+`
+
+var coverTemplate = parse("cover.xhtml", head+`<body epub:type="cover">
+  <div class="cover"><img src="{{ x .Href }}" alt="{{ x .Book.Title }}"/></div>
+</body>
+</html>
+`)
+
+var chapterTemplate = parse("chapter.xhtml", head+`<body>
+  <section epub:type="chapter">
+    <h1 class="chapter-title">{{ x .Title }}</h1>
+{{ .Content }}
+  </section>
+</body>
+</html>
+`)
+
+var navTemplate = parse("nav.xhtml", head+`<body>
+  <nav epub:type="toc" id="toc">
+    <h1>目录</h1>
+    {{ template "points" .Points }}
+  </nav>
+</body>
+</html>
+{{ define "points" }}<ol>
+{{- range . }}
+<li><a href="{{ x .Href }}">{{ x .Title }}</a>{{ if .Children }}{{ template "points" .Children }}{{ end }}</li>
+{{- end }}
+</ol>{{ end }}`)
+
+// stylesheet 电子书的基础样式，代码高亮的类由转换时追加
+const stylesheet = `body {
+  font-family: "PingFang SC", "Hiragino Sans GB", "Microsoft YaHei", serif;
+  line-height: 1.75;
+  margin: 0 1em;
+}
+h1.chapter-title {
+  font-size: 1.6em;
+  margin: 1em 0;
+  text-align: center;
+}
+img {
+  max-width: 100%;
+  height: auto;
+}
+figure {
+  margin: 1em 0;
+  text-align: center;
+}
+figcaption {
+  font-size: 0.85em;
+  color: #888;
+}
+blockquote {
+  margin: 1em 0;
+  padding: 0.5em 1em;
+  border-left: 4px solid #ddd;
+  color: #666;
+}
+table {
+  border-collapse: collapse;
+  margin: 1em 0;
+}
+th, td {
+  border: 1px solid #ddd;
+  padding: 0.3em 0.6em;
+}
+pre {
+  white-space: pre-wrap;
+  word-wrap: break-word;
+}
+.code-block {
+  margin: 1em 0;
+  border-radius: 6px;
+  overflow: hidden;
+}
+.code-block code {
+  display: block;
+  padding: 0.8em 1em;
+  font-family: Menlo, Consolas, monospace;
+  font-size: 0.85em;
+  line-height: 1.6;
+}
+.cover {
+  text-align: center;
+}
+.cover img {
+  max-height: 100%;
+}
+nav ol {
+  list-style: none;
+  padding-left: 1em;
+}
+`
@@ -0,0 +1,184 @@
+package epub
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// converter 将渲染结果转换为 XHTML，整本书共用一份代码高亮样式表
+type converter struct {
+	classes map[string]string // 内联样式 -> 类名
+	rules   []string
+	links   map[string]string // 系列中文章的地址 -> 章节文件
+}
+
+func newConverter(links map[string]string) *converter {
+	return &converter{classes: make(map[string]string), links: links}
+}
+
+// convert 解析 HTML 片段并按 XHTML 输出：空元素自闭合、属性加引号、实体转为字符
+// 代码块中的内联样式改为类，images 中的图片改为书中的地址，网络图片改为链接 (EPUB 不允许引用远程图片)，
+// 指向系列中其他文章的链接改为对应的章节文件。
+func (c *converter) convert(fragment string, images map[string]string) (string, error) {
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, n := range nodes {
+		c.walk(n, images, false)
+		if err := html.Render(&b, n); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+func (c *converter) walk(n *html.Node, images map[string]string, inCode bool) {
+	if n.Type == html.ElementNode {
+		if hasClass(n, "code-block") {
+			inCode = true
+		}
+		if inCode {
+			c.extractStyle(n)
+		}
+		if n.DataAtom == atom.Img {
+			if replaced := c.image(n, images); replaced != nil {
+				n = replaced
+			}
+		}
+		if n.DataAtom == atom.A {
+			c.link(n)
+		}
+	}
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+		c.walk(child, images, inCode)
+		child = next
+	}
+}
+
+// image 改写图片地址，网络图片替换为链接并返回新节点
+func (c *converter) image(n *html.Node, images map[string]string) *html.Node {
+	src := attr(n, "src")
+	if href, ok := images[src]; ok {
+		setAttr(n, "src", href)
+		return nil
+	}
+	if !strings.HasPrefix(src, "http") && !strings.HasPrefix(src, "//") {
+		return nil
+	}
+	if n.Parent == nil {
+		return nil
+	}
+	log.Printf("Warning: EPUB 不支持网络图片，已改为链接文字: %s\n", src)
+	text := attr(n, "alt")
+	if text == "" {
+		text = "图片"
+	}
+	replacement := &html.Node{Type: html.TextNode, Data: "[" + text + "]"}
+	if !insideLink(n) { // 链接中的图片只保留文字，避免链接嵌套
+		link := &html.Node{Type: html.ElementNode, Data: "a", DataAtom: atom.A,
+			Attr: []html.Attribute{{Key: "href", Val: src}}}
+		link.AppendChild(replacement)
+		replacement = link
+	}
+	n.Parent.InsertBefore(replacement, n)
+	n.Parent.RemoveChild(n)
+	return replacement
+}
+
+// link 将指向系列中文章的链接改为章节文件，保留锚点
+func (c *converter) link(n *html.Node) {
+	target, fragment, hasFragment := strings.Cut(attr(n, "href"), "#")
+	file, ok := c.links[target]
+	if !ok {
+		// 含中文的文章地址可能已被 URL 编码
+		unescaped, err := url.PathUnescape(target)
+		if file, ok = c.links[unescaped]; err != nil || !ok {
+			return
+		}
+	}
+	if hasFragment {
+		file += "#" + fragment
+	}
+	setAttr(n, "href", file)
+}
+
+func insideLink(n *html.Node) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.DataAtom == atom.A {
+			return true
+		}
+	}
+	return false
+}
+
+// extractStyle 将内联样式移到样式表，相同样式共用一个类
+func (c *converter) extractStyle(n *html.Node) {
+	style := strings.TrimSpace(attr(n, "style"))
+	if style == "" {
+		return
+	}
+	class, ok := c.classes[style]
+	if !ok {
+		class = fmt.Sprintf("hl-%d", len(c.classes)+1)
+		c.classes[style] = class
+		c.rules = append(c.rules, fmt.Sprintf(".%s { %s }", class, strings.TrimSuffix(style, ";")))
+	}
+	removeAttr(n, "style")
+	if existing := attr(n, "class"); existing != "" {
+		class = existing + " " + class
+	}
+	setAttr(n, "class", class)
+}
+
+// css 代码高亮样式
+func (c *converter) css() string {
+	if len(c.rules) == 0 {
+		return ""
+	}
+	return "\n/* 代码高亮 */\n" + strings.Join(c.rules, "\n") + "\n"
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func setAttr(n *html.Node, key, val string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
+
+func removeAttr(n *html.Node, key string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr = append(n.Attr[:i], n.Attr[i+1:]...)
+			return
+		}
+	}
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
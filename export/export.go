@@ -0,0 +1,170 @@
+// Package export 将文章导出为独立文件，便于发给无法运行本工具的审阅者
+// html 格式为单个 HTML 文件，样式内联、本地图片以 data URI 嵌入；
+// zip 格式包含整理后的 Markdown、渲染后的 HTML 以及 images/ 目录下的全部图片。
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hankmor/mymedia/tools/wechat-preview/render"
+)
+
+// Format 导出格式
+type Format string
+
+const (
+	HTML Format = "html" // 单个 HTML 文件
+	Zip  Format = "zip"  // Markdown、HTML 与图片的压缩包
+	Docx Format = "docx" // Word 文档，由 docx 包生成
+)
+
+// ParseFormat 解析导出格式，空值为 html
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case "":
+		return HTML, nil
+	case HTML, Zip, Docx:
+		return f, nil
+	default:
+		return "", fmt.Errorf("不支持的导出格式: %s (可选 html、zip、docx)", s)
+	}
+}
+
+// ContentType 响应的 Content-Type
+func (f Format) ContentType() string {
+	switch f {
+	case Zip:
+		return "application/zip"
+	case Docx:
+		return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	default:
+		return "text/html; charset=utf-8"
+	}
+}
+
+// Options 导出配置
+type Options struct {
+	CSS   string // 内联到页面中的样式
+	Theme string // 排版主题名，写入页面信息
+}
+
+var page = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <meta name="generator" content="wechat-preview{{ with .Theme }} (theme: {{ . }}){{ end }}">
+    <title>{{ .Title }}</title>
+    <style>
+{{ .CSS }}
+    </style>
+</head>
+
+<body>
+    <div class="article-wrapper">
+        <div class="article-header">
+            <h1>{{ .Title }}</h1>
+        </div>
+
+        <div class="article-content">
+            {{ .HTML }}
+        </div>
+    </div>
+</body>
+
+</html>
+`))
+
+// Page 生成独立的 HTML 页面
+func Page(doc *render.Document, content string, opts Options) ([]byte, error) {
+	var buf bytes.Buffer
+	err := page.Execute(&buf, map[string]any{
+		"Title": doc.Title,
+		"Theme": opts.Theme,
+		"CSS":   template.CSS(opts.CSS),
+		"HTML":  template.HTML(content),
+	})
+	return buf.Bytes(), err
+}
+
+// WriteHTML 导出单个 HTML 文件，图片以 data URI 嵌入
+func WriteHTML(w io.Writer, doc *render.Document, opts Options) error {
+	content := doc.HTML
+	for _, asset := range doc.Assets {
+		data, err := os.ReadFile(asset.File)
+		if err != nil {
+			return err
+		}
+		uri := dataURI(asset.File, data)
+		for _, quote := range []string{`"`, `'`} {
+			content = strings.ReplaceAll(content, "src="+quote+asset.URL()+quote, "src="+quote+uri+quote)
+		}
+	}
+	out, err := Page(doc, content, opts)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// WriteZip 导出压缩包，所有文件位于以 name 命名的目录下：
+// index.html (图片引用 images/ 下的相对路径)、name.md (去掉 Frontmatter 的 Markdown) 与 images/
+func WriteZip(w io.Writer, doc *render.Document, name string, opts Options) error {
+	zw := zip.NewWriter(w)
+	now := time.Now()
+	add := func(file string, data []byte) error {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name + "/" + file, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		return err
+	}
+
+	out, err := Page(doc, doc.HTML, opts)
+	if err != nil {
+		return err
+	}
+	if err := add("index.html", out); err != nil {
+		return err
+	}
+	if err := add(name+".md", []byte(Markdown(doc))); err != nil {
+		return err
+	}
+	for _, asset := range doc.Assets {
+		data, err := os.ReadFile(asset.File)
+		if err != nil {
+			return err
+		}
+		if err := add(asset.Path, data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// Markdown 整理后的 Markdown：去掉 Frontmatter，补回文章标题
+func Markdown(doc *render.Document) string {
+	return "# " + doc.Title + "\n\n" + strings.TrimLeft(doc.Body, "\n")
+}
+
+// dataURI 图片的 data URI，类型按扩展名判断，未知时按内容识别
+func dataURI(file string, data []byte) string {
+	typ := mime.TypeByExtension(strings.ToLower(filepath.Ext(file)))
+	if typ == "" {
+		typ = http.DetectContentType(data)
+	}
+	return "data:" + typ + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
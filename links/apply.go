@@ -0,0 +1,138 @@
+package links
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Result 一个链接的处理结果，随发布响应返回
+type Result struct {
+	Href   string `json:"href"`
+	Text   string `json:"text"`
+	Action Action `json:"action"`
+	Rule   string `json:"rule"`            // 命中的规则模式
+	Ref    int    `json:"ref,omitempty"`   // 文末引用编号
+	Error  string `json:"error,omitempty"` // 二维码生成失败等，此时退化为纯文本
+}
+
+// QRCodeFunc 生成链接二维码并返回图片地址
+type QRCodeFunc func(href string) (string, error)
+
+var (
+	reLink      = regexp.MustCompile(`(?s)<a\s([^>]*)>(.*?)</a>`)
+	reHref      = regexp.MustCompile(`\bhref=["']([^"']*)["']`)
+	reTag       = regexp.MustCompile(`<[^>]*>`)
+	reListItems = regexp.MustCompile(`<li[\s>]`)
+	// reFootnotes 脚注列表的开始标签，正文或代码中出现的类名不会匹配
+	reFootnotes = regexp.MustCompile(`<div class="references-section footnotes-section"[^>]*>`)
+)
+
+// 引用列表的标记与 markdown.Footnote、wechat-format.js 保持一致，脚注与外链引用合并为一个列表
+const (
+	sectionOpen  = `<div class="references-section footnotes-section" style="margin-top: 40px; padding-top: 20px; border-top: 1px solid #eee;">`
+	sectionTitle = `<h3 style="margin-bottom: 15px; font-size: 16px; font-weight: bold;">%s</h3>`
+	listOpen     = `<ul style="padding-left: 0; list-style: none;">`
+	listClose    = "</ul>\n</div>"
+	itemFormat   = `<li style="display: block; margin-bottom: 8px; font-size: 14px; line-height: 1.6; color: #666;"><span class="li-text"><span style="margin-right: 5px; color: #999;">[%d]</span>%s: <span style="color: #333; word-break: break-all;">%s</span></span></li>`
+	supFormat    = `<sup class="footnote-ref" style="margin-left: 2px; color: #999;">[%d]</sup>`
+	qrFormat     = `<span class="link-qrcode" style="display: block; margin: 10px auto; text-align: center;"><img src="%s" alt="%s" style="display: inline-block; width: 120px; height: 120px; margin: 0;" /><span style="display: block; font-size: 12px; color: #999;">扫码访问</span></span>`
+)
+
+// Apply 按策略处理 HTML 中的链接，返回处理后的 HTML 与每个链接的处理结果
+// qrcode 为 nil 时二维码规则退化为纯文本。包裹图片的链接只去掉链接，不会删除图片。
+func (p *Policy) Apply(content string, qrcode QRCodeFunc) (string, []Result) {
+	// 脚注列表位于文末，取最后一个；拆分后单独处理，追加引用时不依赖替换前的位置
+	var section string
+	if loc := reFootnotes.FindAllStringIndex(content, -1); loc != nil {
+		start := loc[len(loc)-1][0]
+		content, section = content[:start], content[start:]
+	}
+	next := 1 + len(reListItems.FindAllString(footnoteList(section), -1))
+
+	var results []Result
+	var refs []string
+	refIndex := make(map[string]int)
+
+	rewrite := func(a string) string {
+		m := reLink.FindStringSubmatch(a)
+		attrs, inner := m[1], m[2]
+		hm := reHref.FindStringSubmatch(attrs)
+		if hm == nil {
+			return a
+		}
+		href := html.UnescapeString(hm[1])
+		rule := p.Match(href)
+		result := Result{Href: href, Text: html.UnescapeString(reTag.ReplaceAllString(inner, "")), Action: rule.Action, Rule: rule.Pattern}
+		hasImage := strings.Contains(inner, "<img")
+
+		var out string
+		switch rule.Action {
+		case Keep:
+			out = a
+		case Footnote:
+			n, ok := refIndex[href]
+			if !ok {
+				n = next
+				next++
+				refIndex[href] = n
+				refs = append(refs, fmt.Sprintf(itemFormat, n, html.EscapeString(result.Text), html.EscapeString(href)))
+			}
+			result.Ref = n
+			out = inner + fmt.Sprintf(supFormat, n)
+		case QRCode:
+			out = inner
+			if qrcode == nil {
+				result.Error = "未配置二维码生成"
+				break
+			}
+			src, err := qrcode(href)
+			if err != nil {
+				result.Error = err.Error()
+				break
+			}
+			out += fmt.Sprintf(qrFormat, html.EscapeString(src), html.EscapeString("二维码："+href))
+		case Drop:
+			if hasImage {
+				out = inner
+			}
+		default:
+			out = inner
+		}
+		results = append(results, result)
+		return out
+	}
+	content = reLink.ReplaceAllStringFunc(content, rewrite)
+	section = reLink.ReplaceAllStringFunc(section, rewrite)
+
+	if len(refs) > 0 {
+		section = appendReferences(section, refs)
+	}
+	return content + section, results
+}
+
+// footnoteList 脚注列表中结束标签之前的部分，用于统计已有的脚注数
+func footnoteList(section string) string {
+	if end := strings.Index(section, listClose); end >= 0 {
+		return section[:end]
+	}
+	return section
+}
+
+// appendReferences 将外链引用追加到已有的脚注列表 section，没有脚注时新建引用列表
+func appendReferences(section string, refs []string) string {
+	items := strings.Join(refs, "\n") + "\n"
+	if end := strings.Index(section, listClose); end >= 0 {
+		head := strings.Replace(section[:end], fmt.Sprintf(sectionTitle, "注释"), fmt.Sprintf(sectionTitle, "注释与引用"), 1)
+		return head + items + section[end:]
+	}
+	return section + sectionOpen + "\n" + fmt.Sprintf(sectionTitle, "引用链接") + "\n" + listOpen + "\n" + items + "</ul>\n</div>\n"
+}
+
+// QRCodePNG 生成链接地址的二维码 PNG
+func QRCodePNG(href string, size int) ([]byte, error) {
+	return qrcode.Encode(href, qrcode.Medium, size)
+}
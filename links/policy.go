@@ -0,0 +1,140 @@
+// Package links 发布到微信时的链接处理策略
+// 微信正文中只有公众号文章 (mp.weixin.qq.com) 的链接可以点击，其它外链会变成无法跳转的文字，
+// 页内锚点与本地预览地址粘贴后同样失效。策略按域名或模式为每个链接选择处理方式：
+// 保留、转为文末引用、转为纯文本、替换为二维码或整体删除。
+package links
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Action 链接的处理方式
+type Action string
+
+const (
+	Keep     Action = "keep"     // 保留链接
+	Footnote Action = "footnote" // 转为文字 + [n] 上标，地址列在文末
+	Plain    Action = "plain"    // 只保留链接文字
+	QRCode   Action = "qrcode"   // 保留文字，并在其后插入地址的二维码
+	Drop     Action = "drop"     // 连同文字一起删除
+)
+
+var actions = map[Action]bool{Keep: true, Footnote: true, Plain: true, QRCode: true, Drop: true}
+
+// DefaultRules 默认策略：公众号文章保留，锚点与本地链接转为纯文本，其余外链转为文末引用
+// 自定义规则排在默认规则之前，可逐条覆盖。
+const DefaultRules = "mp.weixin.qq.com=keep;#=plain;local=plain;*=footnote"
+
+// Rule 一条规则，Pattern 的写法：
+//
+//	#                 页内锚点
+//	local             没有协议与域名的本地链接，例如 /article/xxx、../a.md
+//	*                 任意链接
+//	re:<正则>         正则匹配完整地址
+//	example.com       域名，* 为通配符，例如 *.github.com
+//	github.com/foo/*  域名加路径
+type Rule struct {
+	Pattern string
+	Action  Action
+	match   func(href string, u *url.URL) bool
+}
+
+// Policy 按顺序匹配的规则列表，第一条命中的规则生效
+type Policy struct {
+	Rules []Rule
+}
+
+// Default 默认策略
+func Default() *Policy {
+	p, _ := Parse("")
+	return p
+}
+
+// Parse 解析 "pattern=action;pattern=action" 形式的配置，并在其后追加默认规则
+func Parse(s string) (*Policy, error) {
+	p := &Policy{}
+	for _, spec := range []string{s, DefaultRules} {
+		for _, item := range strings.FieldsFunc(spec, func(r rune) bool { return r == ';' || r == '\n' }) {
+			item = strings.TrimSpace(item)
+			if item == "" {
+				continue
+			}
+			i := strings.LastIndex(item, "=")
+			if i < 0 {
+				return nil, fmt.Errorf("链接规则缺少处理方式: %q", item)
+			}
+			rule, err := NewRule(strings.TrimSpace(item[:i]), Action(strings.ToLower(strings.TrimSpace(item[i+1:]))))
+			if err != nil {
+				return nil, err
+			}
+			p.Rules = append(p.Rules, rule)
+		}
+	}
+	return p, nil
+}
+
+// NewRule 创建规则
+func NewRule(pattern string, action Action) (Rule, error) {
+	if !actions[action] {
+		return Rule{}, fmt.Errorf("未知的链接处理方式 %q (可选 keep、footnote、plain、qrcode、drop)", action)
+	}
+	rule := Rule{Pattern: pattern, Action: action}
+	switch {
+	case pattern == "":
+		return Rule{}, fmt.Errorf("链接规则缺少模式: =%s", action)
+	case pattern == "*":
+		rule.match = func(string, *url.URL) bool { return true }
+	case pattern == "#":
+		rule.match = func(href string, _ *url.URL) bool { return strings.HasPrefix(href, "#") }
+	case pattern == "local":
+		rule.match = func(href string, u *url.URL) bool {
+			return !strings.HasPrefix(href, "#") && (u == nil || (u.Scheme == "" && u.Host == ""))
+		}
+	case strings.HasPrefix(pattern, "re:"):
+		re, err := regexp.Compile(pattern[len("re:"):])
+		if err != nil {
+			return Rule{}, fmt.Errorf("链接规则 %q: %w", pattern, err)
+		}
+		rule.match = func(href string, _ *url.URL) bool { return re.MatchString(href) }
+	default:
+		re := globPattern(pattern)
+		withPath := strings.Contains(pattern, "/")
+		rule.match = func(_ string, u *url.URL) bool {
+			if u == nil || u.Host == "" {
+				return false
+			}
+			target := strings.ToLower(u.Hostname())
+			if withPath {
+				target += u.EscapedPath()
+			}
+			return re.MatchString(target)
+		}
+	}
+	return rule, nil
+}
+
+// globPattern 将通配符模式转为正则，* 匹配任意字符
+func globPattern(pattern string) *regexp.Regexp {
+	parts := strings.Split(strings.ToLower(pattern), "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+// Match 返回链接命中的规则，没有规则命中时保留链接
+func (p *Policy) Match(href string) Rule {
+	u, err := url.Parse(href)
+	if err != nil {
+		u = nil
+	}
+	for _, rule := range p.Rules {
+		if rule.match(href, u) {
+			return rule
+		}
+	}
+	return Rule{Pattern: "", Action: Keep}
+}
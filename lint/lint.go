@@ -0,0 +1,139 @@
+// Package lint 微信公众号兼容性检查
+// 在发布前对文章做一遍检查，把粘贴到微信后才会发现的问题提前报告出来：
+// 标题与摘要超长、图片过多或格式不支持、嵌套表格、外链、iframe、超长代码块等。
+// 检查基于预览目标的渲染结果，同时对照原文定位到行列。
+package lint
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hankmor/mymedia/tools/wechat-preview/links"
+	"github.com/hankmor/mymedia/tools/wechat-preview/markdown"
+	"github.com/hankmor/mymedia/tools/wechat-preview/render"
+	"github.com/hankmor/mymedia/tools/wechat-preview/sanitize"
+)
+
+// Severity 问题级别，存在 error 时默认阻止发布
+type Severity string
+
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+	Info    Severity = "info"
+)
+
+// Finding 一条检查结果，Line 为 0 表示针对整篇文章
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Message  string   `json:"message"`
+	Fix      string   `json:"fix,omitempty"` // 修改建议
+}
+
+// Options 检查配置
+type Options struct {
+	ProjectRoot  string               // 解析 /_local_fs 图片地址
+	Store        *markdown.AssetStore // 解析生成图片地址
+	Links        *links.Policy        // 发布时的链接策略
+	SanitizeMode sanitize.Mode        // 发布时的 HTML 清理模式
+	MaxImages    int                  // 图片数量上限
+	MaxCodeLines int                  // 单个代码块的行数上限
+}
+
+// Rule 一条检查规则，Check 返回的结果由检查器填写规则 ID
+type Rule struct {
+	ID    string
+	Check func(a *Article) []Finding
+}
+
+// Article 待检查的文章
+type Article struct {
+	Doc     *render.Document
+	Options *Options
+	loc     locator
+	imgs    []image
+}
+
+// At 在原文中查找 needle 并返回带位置的结果，同一 needle 多次查找时依次定位到后续出现的位置
+func (a *Article) At(severity Severity, needle, message, fix string) Finding {
+	line, col := a.loc.find(needle)
+	return Finding{Severity: severity, Line: line, Column: col, Message: message, Fix: fix}
+}
+
+// Linter 按规则集检查文章
+type Linter struct {
+	rules   []Rule
+	options Options
+}
+
+// New 创建使用默认规则集的检查器
+func New(options Options) *Linter {
+	return &Linter{rules: DefaultRules(), options: options}
+}
+
+// Lint 检查以预览目标渲染的文章，结果按行号排序
+func (l *Linter) Lint(doc *render.Document) []Finding {
+	a := &Article{Doc: doc, Options: &l.options, loc: locator{source: doc.Source, next: make(map[string]int)}}
+	findings := []Finding{}
+	for _, rule := range l.rules {
+		for _, f := range rule.Check(a) {
+			f.Rule = rule.ID
+			findings = append(findings, f)
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Line < findings[j].Line
+	})
+	return findings
+}
+
+// HasErrors 是否存在阻止发布的问题
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// Count 各级别的问题数量，以级别名称为键，便于模板与前端直接读取
+func Count(findings []Finding) map[string]int {
+	counts := make(map[string]int)
+	for _, f := range findings {
+		counts[string(f.Severity)]++
+	}
+	return counts
+}
+
+// locator 在原文中定位字符串，行列从 1 开始，列按字符计
+type locator struct {
+	source string
+	next   map[string]int
+}
+
+func (l *locator) find(needle string) (line, col int) {
+	if needle == "" {
+		return 0, 0
+	}
+	from := l.next[needle]
+	i := strings.Index(l.source[from:], needle)
+	if i < 0 {
+		if from, i = 0, strings.Index(l.source, needle); i < 0 {
+			return 0, 0
+		}
+	}
+	offset := from + i
+	l.next[needle] = offset + len(needle)
+	return position(l.source, offset)
+}
+
+// position 字节偏移对应的行列
+func position(s string, offset int) (line, col int) {
+	lineStart := strings.LastIndex(s[:offset], "\n") + 1
+	return strings.Count(s[:offset], "\n") + 1, utf8.RuneCountInString(s[lineStart:offset]) + 1
+}
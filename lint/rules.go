@@ -0,0 +1,273 @@
+package lint
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hankmor/mymedia/tools/wechat-preview/links"
+	"github.com/hankmor/mymedia/tools/wechat-preview/render"
+	"github.com/hankmor/mymedia/tools/wechat-preview/sanitize"
+)
+
+// 微信公众号编辑器的限制
+const (
+	MaxTitleLength  = 64       // 标题字数
+	MaxDigestLength = 120      // 摘要字数
+	MaxGIFSize      = 10 << 20 // GIF 大小
+)
+
+// unsupportedFormats 微信不支持的图片格式
+var unsupportedFormats = map[string]string{
+	".webp": "WebP",
+	".svg":  "SVG",
+	".avif": "AVIF",
+	".heic": "HEIC",
+	".tif":  "TIFF",
+	".tiff": "TIFF",
+}
+
+// DefaultRules 默认规则集
+func DefaultRules() []Rule {
+	return []Rule{
+		{ID: "title-length", Check: checkTitle},
+		{ID: "digest-length", Check: checkDigest},
+		{ID: "image-count", Check: checkImageCount},
+		{ID: "image-format", Check: checkImageFormat},
+		{ID: "gif-size", Check: checkGIFSize},
+		{ID: "nested-table", Check: checkNestedTables},
+		{ID: "external-link", Check: checkLinks},
+		{ID: "unsupported-html", Check: checkHTML},
+		{ID: "code-block-size", Check: checkCodeBlocks},
+	}
+}
+
+func checkTitle(a *Article) []Finding {
+	n := utf8.RuneCountInString(a.Doc.Title)
+	if n <= MaxTitleLength {
+		return nil
+	}
+	return []Finding{a.At(Error, a.Doc.Title,
+		fmt.Sprintf("标题 %d 字，超过微信限制的 %d 字", n, MaxTitleLength),
+		fmt.Sprintf("缩短到 %d 字以内", MaxTitleLength))}
+}
+
+// digestKeys 作为摘要的 Frontmatter 字段
+var digestKeys = []string{"description", "summary"}
+
+func checkDigest(a *Article) []Finding {
+	for _, key := range digestKeys {
+		digest := a.Doc.Frontmatter[key]
+		if n := utf8.RuneCountInString(digest); n > MaxDigestLength {
+			return []Finding{a.At(Error, key+":",
+				fmt.Sprintf("摘要 (%s) %d 字，超过微信限制的 %d 字", key, n, MaxDigestLength),
+				fmt.Sprintf("缩短到 %d 字以内", MaxDigestLength))}
+		}
+	}
+	return nil
+}
+
+var (
+	reImg  = regexp.MustCompile(`<img\s[^>]*>`)
+	reSrc  = regexp.MustCompile(`\bsrc=["']([^"']+)["']`)
+	reLink = regexp.MustCompile(`(?s)<a\s[^>]*\bhref=["']([^"']*)["'][^>]*>(.*?)</a>`)
+	reTag  = regexp.MustCompile(`<[^>]*>`)
+)
+
+// image 渲染结果中的图片
+type image struct {
+	src    string // HTML 中的地址
+	file   string // 本地文件，远程图片为空
+	needle string // 在原文中定位用的字符串
+}
+
+// images 渲染结果中的所有图片 (包括公式、图表等生成图片)
+func (a *Article) images() []image {
+	if a.imgs != nil {
+		return a.imgs
+	}
+	list := []image{}
+	for _, tag := range reImg.FindAllString(a.Doc.HTML, -1) {
+		m := reSrc.FindStringSubmatch(tag)
+		if m == nil {
+			continue
+		}
+		img := image{src: html.UnescapeString(m[1])}
+		if rel, ok := strings.CutPrefix(img.src, "/_local_fs/"); ok {
+			img.file = filepath.Join(a.Options.ProjectRoot, filepath.FromSlash(rel))
+			img.needle = path.Base(rel)
+		} else if name, ok := a.Options.Store.NameFromURL(img.src); ok {
+			img.file = a.Options.Store.Path(name)
+		} else {
+			img.needle = img.src
+		}
+		list = append(list, img)
+	}
+	a.imgs = list
+	return list
+}
+
+func checkImageCount(a *Article) []Finding {
+	n := len(a.images())
+	if a.Options.MaxImages <= 0 || n <= a.Options.MaxImages {
+		return nil
+	}
+	return []Finding{{Severity: Warning,
+		Message: fmt.Sprintf("共 %d 张图片，超过 %d 张，微信中加载缓慢", n, a.Options.MaxImages),
+		Fix:     "合并或删减图片"}}
+}
+
+// imageExt 图片扩展名，忽略查询参数
+func imageExt(src string) string {
+	if u, err := url.Parse(src); err == nil {
+		src = u.Path
+	}
+	return strings.ToLower(path.Ext(src))
+}
+
+func checkImageFormat(a *Article) []Finding {
+	var findings []Finding
+	for _, img := range a.images() {
+		format, ok := unsupportedFormats[imageExt(img.src)]
+		if !ok {
+			continue
+		}
+		findings = append(findings, a.At(Error, img.needle,
+			fmt.Sprintf("微信不支持 %s 图片: %s", format, img.src),
+			"转换为 PNG 或 JPEG"))
+	}
+	return findings
+}
+
+func checkGIFSize(a *Article) []Finding {
+	var findings []Finding
+	for _, img := range a.images() {
+		if img.file == "" || imageExt(img.src) != ".gif" {
+			continue
+		}
+		info, err := os.Stat(img.file)
+		if err != nil || info.Size() <= MaxGIFSize {
+			continue
+		}
+		findings = append(findings, a.At(Error, img.needle,
+			fmt.Sprintf("GIF 大小 %.1f MB，超过微信限制的 %d MB: %s", float64(info.Size())/(1<<20), MaxGIFSize>>20, img.src),
+			"降低帧率、尺寸或颜色数，或改为视频"))
+	}
+	return findings
+}
+
+var reTableTag = regexp.MustCompile(`(?i)<(/?)table\b`)
+
+// checkNestedTables 嵌套表格只能来自原始 HTML，直接扫描原文
+func checkNestedTables(a *Article) []Finding {
+	var findings []Finding
+	depth := 0
+	for _, m := range reTableTag.FindAllStringSubmatchIndex(a.Doc.Source, -1) {
+		if m[3] > m[2] {
+			depth = max(depth-1, 0)
+			continue
+		}
+		if depth++; depth > 1 {
+			line, col := position(a.Doc.Source, m[0])
+			findings = append(findings, Finding{Severity: Warning, Line: line, Column: col,
+				Message: "嵌套表格在微信中显示错乱", Fix: "拆分为多个表格或改用列表"})
+		}
+	}
+	return findings
+}
+
+// actionLabels 链接处理方式的说明
+var actionLabels = map[links.Action]string{
+	links.Footnote: "转为文末引用",
+	links.Plain:    "转为纯文本",
+	links.QRCode:   "转为二维码",
+	links.Drop:     "删除",
+}
+
+// checkLinks 外链在微信中不可点击：保留的外链给出警告，会被转换的外链提示发布后的效果
+func checkLinks(a *Article) []Finding {
+	if a.Options.Links == nil {
+		return nil
+	}
+	var findings []Finding
+	for _, m := range reLink.FindAllStringSubmatch(a.Doc.HTML, -1) {
+		href := html.UnescapeString(m[1])
+		u, err := url.Parse(href)
+		if err != nil || u.Host == "" {
+			continue // 锚点与本地链接发布时按策略处理，不单独提示
+		}
+		text := html.UnescapeString(reTag.ReplaceAllString(m[2], ""))
+		rule := a.Options.Links.Match(href)
+		switch {
+		case rule.Action == links.Keep && strings.EqualFold(u.Hostname(), "mp.weixin.qq.com"):
+			continue
+		case rule.Action == links.Keep:
+			findings = append(findings, a.At(Warning, href,
+				fmt.Sprintf("外链在微信中不可点击: %s", href),
+				"在 LINK_POLICY 中改为 footnote 或 qrcode"))
+		default:
+			findings = append(findings, a.At(Info, href,
+				fmt.Sprintf("外链「%s」发布时将%s: %s", text, actionLabels[rule.Action], href), ""))
+		}
+	}
+	return findings
+}
+
+// checkHTML 微信不支持的原始 HTML：strip 模式下发布时会被移除，其它模式会原样粘贴到微信
+func checkHTML(a *Article) []Finding {
+	issues := a.Doc.Sanitized
+	severity, message := Warning, "发布时将移除微信不支持的 HTML: %s"
+	if a.Options.SanitizeMode != sanitize.Strip {
+		_, issues = sanitize.Sanitize(a.Doc.HTML, sanitize.Warn)
+		severity, message = Error, "微信不支持的 HTML: %s"
+	}
+	var findings []Finding
+	for _, issue := range issues {
+		needle := "<" + issue.Name
+		if issue.Kind == sanitize.KindAttribute {
+			needle = issue.Name + "="
+		} else if issue.Kind == sanitize.KindStyle {
+			needle = issue.Name + ":"
+		}
+		findings = append(findings, a.At(severity, needle,
+			fmt.Sprintf(message, issue),
+			"删除这段 HTML，或改用 Markdown 语法与短代码"))
+	}
+	return findings
+}
+
+var reFence = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+
+// checkCodeBlocks 超长代码块在手机上难以阅读，粘贴时也容易卡顿
+func checkCodeBlocks(a *Article) []Finding {
+	if a.Options.MaxCodeLines <= 0 {
+		return nil
+	}
+	_, body := render.SplitFrontmatter(a.Doc.Source)
+	offset := strings.Count(a.Doc.Source, "\n") - strings.Count(body, "\n")
+
+	var findings []Finding
+	var fence string
+	start := 0
+	for i, line := range strings.Split(body, "\n") {
+		m := reFence.FindStringSubmatch(line)
+		switch {
+		case fence == "" && m != nil:
+			fence, start = m[1], i
+		case fence != "" && m != nil && m[1][0] == fence[0] && len(m[1]) >= len(fence) && strings.TrimSpace(line) == m[1]:
+			if n := i - start - 1; n > a.Options.MaxCodeLines {
+				findings = append(findings, Finding{Severity: Warning, Line: offset + start + 1, Column: 1,
+					Message: fmt.Sprintf("代码块 %d 行，超过 %d 行，手机上难以阅读", n, a.Options.MaxCodeLines),
+					Fix:     "只保留关键片段，完整代码放到仓库中"})
+			}
+			fence = ""
+		}
+	}
+	return findings
+}
@@ -1,16 +1,21 @@
 package main
 
 import (
+	"bytes"
+	"cmp"
 	"embed"
 	"flag"
 	"fmt"
+	stdhtml "html"
 	"html/template"
 	"io/fs"
+	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,10 +25,21 @@ import (
 	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/renderer/html"
 
-	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
 	"github.com/hankmor/mymedia/tools/wechat-preview/config"
-	"github.com/hankmor/mymedia/tools/wechat-preview/services"
-	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/hankmor/mymedia/tools/wechat-preview/cover"
+	"github.com/hankmor/mymedia/tools/wechat-preview/docx"
+	"github.com/hankmor/mymedia/tools/wechat-preview/epub"
+	"github.com/hankmor/mymedia/tools/wechat-preview/export"
+	"github.com/hankmor/mymedia/tools/wechat-preview/links"
+	"github.com/hankmor/mymedia/tools/wechat-preview/lint"
+	"github.com/hankmor/mymedia/tools/wechat-preview/markdown"
+	"github.com/hankmor/mymedia/tools/wechat-preview/profile"
+	"github.com/hankmor/mymedia/tools/wechat-preview/render"
+	"github.com/hankmor/mymedia/tools/wechat-preview/sanitize"
+	"github.com/hankmor/mymedia/tools/wechat-preview/sensitive"
+	"github.com/hankmor/mymedia/tools/wechat-preview/shortcode"
+	"github.com/hankmor/mymedia/tools/wechat-preview/stats"
+	"github.com/hankmor/mymedia/tools/wechat-preview/theme"
 )
 
 //go:embed web
@@ -33,20 +49,30 @@ var embedFS embed.FS
 
 // Article 文章元数据
 type Article struct {
-	ID        string    `json:"id"`
-	Title     string    `json:"title"`
-	Series    string    `json:"series"`
-	Path      string    `json:"path"`
-	RelPath   string    `json:"relPath"` // 相对 posts 的路径，用于定位图片
-	Slug      string    `json:"slug"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID        string      `json:"id"`
+	Title     string      `json:"title"`
+	Series    string      `json:"series"`
+	Path      string      `json:"path"`
+	RelPath   string      `json:"relPath"` // 相对 posts 的路径，用于定位图片
+	Slug      string      `json:"slug"`
+	UpdatedAt time.Time   `json:"updatedAt"`
+	Stats     stats.Stats `json:"stats"` // 字数、阅读时间与内容清单
+}
+
+// SeriesStats 系列的统计看板
+type SeriesStats struct {
+	Name     string      `json:"name"`
+	Total    stats.Total `json:"total"`
+	Articles []Article   `json:"articles"`
 }
 
 // ArticleDetail 文章详情
 type ArticleDetail struct {
 	Article
-	HTML        string `json:"html"`
-	RawMarkdown string `json:"rawMarkdown"`
+	HTML        string              `json:"html"`
+	RawMarkdown string              `json:"rawMarkdown"`
+	Headings    []*markdown.Heading `json:"headings"`            // 标题树
+	Sanitized   []sanitize.Issue    `json:"sanitized,omitempty"` // HTML 清理结果
 }
 
 var (
@@ -54,22 +80,65 @@ var (
 	projectRoot string // 项目根目录 (自动探测)
 	articles    []Article
 	md          goldmark.Markdown
+	assets      *markdown.AssetStore // 公式等渲染期生成的图片
+	shortcodes  *shortcode.Engine    // Hugo 短代码
+	pipeline    *render.Pipeline     // 预览、API 与发布共用的渲染管线
+	linter      *lint.Linter         // 微信兼容性检查
+	scanner     *sensitive.Scanner   // 敏感词扫描
+	covers      *cover.Generator     // 封面生成
+	statsCache  = stats.NewCache()   // 文章统计，按修改时间缓存
 )
 
-func init() {
-	// 初始化 Markdown 解析器
+// initMarkdown 初始化 Markdown 解析器，依赖配置，需要在 config.Load 之后调用
+func initMarkdown() {
+	assets = markdown.NewAssetStore(config.AppConfig.CacheDir, "/_generated")
+	activeTheme := theme.Get(config.AppConfig.Theme)
+	numbering, ok := markdown.ParseNumbering(config.AppConfig.HeadingNumbering)
+	if !ok {
+		fmt.Printf("Warning: 未知的标题编号方案 %q，标题不编号\n", config.AppConfig.HeadingNumbering)
+	}
+
+	codeBlock := markdown.NewCodeBlock(markdown.CodeBlockOptions{
+		Style:         config.AppConfig.CodeStyle,
+		LineNumbers:   config.AppConfig.CodeLineNumbers,
+		CollapseLines: config.AppConfig.CodeCollapse,
+	})
+	// 图表围栏交给本地命令渲染为图片
+	diagram := markdown.NewDiagram(assets, config.AppConfig.DiagramCommands,
+		time.Duration(config.AppConfig.DiagramTimeout)*time.Second)
+	for _, language := range diagram.Register(codeBlock) {
+		fmt.Printf("Warning: 找不到 %s 图表的渲染命令 %q，该类图表将显示渲染失败提示，可通过 DIAGRAM_COMMANDS 配置或禁用\n",
+			language, config.AppConfig.DiagramCommands[language])
+	}
+
 	md = goldmark.New(
 		goldmark.WithExtensions(
 			extension.GFM,   // GitHub Flavored Markdown
 			extension.Table, // 表格
 			extension.Strikethrough,
 			extension.TaskList,
-			highlighting.NewHighlighting(
-				highlighting.WithStyle("monokai"), // 使用高对比度主题
-				highlighting.WithFormatOptions(
-					chromahtml.WithLineNumbers(false), // 微信里行号可能样式混乱，先关闭
-				),
-			),
+			// 微信兼容的代码块：内联样式 + 显式换行，主题与行号来自配置
+			codeBlock,
+			// 数学公式：服务端渲染为图片，微信无法运行 MathJax
+			markdown.NewMath(assets),
+			// 提示框：> [!NOTE]、> [!tip]- 标题、:::tip，配色来自主题
+			markdown.NewCallout(activeTheme),
+			// 脚注：上标编号 + 文末注释列表，不使用微信中无法跳转的页内锚点
+			markdown.NewFootnote(),
+			// 列表项：li-text / li-bold 结构，避免微信在列表项中插入多余换行
+			markdown.NewListItem(),
+			// 目录：[TOC]、{{< toc >}} 或 frontmatter toc: true
+			markdown.NewTOC(markdown.TOCOptions{
+				MinLevel:  config.AppConfig.TOCMinLevel,
+				MaxLevel:  config.AppConfig.TOCMaxLevel,
+				Numbering: config.AppConfig.TOCNumbering,
+			}),
+			// 图片：独占一段的图片渲染为 figure，说明文字取标题或替代文字
+			markdown.NewFigure(),
+			// 标题：主题装饰 + 编号 (1.1 / 一、 / 01)，frontmatter heading_numbering 可覆盖或关闭
+			markdown.NewHeadingStyle(activeTheme, numbering),
+			// 中文排版：中英文之间加空格、标点全角化，frontmatter pangu: false 关闭
+			markdown.NewPangu(config.AppConfig.Pangu),
 		),
 		goldmark.WithParserOptions(
 			parser.WithAutoHeadingID(),
@@ -80,15 +149,36 @@ func init() {
 			html.WithUnsafe(), // 允许 HTML 标签
 		),
 	)
+
+	shortcodes = shortcode.New(func(source string) (string, error) {
+		var buf strings.Builder
+		err := md.Convert([]byte(source), &buf)
+		return buf.String(), err
+	}, activeTheme)
+	shortcodes.Register("ref", relRefShortcode)
+	shortcodes.Register("relref", relRefShortcode)
 }
 
 func main() {
+	// 子命令 (例如 pangu) 执行完即退出，不启动预览服务
+	if len(os.Args) > 1 {
+		if run, ok := commands[os.Args[1]]; ok {
+			config.Load()
+			if err := run(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	// 1. 解析命令行参数
 	dirFlag := flag.String("dir", "", "Markdown articles directory (default: current directory)")
 	portFlag := flag.String("port", "8080", "Server port")
 	flag.Parse()
 
 	config.Load() // 加载配置
+	initMarkdown()
 
 	// 2. 确定文章目录优先级：CLI > Env > Default(Current Dir)
 	if *dirFlag != "" {
@@ -126,11 +216,13 @@ func main() {
 
 	fmt.Printf("Using Project Root: %s\n", projectRoot)
 
+	initPipeline()
+
 	fmt.Println("\n========================================")
 	fmt.Printf("   Wechat Preview Tool - CLI Mode\n")
 	fmt.Printf("   Articles: %d\n", len(articles))
 	fmt.Printf("   Scanning: %s\n", postsDir)
-	fmt.Println("========================================\n")
+	fmt.Print("========================================\n\n")
 
 	// 初始化 Gin
 	gin.SetMode(gin.ReleaseMode)
@@ -151,6 +243,9 @@ func main() {
 	// 核心修改：映射 posts 目录为静态资源，用于预览本地图片
 	r.Static("/posts-static", postsDir)
 
+	// 渲染期生成的图片 (公式等)
+	r.Static(assets.URLPrefix, assets.Dir)
+
 	// 4. 处理模板 (Embed)
 	// web/templates -> templates
 	templatesFS, _ := fs.Sub(embedFS, "web/templates")
@@ -159,8 +254,16 @@ func main() {
 	// 路由
 	r.GET("/", handleList)
 	r.GET("/article/:id", handleArticle)
+	r.GET("/article/:id/share", handleShare)
+	r.GET("/series/:name", handleSeries)
 	r.GET("/api/articles", apiArticles)
+	r.GET("/api/series", apiSeries)
+	r.GET("/api/series/:name/epub", apiSeriesEPUB)
 	r.GET("/api/articles/:id", apiArticleDetail)
+	r.GET("/api/articles/:id/lint", apiArticleLint)
+	r.GET("/api/articles/:id/sensitive", apiArticleSensitive)
+	r.GET("/api/articles/:id/export", apiArticleExport)
+	r.GET("/api/articles/:id/cover", apiArticleCover)
 	r.POST("/api/publish/:id", handlePublish)
 
 	// 启动服务
@@ -170,6 +273,52 @@ func main() {
 	r.Run(addr)
 }
 
+// initPipeline 创建渲染管线与检查器
+// 图片改写依赖项目根目录，需要在 initMarkdown 与项目根目录探测之后调用
+func initPipeline() {
+	// 项目中的自定义短代码模板 (与 Hugo 相同的目录约定)
+	if err := shortcodes.LoadTemplates(filepath.Join(projectRoot, "layouts", "shortcodes")); err != nil {
+		fmt.Printf("Warning: 加载短代码模板失败: %v\n", err)
+	}
+
+	policy, mode := linkPolicy(), sanitizeMode()
+	scanner = sensitiveScanner(config.AppConfig.SensitiveWords)
+	var err error
+	if covers, err = coverGenerator(); err != nil {
+		// 不影响预览与发布，封面接口与发布结果中会给出原因
+		fmt.Printf("Warning: 封面生成不可用: %v\n", err)
+		covers = cover.Unavailable(err)
+	}
+	pipeline = render.New(
+		render.Normalize(),
+		render.Frontmatter(),
+		render.FrontmatterSwitch("pangu", markdown.SetPangu),
+		render.FrontmatterValue("heading_numbering", headingNumberingOption),
+		render.ProfileTheme(),
+		render.RemoveTitle(),
+		render.TOCMarker(),
+		render.Shortcodes(shortcodes),
+		render.Markdown(md),
+		render.Sanitize(mode),
+		render.FigureNumbers(config.AppConfig.FigureNumbering),
+		render.LinkPolicy(policy, assets),
+		scanner.Highlight(),
+		render.LocalImages(projectRoot, assets),
+		render.UploadImages(projectRoot, assets),
+		render.ProfileMarkdown(),
+		render.Covers(covers, projectRoot, assets),
+		render.ExportImages(assets),
+	)
+	linter = lint.New(lint.Options{
+		ProjectRoot:  projectRoot,
+		Store:        assets,
+		Links:        policy,
+		SanitizeMode: mode,
+		MaxImages:    config.AppConfig.LintMaxImages,
+		MaxCodeLines: config.AppConfig.LintMaxCodeLines,
+	})
+}
+
 // findProjectRoot 向上查找项目根目录
 func findProjectRoot(startPath string) string {
 	curr := startPath
@@ -259,6 +408,7 @@ func scanArticles() error {
 			RelPath:   relPath,
 			Slug:      slug,
 			UpdatedAt: updatedAt,
+			Stats:     statsCache.Get(path, updatedAt, content),
 		})
 
 		return nil
@@ -315,158 +465,362 @@ func extractMetadata(content string) (string, string) {
 	return title, slug
 }
 
-// replaceRelRef replace Hugo relref shortcode with local link
-func replaceRelRef(content string) string {
-	// Match both `ref` and `relref` with or without quotes
-	// {{< relref "path" >}} or {{< ref "path" >}}
-	re := regexp.MustCompile(`\{\{<\s*(?:relref|ref)\s+["']?([^"'\s}]+)["']?\s*>\}\}`)
-	return re.ReplaceAllStringFunc(content, func(match string) string {
-		submatch := re.FindStringSubmatch(match)
-		if len(submatch) < 2 {
-			return match
-		}
-		refPath := submatch[1]
-
-		// Separate path and anchor
-		var anchor string
-		if idx := strings.LastIndex(refPath, "#"); idx != -1 {
-			anchor = refPath[idx:]
-			refPath = refPath[:idx]
-		}
-
-		// Helper to find article by path
-		findArticle := func(path string) *Article {
-			// Normalize path separators
-			path = filepath.ToSlash(path)
-
-			for i := range articles {
-				art := &articles[i]
-				// 1. Check strict RelPath match
-				// Note: art.RelPath uses OS separators, convert to slash for comparison if needed
-				artRelPath := filepath.ToSlash(art.RelPath)
-				if artRelPath == path {
-					return art
-				}
+// relRefShortcode Hugo ref / relref 短代码
+// {{< relref "path" >}} 或 {{< ref path="path" >}}
+func relRefShortcode(c *shortcode.Context) (string, error) {
+	refPath := c.Get(0)
+	if refPath == "" {
+		refPath = c.Get("path")
+	}
+	return resolveRelRef(refPath, c.Page.Target != markdown.TargetPreview), nil
+}
 
-				// 2. Check if path has /posts prefix or similar (common in hugo relref)
-				// path: /posts/full/path.md -> artRelPath: full/path.md
-				if strings.HasSuffix(path, artRelPath) {
-					// Ensure simple suffix match is safe enough
-					// e.g. path="posts/a/b.md", art="a/b.md" -> match
-					// Check boundary to avoid "ba/b.md" matching "a/b.md"
-					marker := "/" + artRelPath
-					if strings.HasSuffix(path, marker) {
-						return art
-					}
-				}
+// resolveRelRef 将 relref 路径解析为本地预览链接或线上地址
+// online 为 true (发布、导出) 且配置了 BaseURL 时生成线上地址
+func resolveRelRef(refPath string, online bool) string {
+	// Separate path and anchor
+	var anchor string
+	if idx := strings.LastIndex(refPath, "#"); idx != -1 {
+		anchor = refPath[idx:]
+		refPath = refPath[:idx]
+	}
+
+	// Helper to find article by path
+	findArticle := func(path string) *Article {
+		// Normalize path separators
+		path = filepath.ToSlash(path)
+
+		for i := range articles {
+			art := &articles[i]
+			// 1. Check strict RelPath match
+			// Note: art.RelPath uses OS separators, convert to slash for comparison if needed
+			artRelPath := filepath.ToSlash(art.RelPath)
+			if artRelPath == path {
+				return art
 			}
-			return nil
-		}
 
-		// Try to find article
-		art := findArticle(refPath)
-
-		// Fallback: try replacing extension (e.g. .adoc -> .md or .md -> .adoc)
-		if art == nil {
-			ext := filepath.Ext(refPath)
-			if ext != "" {
-				base := strings.TrimSuffix(refPath, ext)
-				// Try .md if original was not .md, or .adoc if not .adoc
-				candidates := []string{base + ".md", base + ".adoc"}
-				for _, c := range candidates {
-					if c == refPath {
-						continue
-					}
-					if art = findArticle(c); art != nil {
-						break
-					}
+			// 2. Check if path has /posts prefix or similar (common in hugo relref)
+			// path: /posts/full/path.md -> artRelPath: full/path.md
+			if strings.HasSuffix(path, artRelPath) {
+				// Ensure simple suffix match is safe enough
+				// e.g. path="posts/a/b.md", art="a/b.md" -> match
+				// Check boundary to avoid "ba/b.md" matching "a/b.md"
+				marker := "/" + artRelPath
+				if strings.HasSuffix(path, marker) {
+					return art
 				}
 			}
 		}
+		return nil
+	}
 
-		if art != nil {
-			// 如果配置了 BaseURL，生成完整的 URL
-			if config.AppConfig.BaseURL != "" {
-				// 格式: BaseURL/posts/Series/Slug#Anchor
-				// 注意：这里假设 URL 结构是 /posts/:series/:slug
-				baseURL := strings.TrimRight(config.AppConfig.BaseURL, "/")
-				targetSlug := art.Slug
-				if targetSlug == "" {
-					targetSlug = art.ID // Fallback ID if no slug
+	// Try to find article
+	art := findArticle(refPath)
+
+	// Fallback: try replacing extension (e.g. .adoc -> .md or .md -> .adoc)
+	if art == nil {
+		ext := filepath.Ext(refPath)
+		if ext != "" {
+			base := strings.TrimSuffix(refPath, ext)
+			// Try .md if original was not .md, or .adoc if not .adoc
+			candidates := []string{base + ".md", base + ".adoc"}
+			for _, c := range candidates {
+				if c == refPath {
+					continue
+				}
+				if art = findArticle(c); art != nil {
+					break
 				}
-				return fmt.Sprintf("%s/posts/%s/%s/%s", baseURL, art.Series, targetSlug, anchor)
 			}
+		}
+	}
 
-			// 默认本地预览链接
-			return fmt.Sprintf("/article/%s%s", art.ID, anchor)
+	if art != nil {
+		// 如果配置了 BaseURL，生成完整的 URL
+		if u := onlineURL(art); online && u != "" {
+			return u + anchor
 		}
 
-		// If not found, keep original or show broken link?
-		// Returning the original tag keeps it raw in markdown.
-		// Returning a dead link might be better for preview.
-		return fmt.Sprintf("#relref-not-found-%s", refPath)
-	})
+		// 默认本地预览链接
+		return fmt.Sprintf("/article/%s%s", art.ID, anchor)
+	}
+
+	// If not found, keep original or show broken link?
+	// Returning the original tag keeps it raw in markdown.
+	// Returning a dead link might be better for preview.
+	return fmt.Sprintf("#relref-not-found-%s", refPath)
 }
 
-// removeFrontmatter 移除 Frontmatter
-func removeFrontmatter(content string) string {
-	content = strings.TrimPrefix(content, "\ufeff") // 处理 BOM
-	if !strings.HasPrefix(strings.TrimSpace(content), "---") {
-		return content
+// onlineURL 文章的线上地址，未配置 BaseURL 时为空
+// 格式: BaseURL/posts/Series/Slug/，这里假设博客的 URL 结构是 /posts/:series/:slug
+func onlineURL(art *Article) string {
+	if config.AppConfig.BaseURL == "" {
+		return ""
 	}
+	baseURL := strings.TrimRight(config.AppConfig.BaseURL, "/")
+	targetSlug := art.Slug
+	if targetSlug == "" {
+		targetSlug = art.ID // Fallback ID if no slug
+	}
+	return fmt.Sprintf("%s/posts/%s/%s/", baseURL, art.Series, targetSlug)
+}
 
-	// 统一处理换行符，简化分割逻辑
-	normalized := strings.ReplaceAll(content, "\r\n", "\n")
-	lines := strings.Split(normalized, "\n")
+// headingNumberingOption frontmatter heading_numbering：false 关闭编号，或指定编号方案
+func headingNumberingOption(pc parser.Context, value string) error {
+	scheme, ok := markdown.ParseNumbering(value)
+	if !ok {
+		return fmt.Errorf("未知的标题编号方案 %q", value)
+	}
+	markdown.SetHeadingNumbering(pc, scheme)
+	return nil
+}
 
-	endIndex := -1
-	// 找到第一个非空的 --- 开始
-	startIndex := -1
-	for i, line := range lines {
-		if strings.TrimSpace(line) == "---" {
-			if startIndex == -1 {
-				startIndex = i
-			} else {
-				endIndex = i
-				break
-			}
+// linkPolicy 解析 LINK_POLICY，配置有误时提示并使用默认规则
+func linkPolicy() *links.Policy {
+	policy, err := links.Parse(config.AppConfig.LinkPolicy)
+	if err != nil {
+		fmt.Printf("Warning: LINK_POLICY 配置有误，使用默认规则: %v\n", err)
+		return links.Default()
+	}
+	return policy
+}
+
+// sanitizeMode 解析 SANITIZE_HTML，未知模式时提示并使用 strip
+func sanitizeMode() sanitize.Mode {
+	mode, ok := sanitize.ParseMode(config.AppConfig.SanitizeHTML)
+	if !ok {
+		fmt.Printf("Warning: 未知的 SANITIZE_HTML 模式 %q，使用 strip\n", config.AppConfig.SanitizeHTML)
+	}
+	return mode
+}
+
+// sensitiveScanner 加载敏感词表，读取失败时提示并不做扫描
+func sensitiveScanner(files []string) *sensitive.Scanner {
+	words, err := sensitive.LoadFiles(files...)
+	if err != nil {
+		fmt.Printf("Warning: 加载敏感词表失败: %v\n", err)
+	}
+	return sensitive.NewScanner(words)
+}
+
+// coverGenerator 按 COVER_TEMPLATE 与 COVER_FONT 创建封面生成器，配置有误时提示并使用内置模板或内置字体
+// 模板未设置装饰条颜色时使用主题主色。
+func coverGenerator() (*cover.Generator, error) {
+	tmpl := cover.Default
+	if file := config.AppConfig.CoverTemplate; file != "" {
+		t, err := cover.LoadTemplate(file)
+		if err != nil {
+			fmt.Printf("Warning: 加载封面模板失败，使用内置模板: %v\n", err)
+		} else {
+			tmpl = t
 		}
 	}
+	tmpl.Accent = cmp.Or(tmpl.Accent, theme.Get(config.AppConfig.Theme).Primary)
 
-	if startIndex != -1 && endIndex != -1 {
-		return strings.Join(lines[endIndex+1:], "\n")
+	fonts, err := cover.LoadFonts(config.AppConfig.CoverFonts...)
+	if err != nil {
+		fmt.Printf("Warning: %v，使用内置字体\n", err)
+		if fonts, err = cover.LoadFonts(); err != nil {
+			return nil, err
+		}
+	}
+	if len(fonts.Missing("中文")) > 0 {
+		fmt.Println("Warning: 未打包中文字体，中文标题无法生成封面，见 cover/fonts/README.md")
+	}
+	gen, err := cover.New(tmpl, fonts)
+	if err != nil {
+		fmt.Printf("Warning: 封面模板有误，使用内置模板: %v\n", err)
+		tmpl = cover.Default
+		tmpl.Accent = theme.Get(config.AppConfig.Theme).Primary
+		return cover.New(tmpl, fonts)
 	}
-	return content
+	return gen, nil
 }
 
-// removeTitle 移除内容中的第一个 H1 标题
-func removeTitle(content string) string {
-	lines := strings.Split(content, "\n")
-	var newLines []string
-	removed := false
-	for _, line := range lines {
-		if !removed && strings.HasPrefix(strings.TrimSpace(line), "# ") {
-			removed = true
+// renderArticle 读取文章并按目标渲染
+func renderArticle(article *Article, target markdown.Target) (*render.Document, error) {
+	return renderProfile(article, target, nil)
+}
+
+// renderProfile 按发布平台配置渲染文章，p 为 nil 时与微信公众号相同
+func renderProfile(article *Article, target markdown.Target, p *profile.Profile) (*render.Document, error) {
+	content, err := os.ReadFile(article.Path)
+	if err != nil {
+		return nil, err
+	}
+	doc := render.NewDocument(article.Path, article.Title, string(content), target)
+	doc.Profile = p
+	if article.Series != "其他" { // 文章目录下直接存放的文章不属于任何系列
+		doc.Series = article.Series
+	}
+	if err := pipeline.Render(doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// currentArticles 文章列表的副本，统计信息按文件修改时间刷新
+// 启动后编辑过的文章无需重启即可看到新的字数；返回副本避免并发请求修改全局列表。
+func currentArticles() []Article {
+	list := make([]Article, len(articles))
+	for i, article := range articles {
+		if s, err := statsCache.File(article.Path); err == nil {
+			article.Stats = s
+		}
+		list[i] = article
+	}
+	return list
+}
+
+// seriesStats 按系列分组统计，系列按名称排序
+func seriesStats(list []Article) []*SeriesStats {
+	index := make(map[string]*SeriesStats)
+	var result []*SeriesStats
+	for _, article := range list {
+		s, ok := index[article.Series]
+		if !ok {
+			s = &SeriesStats{Name: article.Series}
+			index[article.Series] = s
+			result = append(result, s)
+		}
+		s.Articles = append(s.Articles, article)
+		s.Total.Add(article.Stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// seriesIndex 系列目录下的元数据文件 (Hugo 的 section 页面)，不作为文章
+const seriesIndex = "_index.md"
+
+// seriesBook 将系列中的文章按顺序渲染为电子书
+// 章节顺序与 Hugo 一致：先按 weight (未设置的排在最后)，再按 date，最后按文件路径；
+// 书名、作者、简介与封面取自系列目录下 _index.md 的 title、author、description、cover 字段。
+func seriesBook(series string) (*epub.Book, error) {
+	type chapter struct {
+		article *Article
+		doc     *render.Document
+		weight  int
+	}
+	var chapters []chapter
+	for i := range articles {
+		article := &articles[i]
+		if article.Series != series || filepath.Base(article.Path) == seriesIndex {
 			continue
 		}
-		newLines = append(newLines, line)
+		doc, err := renderArticle(article, render.Export)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", article.RelPath, err)
+		}
+		weight, _ := strconv.Atoi(doc.Frontmatter["weight"])
+		chapters = append(chapters, chapter{article: article, doc: doc, weight: weight})
+	}
+	if len(chapters) == 0 {
+		return nil, fmt.Errorf("系列不存在或没有文章: %s", series)
+	}
+	sort.SliceStable(chapters, func(i, j int) bool {
+		a, b := chapters[i], chapters[j]
+		if (a.weight == 0) != (b.weight == 0) {
+			return a.weight != 0
+		}
+		if a.weight != b.weight {
+			return a.weight < b.weight
+		}
+		if da, db := a.doc.Frontmatter["date"], b.doc.Frontmatter["date"]; da != db {
+			return da < db
+		}
+		return a.article.RelPath < b.article.RelPath
+	})
+
+	dir := filepath.Join(postsDir, series)
+	meta := make(map[string]string)
+	if content, err := os.ReadFile(filepath.Join(dir, seriesIndex)); err == nil {
+		front, _ := render.SplitFrontmatter(string(content))
+		meta = render.ParseFrontmatter(front)
+	}
+	book := &epub.Book{
+		Title:       cmp.Or(meta["title"], series),
+		Author:      meta["author"],
+		Language:    meta["language"],
+		Description: cmp.Or(meta["description"], meta["summary"]),
+		Cover:       seriesCover(dir, cmp.Or(meta["cover"], meta["image"])),
+		NavLevel:    config.AppConfig.TOCMaxLevel,
+	}
+	for _, ch := range chapters {
+		book.Chapters = append(book.Chapters, epub.Chapter{
+			Title:    ch.article.Title,
+			HTML:     ch.doc.HTML,
+			Headings: ch.doc.Headings,
+			Assets:   ch.doc.Assets,
+			Links:    articleLinks(ch.article),
+		})
+		book.Modified = latest(book.Modified, ch.article.UpdatedAt)
+	}
+	return book, nil
+}
+
+// articleLinks 渲染结果中指向文章的地址：本地预览地址，以及配置了 BaseURL 时的线上地址 (与 resolveRelRef 一致)
+func articleLinks(art *Article) []string {
+	links := []string{fmt.Sprintf("/article/%s", art.ID)}
+	if u := onlineURL(art); u != "" {
+		links = append(links, u)
+	}
+	return links
+}
+
+// seriesCover 解析封面图片：以 / 开头时相对项目的 static 目录或项目根目录，否则相对系列目录
+func seriesCover(dir, src string) string {
+	if src == "" {
+		return ""
+	}
+	file, err := cover.Resolve(dir, projectRoot, src)
+	if err != nil {
+		fmt.Printf("Warning: EPUB 封面需要本地图片: %v\n", err)
+	}
+	return file
+}
+
+// latest 较晚的时间
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
 	}
-	return strings.Join(newLines, "\n")
+	return a
 }
 
 // handleList 文章列表页面
 func handleList(c *gin.Context) {
 	// 按系列分组
+	list := currentArticles()
 	grouped := make(map[string][]Article)
-	for _, article := range articles {
+	totals := make(map[string]stats.Total)
+	for _, article := range list {
 		grouped[article.Series] = append(grouped[article.Series], article)
+		total := totals[article.Series]
+		total.Add(article.Stats)
+		totals[article.Series] = total
 	}
 
 	c.HTML(200, "list.html", gin.H{
 		"groupedArticles": grouped,
+		"totals":          totals,
 	})
 }
 
+// handleSeries 系列统计看板页面
+func handleSeries(c *gin.Context) {
+	name := c.Param("name")
+	for _, s := range seriesStats(currentArticles()) {
+		if s.Name == name {
+			c.HTML(200, "series.html", s)
+			return
+		}
+	}
+	c.String(404, "系列不存在")
+}
+
 // handleArticle 文章详情页面
 func handleArticle(c *gin.Context) {
 	fmt.Println(">>> Entering handleArticle")
@@ -486,99 +840,241 @@ func handleArticle(c *gin.Context) {
 		return
 	}
 
-	// 读取并渲染 Markdown
-	content, err := os.ReadFile(article.Path)
+	doc, err := renderArticle(article, render.Preview)
 	if err != nil {
-		c.String(500, "读取文章失败")
+		c.String(500, "渲染文章失败")
 		return
 	}
 
-	// 1. 移除 Frontmatter
-	contentStr := removeFrontmatter(string(content))
-
-	// 2. 移除标题 (H1)
-	markdownContent := removeTitle(contentStr)
-
-	// 3. 处理 Hugo relref
-	markdownContent = replaceRelRef(markdownContent)
+	findings := linter.Lint(doc)
+	_, hasCover := doc.Frontmatter["cover"]
+	c.HTML(200, "article.html", gin.H{
+		"title":      article.Title,
+		"html":       template.HTML(doc.HTML),
+		"id":         article.ID,
+		"series":     article.Series,
+		"lint":       findings,
+		"counts":     lint.Count(findings),
+		"profiles":   profile.All(),
+		"cover":      doc.Frontmatter["cover"],
+		"hasCover":   hasCover,
+		"coverSizes": cover.Sizes,
+	})
+}
 
-	var buf strings.Builder
-	if err := md.Convert([]byte(markdownContent), &buf); err != nil {
-		c.String(500, "渲染文章失败")
+// handlePublish 处理发布请求，?profile= 指定发布平台 (wechat、zhihu、juejin、csdn、toutiao)
+func handlePublish(c *gin.Context) {
+	id := c.Param("id")
+	var article *Article
+	for i := range articles {
+		if articles[i].ID == id {
+			article = &articles[i]
+			break
+		}
+	}
+	if article == nil {
+		c.JSON(404, gin.H{"error": "文章不存在"})
 		return
 	}
 
-	// 1. 列表项优化 (strong -> span, li wrap)
-	htmlContent := buf.String()
+	// 发布平台，默认为微信公众号
+	p, err := profile.Get(c.Query("profile"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
 
-	// 先替换 strong 为 span class="li-bold"
-	reStrong := regexp.MustCompile(`<li><strong>([^<]+)</strong>`)
-	htmlContent = reStrong.ReplaceAllString(htmlContent, `<li><span class="li-bold">$1</span>`)
+	// 微信兼容性检查存在错误时阻止发布，确认后可通过 ?force=1 跳过
+	if force, _ := strconv.ParseBool(c.Query("force")); p.Lint && !force {
+		preview, err := renderArticle(article, render.Preview)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		if findings := linter.Lint(preview); lint.HasErrors(findings) {
+			c.JSON(422, gin.H{
+				"success": false,
+				"error":   "兼容性检查未通过",
+				"lint":    findings,
+			})
+			return
+		}
+	}
 
-	// 将 li 内部的所有内容包裹在 <span class="li-text"> 中
-	reLiContent := regexp.MustCompile(`<li>(.*?)</li>`)
-	htmlContent = reLiContent.ReplaceAllString(htmlContent, `<li><span class="li-text">$1</span></li>`)
+	// 渲染并上传图片 (发布时 relref 按 BaseURL 生成线上地址)
+	doc, err := renderProfile(article, render.Publish, p)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	result := doc.Publish
 
-	// 4. 本地图片路径修正 (动态解析)
-	// 假设图片引用是 relative path: ![](./images/foo.png) or ![](images/foo.png) or even ../../../static/foo.png
-	articleDir := filepath.Dir(article.Path)
+	c.JSON(200, gin.H{
+		"success": true,
+		"profile": p,
+		"content": map[string]string{
+			"markdown": result.PublishContent,
+			"html":     doc.HTML, // 返回已处理的 HTML
+		},
+		"uploaded":   result.UploadedImages,
+		"covers":     result.Covers,
+		"coverError": result.CoverError,
+		"links":      doc.Links,
+		"sanitized":  doc.Sanitized,
+		"logs":       result.Errors,
+	})
+}
 
-	// 调试日志：打印文章目录
-	fmt.Printf("Debug: Article Dir for %s is %s\n", article.ID, articleDir)
+// apiArticles API: 文章列表
+func apiArticles(c *gin.Context) {
+	c.JSON(200, currentArticles())
+}
 
-	// 正则匹配 img src，排除 http 开头的
-	reImg := regexp.MustCompile(`src=["']([^"']+)["']`)
+// apiSeries API: 各系列的统计合计
+func apiSeries(c *gin.Context) {
+	series := seriesStats(currentArticles())
+	if series == nil {
+		series = []*SeriesStats{}
+	}
+	c.JSON(200, series)
+}
 
-	htmlContent = reImg.ReplaceAllStringFunc(htmlContent, func(match string) string {
-		// match: src="./images/foo.png"
-		parts := strings.SplitN(match, "=", 2)
-		if len(parts) != 2 {
-			return match
+// apiArticleCover API: 文章封面 (PNG)，?size=wide (900×383，默认) 或 square (1:1)
+// frontmatter 设置了本地 cover 时按 cover_focus 裁剪，网络图片重定向到原地址，否则由标题与系列名生成。
+func apiArticleCover(c *gin.Context) {
+	var article *Article
+	for i := range articles {
+		if articles[i].ID == c.Param("id") {
+			article = &articles[i]
+			break
 		}
-		quote := parts[1][0:1]
-		src := parts[1][1 : len(parts[1])-1]
+	}
+	if article == nil {
+		c.JSON(404, gin.H{"error": "文章不存在"})
+		return
+	}
+	size, err := cover.ParseSize(c.Query("size"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	content, err := os.ReadFile(article.Path)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	front, _ := render.SplitFrontmatter(string(content))
+	src, err := coverSource(article, render.ParseFrontmatter(front))
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	if src.Remote != "" {
+		c.Redirect(302, src.Remote)
+		return
+	}
+	name, err := src.Save(covers, assets, size)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.File(assets.Path(name))
+}
 
-		// 忽略绝对路径（HTTP）
-		if strings.HasPrefix(src, "http") || strings.HasPrefix(src, "//") {
-			return match
+// coverSource 文章的封面来源，系列名不包括文章目录下直接存放的文章所属的 "其他"
+func coverSource(article *Article, front map[string]string) (cover.Source, error) {
+	info := cover.Info{Title: article.Title}
+	if article.Series != "其他" {
+		info.Series = article.Series
+	}
+	return cover.FromFrontmatter(front, filepath.Dir(article.Path), projectRoot, info)
+}
+
+// handleShare 分享卡片预览：订阅号消息列表中 2.35:1 的封面与聊天中 1:1 的缩略图，以及标题与摘要
+func handleShare(c *gin.Context) {
+	var article *Article
+	for i := range articles {
+		if articles[i].ID == c.Param("id") {
+			article = &articles[i]
+			break
 		}
+	}
+	if article == nil {
+		c.String(404, "文章不存在")
+		return
+	}
+	doc, err := renderArticle(article, render.Preview)
+	if err != nil {
+		c.String(500, "渲染文章失败")
+		return
+	}
+	digest, auto := shareDigest(doc)
+	src, err := coverSource(article, doc.Frontmatter)
+	var coverError string
+	if err != nil {
+		coverError = err.Error()
+	}
+	c.HTML(200, "share.html", gin.H{
+		"title":      article.Title,
+		"id":         article.ID,
+		"digest":     digest,
+		"autoDigest": auto,
+		"cover":      doc.Frontmatter["cover"],
+		"remote":     src.Remote,
+		"focus":      src.Focus.String(),
+		"position":   fmt.Sprintf("%g%% %g%%", src.Focus.X*100, src.Focus.Y*100), // 网络图片用 CSS 近似裁剪
+		"coverError": coverError,
+	})
+}
 
-		// 尝试解析绝对路径
-		var absImgPath string
-		if filepath.IsAbs(src) {
-			absImgPath = src
-		} else {
-			absImgPath = filepath.Join(articleDir, src)
-		}
-		absImgPath = filepath.Clean(absImgPath)
-
-		// 检查是否在 ProjectRoot 内
-		if strings.HasPrefix(absImgPath, projectRoot) {
-			relPath, err := filepath.Rel(projectRoot, absImgPath)
-			if err == nil {
-				// 构造新 URL: /_local_fs/relPath
-				// 注意 windows 下 filepath.Rel 返回反斜杠，需要转换
-				relPath = filepath.ToSlash(relPath)
-				newSrc := fmt.Sprintf("/_local_fs/%s", relPath)
-				fmt.Printf("Debug: Rewriting %s -> %s\n", src, newSrc)
-				return fmt.Sprintf("src=%s%s%s", quote, newSrc, quote)
-			}
+// autoDigestLength 未设置摘要时，微信截取正文开头的字数
+const autoDigestLength = 54
+
+var reTag = regexp.MustCompile(`<[^>]*>`)
+
+// shareDigest 分享卡片上的摘要：frontmatter description 或 summary，未设置时与微信一样截取正文开头
+func shareDigest(doc *render.Document) (digest string, auto bool) {
+	if digest := cmp.Or(doc.Frontmatter["description"], doc.Frontmatter["summary"]); digest != "" {
+		return digest, false
+	}
+	text := strings.Join(strings.Fields(stdhtml.UnescapeString(reTag.ReplaceAllString(doc.HTML, " "))), " ")
+	if runes := []rune(text); len(runes) > autoDigestLength {
+		text = strings.TrimSpace(string(runes[:autoDigestLength]))
+	}
+	return text, true
+}
+
+// apiArticleLint API: 文章的微信兼容性检查结果
+func apiArticleLint(c *gin.Context) {
+	id := c.Param("id")
+	var article *Article
+	for i := range articles {
+		if articles[i].ID == id {
+			article = &articles[i]
+			break
 		}
+	}
+	if article == nil {
+		c.JSON(404, gin.H{"error": "文章不存在"})
+		return
+	}
 
-		fmt.Printf("Debug: Failed to rewrite image path: %s (Root: %s)\n", src, projectRoot)
-		return match
-	})
+	doc, err := renderArticle(article, render.Preview)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "渲染文章失败"})
+		return
+	}
 
-	c.HTML(200, "article.html", gin.H{
-		"title":  article.Title,
-		"html":   template.HTML(htmlContent),
-		"id":     article.ID,
-		"series": article.Series,
+	findings := linter.Lint(doc)
+	c.JSON(200, gin.H{
+		"findings": findings,
+		"counts":   lint.Count(findings),
+		"blocking": lint.HasErrors(findings),
 	})
 }
 
-// handlePublish 处理发布请求
-func handlePublish(c *gin.Context) {
+// apiArticleSensitive API: 文章中命中的敏感词
+func apiArticleSensitive(c *gin.Context) {
 	id := c.Param("id")
 	var article *Article
 	for i := range articles {
@@ -592,45 +1088,90 @@ func handlePublish(c *gin.Context) {
 		return
 	}
 
-	// 调用发布服务
-	// projectRoot 需要绝对路径? or relative is fine
-	// 我们用 ..
-	result, err := services.PublishArticle(article.Path, projectRoot)
+	content, err := os.ReadFile(article.Path)
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
+	matches := scanner.Scan(string(content))
+	if matches == nil {
+		matches = []sensitive.Match{}
+	}
+	c.JSON(200, gin.H{
+		"enabled": !scanner.Empty(),
+		"matches": matches,
+	})
+}
 
-	// 移除 Frontmatter (发布内容也不应包含)
-	result.PublishContent = removeFrontmatter(result.PublishContent)
+// apiArticleExport API: 导出为单个 HTML 文件 (?format=html)、压缩包 (?format=zip) 或 Word 文档 (?format=docx)
+func apiArticleExport(c *gin.Context) {
+	id := c.Param("id")
+	var article *Article
+	for i := range articles {
+		if articles[i].ID == id {
+			article = &articles[i]
+			break
+		}
+	}
+	if article == nil {
+		c.JSON(404, gin.H{"error": "文章不存在"})
+		return
+	}
+
+	format, err := export.ParseFormat(c.Query("format"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
 
-	// 渲染 Markdown 为 HTML 供复制
-	var buf strings.Builder
-	// 移除标题
-	publishContent := removeTitle(result.PublishContent)
-	md.Convert([]byte(publishContent), &buf)
+	doc, err := renderArticle(article, render.Export)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "渲染文章失败"})
+		return
+	}
 
-	// 同样应用列表项优化
-	htmlContent := buf.String()
-	reStrong := regexp.MustCompile(`<li><strong>([^<]+)</strong>`)
-	htmlContent = reStrong.ReplaceAllString(htmlContent, `<li><span class="li-bold">$1</span>`)
-	reLiContent := regexp.MustCompile(`<li>(.*?)</li>`)
-	htmlContent = reLiContent.ReplaceAllString(htmlContent, `<li><span class="li-text">$1</span></li>`)
+	// 页面样式与预览页相同，主题配色已在渲染时写入内联样式
+	css, err := fs.ReadFile(embedFS, "web/static/css/wechat.css")
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	opts := export.Options{CSS: string(css), Theme: theme.Get(config.AppConfig.Theme).Name}
+
+	var buf bytes.Buffer
+	switch format {
+	case export.Zip:
+		err = export.WriteZip(&buf, doc, article.Slug, opts)
+	case export.Docx:
+		err = docx.Write(&buf, doc)
+	default:
+		err = export.WriteHTML(&buf, doc, opts)
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
 
-	c.JSON(200, gin.H{
-		"success": true,
-		"content": map[string]string{
-			"markdown": result.PublishContent,
-			"html":     htmlContent, // 返回已处理的 HTML
-		},
-		"uploaded": result.UploadedImages,
-		"logs":     result.Errors,
-	})
+	filename := article.Slug + "." + string(format)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Data(200, format.ContentType(), buf.Bytes())
 }
 
-// apiArticles API: 文章列表
-func apiArticles(c *gin.Context) {
-	c.JSON(200, articles)
+// apiSeriesEPUB API: 将系列导出为 EPUB 电子书
+func apiSeriesEPUB(c *gin.Context) {
+	name := c.Param("name")
+	book, err := seriesBook(name)
+	if err != nil {
+		c.JSON(404, gin.H{"error": err.Error()})
+		return
+	}
+	var buf bytes.Buffer
+	if err := epub.Write(&buf, book); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + ".epub"}))
+	c.Data(200, "application/epub+zip", buf.Bytes())
 }
 
 // apiArticleDetail API: 文章详情
@@ -651,30 +1192,17 @@ func apiArticleDetail(c *gin.Context) {
 		return
 	}
 
-	// 读取内容
-	content, err := os.ReadFile(article.Path)
+	doc, err := renderArticle(article, render.Preview)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "读取文章失败"})
-		return
-	}
-
-	// 清洗内容：移除 Frontmatter 和 H1 标题
-	contentStr := removeFrontmatter(string(content))
-	contentStr = removeTitle(contentStr)
-
-	// 处理 relref (仅用于渲染HTML，RawMarkdown保持原样或也替换？保持原样更便于编辑)
-	htmlMarkdown := replaceRelRef(contentStr)
-
-	// 渲染 HTML
-	var buf strings.Builder
-	if err := md.Convert([]byte(htmlMarkdown), &buf); err != nil { // 使用处理后的 markdown
 		c.JSON(500, gin.H{"error": "渲染文章失败"})
 		return
 	}
 
 	c.JSON(200, ArticleDetail{
 		Article:     *article,
-		HTML:        buf.String(),
-		RawMarkdown: contentStr,
+		HTML:        doc.HTML,
+		RawMarkdown: doc.Body, // 未经短代码处理，便于编辑
+		Headings:    doc.Headings,
+		Sanitized:   doc.Sanitized,
 	})
 }
@@ -0,0 +1,58 @@
+package markdown
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// AssetStore 渲染过程中生成的图片 (公式、图表等) 的本地缓存
+// 预览时通过 URLPrefix 访问，发布时由上传服务按相同的相对路径上传到图床
+type AssetStore struct {
+	Dir       string // 本地缓存目录
+	URLPrefix string // 预览访问前缀，例如 "/_generated"
+}
+
+// NewAssetStore 创建生成资源缓存
+func NewAssetStore(dir, urlPrefix string) *AssetStore {
+	return &AssetStore{Dir: dir, URLPrefix: strings.TrimRight(urlPrefix, "/")}
+}
+
+// Name 根据资源类型与内容摘要生成相对路径，例如 "math/3f2a...png"
+func (s *AssetStore) Name(kind, content, ext string) string {
+	sum := sha1.Sum([]byte(content))
+	return path.Join(kind, hex.EncodeToString(sum[:])[:16]+ext)
+}
+
+// Path 相对路径对应的本地文件
+func (s *AssetStore) Path(name string) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(name))
+}
+
+// URL 相对路径对应的预览地址
+func (s *AssetStore) URL(name string) string {
+	return s.URLPrefix + "/" + name
+}
+
+// NameFromURL 从预览地址解析出相对路径，非生成资源返回 false
+func (s *AssetStore) NameFromURL(url string) (string, bool) {
+	return strings.CutPrefix(url, s.URLPrefix+"/")
+}
+
+// Exists 资源是否已生成
+func (s *AssetStore) Exists(name string) bool {
+	_, err := os.Stat(s.Path(name))
+	return err == nil
+}
+
+// Save 写入资源文件
+func (s *AssetStore) Save(name string, data []byte) error {
+	p := s.Path(name)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0o644)
+}
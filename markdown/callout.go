@@ -0,0 +1,211 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/hankmor/mymedia/tools/wechat-preview/theme"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindCalloutBlock 提示框节点类型
+var KindCalloutBlock = ast.NewNodeKind("CalloutBlock")
+
+// CalloutBlock 提示框，子节点为提示框正文
+type CalloutBlock struct {
+	ast.BaseBlock
+	CalloutType string // note、tip、warning 等，未规范化
+	Title       string // 自定义标题，为空时使用主题中的默认标题
+	Fold        string // Obsidian 折叠标记 "+" / "-"，微信中无法折叠，始终展开
+	fence       int    // ::: 容器的冒号数量
+}
+
+// Kind 实现 ast.Node
+func (n *CalloutBlock) Kind() ast.NodeKind { return KindCalloutBlock }
+
+// Dump 实现 ast.Node
+func (n *CalloutBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"CalloutType": n.CalloutType, "Title": n.Title}, nil)
+}
+
+// Callout 提示框扩展
+// 支持三种写法，渲染为带图标与标题的彩色内联样式方框，配色来自主题：
+//   - GitHub Alerts: > [!NOTE]
+//   - Obsidian Callouts: > [!tip]- 自定义标题
+//   - 容器: :::tip 自定义标题 ... :::
+type Callout struct {
+	theme *theme.Theme
+}
+
+// NewCallout 创建提示框扩展
+func NewCallout(t *theme.Theme) *Callout {
+	return &Callout{theme: t}
+}
+
+// Extend 实现 goldmark.Extender
+func (c *Callout) Extend(md goldmark.Markdown) {
+	md.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(&calloutContainerParser{}, 90)),
+		parser.WithASTTransformers(util.Prioritized(&calloutTransformer{}, 100)),
+	)
+	md.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(c, 100),
+	))
+}
+
+// RegisterFuncs 实现 renderer.NodeRenderer
+func (c *Callout) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindCalloutBlock, c.render)
+}
+
+func (c *Callout) render(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*CalloutBlock)
+	if entering {
+		w.WriteString(CalloutOpen(c.theme, n.CalloutType, n.Title))
+		w.WriteString("\n")
+	} else {
+		w.WriteString(CalloutClose + "\n")
+	}
+	return ast.WalkContinue, nil
+}
+
+// CalloutClose 提示框结束标签
+const CalloutClose = `</section>`
+
+// CalloutOpen 提示框开始标签与标题行，正文由调用方写入，最后写入 CalloutClose
+// 短代码 notice / admonition 也使用该函数，保证两种写法外观一致
+func CalloutOpen(t *theme.Theme, kind, title string) string {
+	style := t.Callout(kind)
+	kind, ok := theme.CalloutKind(kind)
+	if !ok {
+		kind = "note"
+	}
+	if title == "" {
+		title = style.Label
+	}
+	return fmt.Sprintf(`<section class="callout callout-%s" style="margin: 20px 0; padding: 12px 16px; border-left: 4px solid %s; border-radius: 4px; background: %s; font-size: 15px; line-height: 1.75;">`+
+		`<p class="callout-title" style="margin: 0 0 6px; font-weight: bold; color: %s;">%s %s</p>`,
+		kind, style.Color, style.Background, style.Color, style.Icon, html.EscapeString(title))
+}
+
+// calloutMarker 引用块首行的 [!type]，可带折叠标记与标题
+var calloutMarker = regexp.MustCompile(`^\[!([A-Za-z][\w-]*)\]([+-]?)[ \t]*(.*)$`)
+
+// calloutTransformer 将首行为 [!type] 的引用块转换为提示框
+type calloutTransformer struct{}
+
+func (t *calloutTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var quotes []*ast.Blockquote
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if q, ok := n.(*ast.Blockquote); ok && entering {
+			quotes = append(quotes, q)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, q := range quotes {
+		para, ok := q.FirstChild().(*ast.Paragraph)
+		if !ok || para.Lines().Len() == 0 {
+			continue
+		}
+		first := para.Lines().At(0)
+		m := calloutMarker.FindSubmatch(bytes.TrimSpace(first.Value(source)))
+		if m == nil {
+			continue
+		}
+
+		callout := &CalloutBlock{
+			CalloutType: string(m[1]),
+			Fold:        string(m[2]),
+			Title:       strings.TrimSpace(string(m[3])),
+		}
+		removeFirstLine(para)
+		if para.FirstChild() == nil {
+			q.RemoveChild(q, para)
+		}
+		for child := q.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			callout.AppendChild(callout, child)
+			child = next
+		}
+		q.Parent().ReplaceChild(q.Parent(), q, callout)
+	}
+}
+
+// removeFirstLine 删除段落第一行对应的行内节点 (直到第一个换行)
+func removeFirstLine(para *ast.Paragraph) {
+	for child := para.FirstChild(); child != nil; {
+		next := child.NextSibling()
+		para.RemoveChild(para, child)
+		if t, ok := child.(*ast.Text); ok && (t.SoftLineBreak() || t.HardLineBreak()) {
+			break
+		}
+		child = next
+	}
+}
+
+// calloutContainerParser 解析 :::type 标题 ... ::: 容器
+type calloutContainerParser struct{}
+
+func (p *calloutContainerParser) Trigger() []byte {
+	return []byte{':'}
+}
+
+func (p *calloutContainerParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 {
+		return nil, parser.NoChildren
+	}
+	rest := line[pos:]
+	fence := 0
+	for fence < len(rest) && rest[fence] == ':' {
+		fence++
+	}
+	if fence < 3 {
+		return nil, parser.NoChildren
+	}
+
+	fields := strings.Fields(string(rest[fence:]))
+	if len(fields) == 0 {
+		return nil, parser.NoChildren
+	}
+	node := &CalloutBlock{
+		CalloutType: fields[0],
+		Title:       strings.Join(fields[1:], " "),
+		fence:       fence,
+	}
+	reader.Advance(segment.Len() - 1)
+	return node, parser.HasChildren
+}
+
+func (p *calloutContainerParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	n := node.(*CalloutBlock)
+	line, segment := reader.PeekLine()
+	trimmed := bytes.TrimSpace(line)
+	// 结束标记的冒号数量需与开始标记一致，嵌套容器可用更多的冒号区分
+	if len(trimmed) == n.fence && bytes.Count(trimmed, []byte(":")) == n.fence {
+		reader.Advance(segment.Len() - 1)
+		return parser.Close
+	}
+	return parser.Continue | parser.HasChildren
+}
+
+func (p *calloutContainerParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (p *calloutContainerParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (p *calloutContainerParser) CanAcceptIndentedLine() bool {
+	return false
+}
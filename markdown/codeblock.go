@@ -0,0 +1,245 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// codeFontFamily 代码块字体，微信会丢弃外部 CSS，需要写在行内
+const codeFontFamily = "Menlo, Monaco, Consolas, 'Courier New', monospace"
+
+// CodeBlockOptions 代码块渲染配置
+type CodeBlockOptions struct {
+	Style       string // Chroma 主题名，例如 "monokai"、"github"
+	LineNumbers bool   // 是否显示行号
+	// CollapseLines 超过该行数的代码块自动折叠为固定高度，0 表示不自动折叠
+	CollapseLines int
+}
+
+// CodeBlock 微信兼容的代码块渲染扩展
+// 微信编辑器会吞掉 <pre> 中的换行和缩进，因此这里直接输出：
+// 1. 每行之间使用显式 <br>
+// 2. 空格与 Tab 全部转为 &nbsp;
+// 3. 外层 white-space: nowrap + overflow-x: auto 实现横向滚动
+// 4. 行号使用行内 span 而不是 table，避免粘贴后错位
+type CodeBlock struct {
+	options  CodeBlockOptions
+	style    *chroma.Style
+	handlers map[string]FenceHandler
+}
+
+// FenceHandler 按语言接管代码围栏的渲染 (如图表)，handled 为 false 时按普通代码块渲染
+type FenceHandler func(info FenceInfo, code string) (html string, handled bool)
+
+// NewCodeBlock 创建代码块渲染扩展，未知主题回退到 monokai
+func NewCodeBlock(options CodeBlockOptions) *CodeBlock {
+	style, ok := styles.Registry[options.Style]
+	if !ok {
+		style = styles.Get("monokai")
+	}
+	return &CodeBlock{options: options, style: style, handlers: make(map[string]FenceHandler)}
+}
+
+// Handle 为指定语言注册围栏渲染钩子
+func (c *CodeBlock) Handle(language string, handler FenceHandler) {
+	c.handlers[language] = handler
+}
+
+// Extend 实现 goldmark.Extender
+func (c *CodeBlock) Extend(m goldmark.Markdown) {
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(c, 100),
+	))
+}
+
+// RegisterFuncs 实现 renderer.NodeRenderer
+func (c *CodeBlock) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, c.renderCodeBlock)
+	reg.Register(ast.KindCodeBlock, c.renderCodeBlock)
+}
+
+func (c *CodeBlock) renderCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+
+	lines := node.Lines()
+	var info FenceInfo
+	if fenced, ok := node.(*ast.FencedCodeBlock); ok && fenced.Info != nil {
+		info = ParseFenceInfo(string(fenced.Info.Segment.Value(source)), lines.Len())
+	}
+
+	var code strings.Builder
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		code.Write(line.Value(source))
+	}
+
+	if handler, ok := c.handlers[info.Language]; ok {
+		if out, handled := handler(info, code.String()); handled {
+			w.WriteString(out)
+			return ast.WalkSkipChildren, nil
+		}
+	}
+
+	w.WriteString(c.Render(info, code.String()))
+	return ast.WalkSkipChildren, nil
+}
+
+// Render 将代码渲染为带内联样式的 HTML 片段
+func (c *CodeBlock) Render(info FenceInfo, code string) string {
+	code = strings.TrimRight(code, "\n")
+
+	bg := c.style.Get(chroma.Background)
+	background := "#272822"
+	if bg.Background.IsSet() {
+		background = bg.Background.String()
+	}
+	foreground := "#f8f8f2"
+	if bg.Colour.IsSet() {
+		foreground = bg.Colour.String()
+	}
+	muted := c.style.Get(chroma.Comment)
+	mutedColor := "#75715e"
+	if muted.Colour.IsSet() {
+		mutedColor = muted.Colour.String()
+	}
+	// 高亮行背景需要区分深浅色主题
+	highlightBg := "rgba(255,255,255,0.12)"
+	if bg.Background.IsSet() && bg.Background.Brightness() > 0.5 {
+		highlightBg = "rgba(0,0,0,0.08)"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<section class="code-block" style="margin: 20px 0; border-radius: 6px; overflow: hidden; background: %s;">`, background)
+
+	// 标题栏：文件名在左，语言在右
+	if info.Title != "" || info.Language != "" {
+		fmt.Fprintf(&b, `<section class="code-header" style="display: flex; justify-content: space-between; padding: 6px 16px; font-size: 12px; line-height: 1.5; color: %s; background: %s; border-bottom: 1px solid rgba(128,128,128,0.25); font-family: %s;">`,
+			mutedColor, background, codeFontFamily)
+		fmt.Fprintf(&b, `<span style="color: %s;">%s</span>`, foreground, html.EscapeString(info.Title))
+		fmt.Fprintf(&b, `<span>%s</span>`, html.EscapeString(info.Language))
+		b.WriteString(`</section>`)
+	}
+
+	lines := c.tokenize(info.Language, code)
+
+	// 折叠：超过阈值或显式声明时限制高度，在区域内纵向滚动
+	codeStyle := fmt.Sprintf("display: block; overflow-x: auto; padding: 12px 16px; white-space: nowrap; font-family: %s; font-size: 13px; line-height: 1.6; color: %s; background: transparent;",
+		codeFontFamily, foreground)
+	if info.Collapse || (c.options.CollapseLines > 0 && len(lines) > c.options.CollapseLines) {
+		codeStyle += " max-height: 400px; overflow-y: auto;"
+	}
+
+	fmt.Fprintf(&b, `<pre style="margin: 0; padding: 0; border: none; border-radius: 0; background: %s;">`, background)
+	fmt.Fprintf(&b, `<code style="%s">`, codeStyle)
+
+	isDiff := info.Language == "diff" || info.Language == "patch"
+	// 有行级背景时每行都包成占满宽度的 inline-block，保证背景色对齐
+	lineBlocks := isDiff || len(info.Highlight) > 0
+	width := len(fmt.Sprint(len(lines)))
+	for i, tokens := range lines {
+		if i > 0 {
+			b.WriteString("<br>")
+		}
+		if lineBlocks {
+			lineBg := "transparent"
+			if isDiff {
+				lineBg = diffLineBackground(tokens)
+			}
+			if info.Highlight[i+1] {
+				lineBg = highlightBg
+			}
+			fmt.Fprintf(&b, `<span style="display: inline-block; min-width: 100%%; background: %s;">`, lineBg)
+		}
+		if c.options.LineNumbers {
+			fmt.Fprintf(&b, `<span style="display: inline-block; min-width: %dem; margin-right: 12px; text-align: right; color: %s; user-select: none;">%d</span>`,
+				width, mutedColor, i+1)
+		}
+		for _, token := range tokens {
+			text := escapeCode(strings.TrimRight(token.Value, "\n"))
+			if text == "" {
+				continue
+			}
+			if css := c.tokenStyle(token.Type); css != "" {
+				fmt.Fprintf(&b, `<span style="%s">%s</span>`, css, text)
+			} else {
+				b.WriteString(text)
+			}
+		}
+		if lineBlocks {
+			b.WriteString("</span>")
+		}
+	}
+
+	b.WriteString("</code></pre></section>\n")
+	return b.String()
+}
+
+// diffLineBackground 根据 diff 行首符号返回背景色
+func diffLineBackground(tokens []chroma.Token) string {
+	var line strings.Builder
+	for _, token := range tokens {
+		line.WriteString(token.Value)
+	}
+	text := line.String()
+	switch {
+	case strings.HasPrefix(text, "+++"), strings.HasPrefix(text, "---"):
+		return "transparent"
+	case strings.HasPrefix(text, "+"):
+		return "rgba(46,160,67,0.25)"
+	case strings.HasPrefix(text, "-"):
+		return "rgba(248,81,73,0.25)"
+	}
+	return "transparent"
+}
+
+// tokenize 使用 Chroma 分词并按行切分，找不到词法分析器时按纯文本处理
+func (c *CodeBlock) tokenize(language, code string) [][]chroma.Token {
+	lexer := lexers.Get(language)
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+	lexer = chroma.Coalesce(lexer)
+
+	iterator, err := lexer.Tokenise(nil, code+"\n")
+	if err != nil {
+		return [][]chroma.Token{{{Type: chroma.Text, Value: code}}}
+	}
+	return chroma.SplitTokensIntoLines(iterator.Tokens())
+}
+
+// tokenStyle 将 Chroma 样式转换为内联 CSS
+func (c *CodeBlock) tokenStyle(tokenType chroma.TokenType) string {
+	entry := c.style.Get(tokenType)
+	var css []string
+	if entry.Colour.IsSet() {
+		css = append(css, "color: "+entry.Colour.String())
+	}
+	if entry.Bold == chroma.Yes {
+		css = append(css, "font-weight: bold")
+	}
+	if entry.Italic == chroma.Yes {
+		css = append(css, "font-style: italic")
+	}
+	if entry.Underline == chroma.Yes {
+		css = append(css, "text-decoration: underline")
+	}
+	return strings.Join(css, "; ")
+}
+
+// escapeCode 转义 HTML 并将空白替换为 &nbsp;，保证缩进在微信中不丢失
+func escapeCode(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, "\t", "    ")
+	return strings.ReplaceAll(s, " ", "&nbsp;")
+}
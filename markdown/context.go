@@ -0,0 +1,72 @@
+package markdown
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+)
+
+// Target 渲染目标，同一篇文章在本地预览与发布到微信时的输出略有不同
+type Target int
+
+const (
+	TargetPreview Target = iota // 本地预览，保留页内锚点等交互
+	TargetPublish               // 发布到微信，去掉无法使用的链接
+	TargetExport                // 导出为独立文件，保留页内锚点
+)
+
+var targetKey = parser.NewContextKey()
+
+// NewContext 创建一次转换使用的解析上下文
+// 标题 ID 按 Hugo 的规则生成 (保留中文)，使预览中的锚点与线上文章一致
+func NewContext(target Target) parser.Context {
+	pc := parser.NewContext(parser.WithIDs(newHeadingIDs()))
+	pc.Set(targetKey, target)
+	return pc
+}
+
+// TargetOf 获取上下文中的渲染目标，未设置时视为本地预览
+func TargetOf(pc parser.Context) Target {
+	if target, ok := pc.Get(targetKey).(Target); ok {
+		return target
+	}
+	return TargetPreview
+}
+
+// headingIDs 与 Hugo 一致的标题 ID：小写，保留字母数字 (含中文)，空白转为连字符
+type headingIDs struct {
+	used map[string]bool
+}
+
+func newHeadingIDs() *headingIDs {
+	return &headingIDs{used: make(map[string]bool)}
+}
+
+func (s *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(string(value)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(unicode.ToLower(r))
+		case unicode.IsSpace(r) || r == '-' || r == '_':
+			b.WriteRune('-')
+		}
+	}
+	id := b.String()
+	if id == "" {
+		id = "heading"
+	}
+	unique := id
+	for i := 1; s.used[unique]; i++ {
+		unique = id + "-" + strconv.Itoa(i)
+	}
+	s.used[unique] = true
+	return []byte(unique)
+}
+
+func (s *headingIDs) Put(value []byte) {
+	s.used[string(value)] = true
+}
@@ -0,0 +1,155 @@
+package markdown
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Diagram 通过本地命令渲染图表围栏 (mermaid、plantuml、dot 等)
+// 命令从 stdin 读取图表源码，向 stdout 输出 PNG (公众号等平台不支持 SVG，输出 SVG 时视为渲染失败)；
+// 结果按 "语言 + 命令 + 源码" 的摘要缓存在 AssetStore 中，源码不变时不会重复执行命令。
+type Diagram struct {
+	store    *AssetStore
+	commands map[string]string // 语言 -> 命令行
+	timeout  time.Duration
+	code     *CodeBlock // 渲染失败时用于输出原始代码
+}
+
+// NewDiagram 创建图表渲染器，timeout 为单次命令的超时时间
+func NewDiagram(store *AssetStore, commands map[string]string, timeout time.Duration) *Diagram {
+	return &Diagram{store: store, commands: commands, timeout: timeout}
+}
+
+// Register 将已配置命令的语言注册到代码块渲染器，返回找不到命令的语言 (按语言排序)
+// 命令不存在的语言同样注册，渲染时给出错误提示，避免图表被当作普通代码发布出去。
+func (d *Diagram) Register(c *CodeBlock) []string {
+	d.code = c
+	var missing []string
+	for language, command := range d.commands {
+		args := splitCommand(command)
+		if len(args) == 0 {
+			continue
+		}
+		if _, err := exec.LookPath(args[0]); err != nil {
+			missing = append(missing, language)
+		}
+		c.Handle(language, d.Render)
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// Render 实现 FenceHandler：渲染失败时输出错误提示并保留原代码块
+func (d *Diagram) Render(info FenceInfo, code string) (string, bool) {
+	command, ok := d.commands[info.Language]
+	if !ok {
+		return "", false
+	}
+
+	name, err := d.render(info.Language, command, code)
+	if err != nil {
+		errorBox := fmt.Sprintf(`<section class="diagram-error" style="margin: 20px 0 0; padding: 10px 14px; color: #c0392b; background: #fdecea; border-left: 4px solid #c0392b; border-radius: 4px; font-size: 14px;">图表渲染失败 (%s): %s</section>`,
+			html.EscapeString(info.Language), html.EscapeString(err.Error()))
+		return errorBox + d.code.Render(info, code), true
+	}
+
+	alt := info.Title
+	if alt == "" {
+		alt = info.Language + " diagram"
+	}
+	return fmt.Sprintf(`<section class="diagram" style="margin: 20px 0; text-align: center;"><img src="%s" alt="%s" style="display: inline-block; max-width: 100%%; margin: 0 auto;" /></section>`+"\n",
+		d.store.URL(name), html.EscapeString(alt)), true
+}
+
+// render 执行命令生成图片，返回资源名
+func (d *Diagram) render(language, command, code string) (string, error) {
+	key := language + "\x00" + command + "\x00" + code
+	if name := d.store.Name("diagram", key, ".png"); d.store.Exists(name) {
+		return name, nil
+	}
+
+	args := splitCommand(command)
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(code)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	// 超时杀掉进程后，子进程可能仍占用输出管道，不再等待其退出
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("命令执行超时 (%s)", d.timeout)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", errors.New(msg)
+	}
+
+	ext, err := sniffImage(stdout.Bytes())
+	if err != nil {
+		return "", err
+	}
+	name := d.store.Name("diagram", key, ext)
+	if err := d.store.Save(name, stdout.Bytes()); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// sniffImage 根据输出内容判断图片格式，只接受 PNG
+func sniffImage(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG")):
+		return ".png", nil
+	case bytes.Contains(data[:min(len(data), 512)], []byte("<svg")):
+		return "", fmt.Errorf("命令输出为 SVG 图片，公众号等平台不支持，请让命令输出 PNG (如 mmdc -e png、dot -Tpng)")
+	}
+	return "", fmt.Errorf("命令输出不是 PNG 图片")
+}
+
+// splitCommand 按空白切分命令行，支持单双引号包裹含空格的参数
+func splitCommand(command string) []string {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	inArg := false
+	for _, r := range command {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args
+}
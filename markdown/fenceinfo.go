@@ -0,0 +1,133 @@
+package markdown
+
+import (
+	"strconv"
+	"strings"
+)
+
+// FenceInfo 代码围栏信息串解析结果
+// 例如: ```go title="main.go" {3,7-9} collapse
+type FenceInfo struct {
+	Language  string            // 语言，例如 go、diff
+	Title     string            // 文件名标题，来自 title="..."
+	Highlight map[int]bool      // 需要高亮的行号 (从 1 开始)，来自 {3,7-9}
+	Collapse  bool              // 是否折叠为固定高度的滚动区域
+	Attrs     map[string]string // 其余 key=value 属性，留给扩展使用
+}
+
+// ParseFenceInfo 解析围栏信息串
+// 支持的写法：
+//   - 第一个裸词作为语言
+//   - key="value" / key='value' / key=value 属性
+//   - {1,3-5} 行号高亮，也兼容 hl_lines="1 3-5" 写法
+//   - collapse 裸词开启折叠
+//
+// lineCount 为代码块的行数，超出范围的高亮行号被忽略。
+func ParseFenceInfo(info string, lineCount int) FenceInfo {
+	result := FenceInfo{
+		Highlight: make(map[int]bool),
+		Attrs:     make(map[string]string),
+	}
+
+	for i, field := range splitFenceFields(info) {
+		switch {
+		case strings.HasPrefix(field, "{") && strings.HasSuffix(field, "}"):
+			parseLineRanges(strings.Trim(field, "{}"), lineCount, result.Highlight)
+		case strings.Contains(field, "="):
+			key, value, _ := strings.Cut(field, "=")
+			key = strings.ToLower(strings.TrimSpace(key))
+			value = strings.Trim(strings.TrimSpace(value), `"'`)
+			switch key {
+			case "title", "filename":
+				result.Title = value
+			case "hl_lines", "highlight":
+				parseLineRanges(value, lineCount, result.Highlight)
+			case "collapse":
+				result.Collapse, _ = strconv.ParseBool(value)
+			default:
+				result.Attrs[key] = value
+			}
+		case field == "collapse":
+			result.Collapse = true
+		case i == 0:
+			result.Language = field
+		default:
+			result.Attrs[field] = ""
+		}
+	}
+	return result
+}
+
+// splitFenceFields 按空白切分信息串，引号和花括号内的空白不切分
+func splitFenceFields(info string) []string {
+	var fields []string
+	var current strings.Builder
+	var quote rune
+	braces := 0
+
+	flush := func() {
+		if current.Len() > 0 {
+			fields = append(fields, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range info {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			current.WriteRune(r)
+		case r == '{':
+			// 语言后面紧跟花括号时 (```go{3})，先切出语言
+			if braces == 0 && len(fields) == 0 && current.Len() > 0 {
+				flush()
+			}
+			braces++
+			current.WriteRune(r)
+		case r == '}':
+			braces--
+			current.WriteRune(r)
+		case (r == ' ' || r == '\t') && braces <= 0:
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+	return fields
+}
+
+// parseLineRanges 解析 "3,7-9" 或 "3 7-9" 形式的行号区间，区间限制在 [1, lineCount] 内
+// 不限制时 {1-1000000000} 这样的写法会写入海量行号，拖垮渲染。
+func parseLineRanges(spec string, lineCount int, lines map[int]bool) {
+	spec = strings.ReplaceAll(spec, " ", ",")
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		start, end, isRange := strings.Cut(part, "-")
+		from, err := strconv.Atoi(start)
+		if err != nil {
+			continue
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(end); err != nil {
+				continue
+			}
+		}
+		from, to = max(from, 1), min(to, lineCount)
+		if from > to {
+			continue
+		}
+		for n := from; n <= to; n++ {
+			lines[n] = true
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// FigureImage 带说明文字的图片
+type FigureImage struct {
+	Src     string // 图片地址 (已做 URL 编码)
+	Alt     string
+	Caption string // 说明文字 HTML，为空时不输出 figcaption
+	Width   string // 例如 "60%"、"300"、"300px"
+	Height  string
+	Link    string // 点击图片跳转的地址
+}
+
+// FigureHTML 输出 figure 元素，样式全部内联，粘贴到微信后说明文字仍居中显示为灰色
+// 图片编号 ("图 1：") 在渲染完成后统一添加，见 render.FigureNumbers
+func FigureHTML(f FigureImage) string {
+	imgStyle := "display: block; max-width: 100%; margin: 0 auto;"
+	if f.Width != "" {
+		imgStyle += " width: " + cssLength(f.Width) + ";"
+	}
+	if f.Height != "" {
+		imgStyle += " height: " + cssLength(f.Height) + ";"
+	}
+
+	var b strings.Builder
+	b.WriteString(`<figure class="figure" style="margin: 20px 0; text-align: center;">`)
+	if f.Link != "" {
+		fmt.Fprintf(&b, `<a href="%s">`, html.EscapeString(f.Link))
+	}
+	fmt.Fprintf(&b, `<img src="%s" alt="%s" style="%s" />`, html.EscapeString(f.Src), html.EscapeString(f.Alt), imgStyle)
+	if f.Link != "" {
+		b.WriteString(`</a>`)
+	}
+	if f.Caption != "" {
+		b.WriteString(FigcaptionOpen + f.Caption + `</figcaption>`)
+	}
+	b.WriteString(`</figure>`)
+	return b.String()
+}
+
+// FigcaptionOpen 图片说明的开始标签
+const FigcaptionOpen = `<figcaption style="margin-top: 8px; text-align: center; color: #999; font-size: 14px; line-height: 1.6;">`
+
+// cssLength 纯数字的尺寸按像素处理
+func cssLength(s string) string {
+	if strings.Trim(s, "0123456789.") == "" {
+		return s + "px"
+	}
+	return s
+}
+
+// KindFigureBlock 图片块节点类型
+var KindFigureBlock = ast.NewNodeKind("FigureBlock")
+
+// FigureBlock 独占一段的图片
+type FigureBlock struct {
+	ast.BaseBlock
+	Image FigureImage
+}
+
+// Kind 实现 ast.Node
+func (n *FigureBlock) Kind() ast.NodeKind { return KindFigureBlock }
+
+// Dump 实现 ast.Node
+func (n *FigureBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Src": n.Image.Src}, nil)
+}
+
+// Figure 将独占一段的图片 (可被链接包裹) 渲染为带说明文字的 figure
+// 说明文字取图片标题，没有标题时取替代文字；行内图片保持原样。
+type Figure struct{}
+
+// NewFigure 创建图片说明扩展
+func NewFigure() *Figure {
+	return &Figure{}
+}
+
+// Extend 实现 goldmark.Extender
+func (f *Figure) Extend(md goldmark.Markdown) {
+	// 先于列表转换执行，列表项中的图片段落同样生成 figure
+	md.Parser().AddOptions(
+		parser.WithASTTransformers(util.Prioritized(f, 95)),
+	)
+	md.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(f, 100),
+	))
+}
+
+// Transform 实现 parser.ASTTransformer
+func (f *Figure) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var paragraphs []*ast.Paragraph
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if p, ok := n.(*ast.Paragraph); ok && entering && p.ChildCount() == 1 {
+			paragraphs = append(paragraphs, p)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, p := range paragraphs {
+		var link string
+		child := p.FirstChild()
+		if l, ok := child.(*ast.Link); ok && l.ChildCount() == 1 {
+			link = string(util.URLEscape(l.Destination, true))
+			child = l.FirstChild()
+		}
+		img, ok := child.(*ast.Image)
+		if !ok {
+			continue
+		}
+
+		alt := nodeText(img, source)
+		caption := string(img.Title)
+		if caption == "" {
+			caption = alt
+		}
+		block := &FigureBlock{Image: FigureImage{
+			Src:     string(util.URLEscape(img.Destination, true)),
+			Alt:     alt,
+			Caption: html.EscapeString(caption),
+			Link:    link,
+		}}
+		p.Parent().ReplaceChild(p.Parent(), p, block)
+	}
+}
+
+// RegisterFuncs 实现 renderer.NodeRenderer
+func (f *Figure) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindFigureBlock, f.render)
+}
+
+func (f *Figure) render(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		w.WriteString(FigureHTML(node.(*FigureBlock).Image) + "\n")
+	}
+	return ast.WalkSkipChildren, nil
+}
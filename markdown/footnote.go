@@ -0,0 +1,103 @@
+package markdown
+
+import (
+	"fmt"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Footnote 微信风格的脚注
+// 解析沿用 goldmark 的脚注扩展，渲染时去掉所有页内锚点 (微信中 # 链接无法跳转)：
+// 正文中只保留 [n] 上标，文末输出编号注释列表。列表结构与 wechat-format.js 生成的
+// "引用链接" 一致，外链引用 (预览时由前端、发布时由 links 包生成) 接在脚注之后统一编号。
+type Footnote struct{}
+
+// NewFootnote 创建脚注扩展
+func NewFootnote() *Footnote {
+	return &Footnote{}
+}
+
+// Extend 实现 goldmark.Extender
+func (f *Footnote) Extend(md goldmark.Markdown) {
+	extension.Footnote.Extend(md)
+	md.Parser().AddOptions(
+		parser.WithASTTransformers(util.Prioritized(&footnoteTransformer{}, 100)),
+	)
+	// 优先级高于 goldmark 自带的脚注渲染器 (500)，覆盖其输出
+	md.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(f, 100),
+	))
+}
+
+// RegisterFuncs 实现 renderer.NodeRenderer
+func (f *Footnote) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(east.KindFootnoteLink, f.renderLink)
+	reg.Register(east.KindFootnoteBacklink, f.renderBacklink)
+	reg.Register(east.KindFootnote, f.renderFootnote)
+	reg.Register(east.KindFootnoteList, f.renderList)
+}
+
+func (f *Footnote) renderLink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		n := node.(*east.FootnoteLink)
+		fmt.Fprintf(w, `<sup class="footnote-ref" style="margin-left: 2px; color: #999;">[%d]</sup>`, n.Index)
+	}
+	return ast.WalkContinue, nil
+}
+
+// renderBacklink 返回链接依赖页内锚点，不输出
+func (f *Footnote) renderBacklink(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	return ast.WalkSkipChildren, nil
+}
+
+func (f *Footnote) renderFootnote(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		n := node.(*east.Footnote)
+		w.WriteString(`<li style="display: block; margin-bottom: 8px; font-size: 14px; line-height: 1.6; color: #666;">`)
+		fmt.Fprintf(w, `<span class="li-text"><span style="margin-right: 5px; color: #999;">[%d]</span>`, n.Index)
+	} else {
+		w.WriteString("</span></li>\n")
+	}
+	return ast.WalkContinue, nil
+}
+
+func (f *Footnote) renderList(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		w.WriteString(`<div class="references-section footnotes-section" style="margin-top: 40px; padding-top: 20px; border-top: 1px solid #eee;">` + "\n")
+		w.WriteString(`<h3 style="margin-bottom: 15px; font-size: 16px; font-weight: bold;">注释</h3>` + "\n")
+		w.WriteString(`<ul style="padding-left: 0; list-style: none;">` + "\n")
+	} else {
+		w.WriteString("</ul>\n</div>\n")
+	}
+	return ast.WalkContinue, nil
+}
+
+// footnoteTransformer 只有一个段落的脚注去掉 <p>，让编号与内容保持在同一行
+type footnoteTransformer struct{}
+
+func (t *footnoteTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		fn, ok := n.(*east.Footnote)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		if para, ok := fn.FirstChild().(*ast.Paragraph); ok && fn.ChildCount() == 1 {
+			block := ast.NewTextBlock()
+			block.SetLines(para.Lines())
+			for child := para.FirstChild(); child != nil; {
+				next := child.NextSibling()
+				block.AppendChild(block, child)
+				child = next
+			}
+			fn.ReplaceChild(fn, para, block)
+		}
+		return ast.WalkSkipChildren, nil
+	})
+}
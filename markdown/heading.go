@@ -0,0 +1,238 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+
+	"github.com/hankmor/mymedia/tools/wechat-preview/theme"
+)
+
+// 标题编号方案
+const (
+	NumberingDecimal = "decimal" // 1、1.1、1.1.1
+	NumberingChinese = "chinese" // 一、 (一) 1.
+	NumberingPadded  = "padded"  // 01、1.1、1.1.1
+)
+
+// numberingAliases 编号方案的别名，配置中可直接写示例
+var numberingAliases = map[string]string{
+	NumberingDecimal: NumberingDecimal,
+	"1.1":            NumberingDecimal,
+	NumberingChinese: NumberingChinese,
+	"一、":             NumberingChinese,
+	NumberingPadded:  NumberingPadded,
+	"01":             NumberingPadded,
+}
+
+// ParseNumbering 解析编号方案，"" / false / none 表示不编号，未知方案返回 false
+func ParseNumbering(s string) (string, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "", "false", "none", "off":
+		return "", true
+	}
+	scheme, ok := numberingAliases[s]
+	return scheme, ok
+}
+
+// 编号从 H2 开始 (H1 为文章标题，已移除)，最多编到 H4
+const (
+	numberingMinLevel = 2
+	numberingMaxLevel = 4
+)
+
+// numberAttr 标题编号保存在节点属性中，供渲染与目录使用
+var numberAttr = []byte("data-number")
+
+// headingNumber 获取转换时写入的标题编号
+func headingNumber(n *ast.Heading) string {
+	if v, ok := n.AttributeString(string(numberAttr)); ok {
+		if b, ok := v.([]byte); ok {
+			return string(b)
+		}
+	}
+	return ""
+}
+
+// HeadingStyle 微信风格的标题：按编号方案为 H2~H4 编号，并用主题中的装饰模板输出内联样式
+// 编号方案可通过 SetHeadingNumbering 按文章覆盖 (frontmatter heading_numbering: false)，
+// 主题可通过 SetHeadingTheme 按次覆盖 (例如发布到头条号时使用 toutiao 主题)。
+type HeadingStyle struct {
+	numbering string
+	theme     *theme.Theme
+	templates map[string]map[int]*template.Template // 主题名 -> 级别 -> 模板
+}
+
+// NewHeadingStyle 创建标题扩展，numbering 为空表示默认不编号
+func NewHeadingStyle(t *theme.Theme, numbering string) *HeadingStyle {
+	h := &HeadingStyle{numbering: numbering, theme: t, templates: make(map[string]map[int]*template.Template)}
+	for _, name := range append(theme.Names(), t.Name) {
+		th := theme.Get(name)
+		if name == t.Name {
+			th = t
+		}
+		h.templates[name] = make(map[int]*template.Template)
+		for level := 1; level <= 6; level++ {
+			h.templates[name][level] = template.Must(template.New(fmt.Sprintf("h%d", level)).Parse(th.Heading(level)))
+		}
+	}
+	return h
+}
+
+var (
+	numberingKey = parser.NewContextKey()
+	themeKey     = parser.NewContextKey()
+)
+
+// themeAttr 文档节点上记录本次转换使用的主题，渲染时读取
+var themeAttr = []byte("wp-heading-theme")
+
+// SetHeadingNumbering 设置本次转换的标题编号方案，空字符串表示不编号
+func SetHeadingNumbering(pc parser.Context, scheme string) {
+	pc.Set(numberingKey, scheme)
+}
+
+// SetHeadingTheme 设置本次转换的标题装饰主题，覆盖创建扩展时的主题
+func SetHeadingTheme(pc parser.Context, t *theme.Theme) {
+	pc.Set(themeKey, t)
+}
+
+// Extend 实现 goldmark.Extender
+func (h *HeadingStyle) Extend(md goldmark.Markdown) {
+	// 先于目录执行，目录项使用相同的编号
+	md.Parser().AddOptions(
+		parser.WithASTTransformers(util.Prioritized(h, 90)),
+	)
+	md.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(h, 100),
+	))
+}
+
+// Transform 实现 parser.ASTTransformer
+func (h *HeadingStyle) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	if t, ok := pc.Get(themeKey).(*theme.Theme); ok {
+		doc.SetAttribute(themeAttr, t)
+	}
+
+	scheme := h.numbering
+	if v, ok := pc.Get(numberingKey).(string); ok {
+		scheme = v
+	}
+	if scheme == "" {
+		return
+	}
+
+	counters := make([]int, numberingMaxLevel+1)
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		heading, ok := n.(*ast.Heading)
+		if !ok || heading.Level < numberingMinLevel || heading.Level > numberingMaxLevel {
+			continue
+		}
+		counters[heading.Level]++
+		for l := heading.Level + 1; l < len(counters); l++ {
+			counters[l] = 0
+		}
+		heading.SetAttribute(numberAttr, []byte(formatNumber(scheme, counters[numberingMinLevel:heading.Level+1])))
+	}
+}
+
+// formatNumber 按方案格式化编号，counters 为从 H2 到当前级别的计数
+func formatNumber(scheme string, counters []int) string {
+	depth := len(counters) - 1
+	n := counters[depth]
+	switch {
+	case scheme == NumberingChinese && depth == 0:
+		return chineseNumber(n) + "、"
+	case scheme == NumberingChinese && depth == 1:
+		return "（" + chineseNumber(n) + "）"
+	case scheme == NumberingChinese:
+		return strconv.Itoa(n) + "."
+	case scheme == NumberingPadded && depth == 0:
+		return fmt.Sprintf("%02d", n)
+	}
+	parts := make([]string, len(counters))
+	for i, c := range counters {
+		parts[i] = strconv.Itoa(c)
+	}
+	return strings.Join(parts, ".")
+}
+
+var chineseDigits = []string{"零", "一", "二", "三", "四", "五", "六", "七", "八", "九"}
+
+// chineseNumber 小写中文数字，支持 1~99
+func chineseNumber(n int) string {
+	if n < 10 {
+		return chineseDigits[n]
+	}
+	if n >= 100 {
+		return strconv.Itoa(n)
+	}
+	s := "十"
+	if n >= 20 {
+		s = chineseDigits[n/10] + s
+	}
+	if n%10 != 0 {
+		s += chineseDigits[n%10]
+	}
+	return s
+}
+
+// RegisterFuncs 实现 renderer.NodeRenderer
+func (h *HeadingStyle) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindHeading, h.render)
+}
+
+// contentMarker 模板中 .Content 的占位，渲染后以此拆分为标题的开始与结束部分，中间输出子节点
+const contentMarker = "WPHEADINGCONTENT"
+
+// headingData 标题模板数据
+type headingData struct {
+	Level   int
+	ID      string
+	Number  string
+	Content template.HTML
+	Color   template.CSS
+}
+
+func (h *HeadingStyle) render(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*ast.Heading)
+	t := h.theme
+	if doc := n.OwnerDocument(); doc != nil {
+		if v, ok := doc.Attribute(themeAttr); ok {
+			t = v.(*theme.Theme)
+		}
+	}
+	data := headingData{
+		Level:   n.Level,
+		Number:  headingNumber(n),
+		Content: contentMarker,
+		Color:   template.CSS(t.Primary),
+	}
+	if id, ok := n.AttributeString("id"); ok {
+		if id, ok := id.([]byte); ok {
+			data.ID = string(id)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := h.templates[t.Name][n.Level].Execute(&buf, data); err != nil {
+		return ast.WalkStop, err
+	}
+	open, end, _ := strings.Cut(buf.String(), contentMarker)
+	if entering {
+		w.WriteString(open)
+	} else {
+		w.WriteString(end + "\n")
+	}
+	return ast.WalkContinue, nil
+}
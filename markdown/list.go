@@ -0,0 +1,172 @@
+package markdown
+
+import (
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindListText 列表项文字节点类型
+var KindListText = ast.NewNodeKind("ListText")
+
+// ListText 列表项中的一段文字，渲染为 <span class="li-text">
+// 微信编辑器会在 <li> 内的块级元素前后插入换行，文字统一包在行内 span 中
+type ListText struct {
+	ast.BaseInline
+}
+
+// Kind 实现 ast.Node
+func (n *ListText) Kind() ast.NodeKind { return KindListText }
+
+// Dump 实现 ast.Node
+func (n *ListText) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// KindListBold 列表项开头加粗文字节点类型
+var KindListBold = ast.NewNodeKind("ListBold")
+
+// ListBold 列表项开头的加粗文字，渲染为 <span class="li-bold">，避免微信把 <strong> 后的内容换行
+type ListBold struct {
+	ast.BaseInline
+}
+
+// Kind 实现 ast.Node
+func (n *ListBold) Kind() ast.NodeKind { return KindListBold }
+
+// Dump 实现 ast.Node
+func (n *ListBold) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// ListItem 微信兼容的列表项渲染
+// 列表项中的段落去掉 <p>，文字包在 li-text 中，开头的加粗改为 li-bold；
+// 嵌套列表等块级内容保持原样跟在文字之后。任务列表的复选框渲染为字符，微信会过滤 <input>。
+type ListItem struct {
+	html.Config // 沿用 XHTML 等 HTML 渲染选项
+}
+
+// NewListItem 创建列表项扩展
+func NewListItem() *ListItem {
+	return &ListItem{Config: html.NewConfig()}
+}
+
+// Extend 实现 goldmark.Extender
+func (l *ListItem) Extend(md goldmark.Markdown) {
+	md.Parser().AddOptions(
+		parser.WithASTTransformers(util.Prioritized(&listItemTransformer{}, 100)),
+	)
+	md.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(l, 100),
+	))
+}
+
+// RegisterFuncs 实现 renderer.NodeRenderer
+func (l *ListItem) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindListItem, l.renderListItem)
+	reg.Register(KindListText, l.renderListText)
+	reg.Register(KindListBold, l.renderListBold)
+	reg.Register(east.KindTaskCheckBox, l.renderTaskCheckBox)
+}
+
+func (l *ListItem) renderListItem(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		w.WriteString("<li")
+		if node.Attributes() != nil {
+			html.RenderAttributes(w, node, html.ListItemAttributeFilter)
+		}
+		w.WriteString(">")
+	} else {
+		w.WriteString("</li>\n")
+	}
+	return ast.WalkContinue, nil
+}
+
+func (l *ListItem) renderListText(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		// 同一列表项中的第二段文字起另起一行
+		if prev := node.Parent().PreviousSibling(); prev != nil && prev.Kind() == ast.KindTextBlock {
+			if l.XHTML {
+				w.WriteString("<br />")
+			} else {
+				w.WriteString("<br>")
+			}
+		}
+		w.WriteString(`<span class="li-text">`)
+	} else {
+		w.WriteString("</span>")
+	}
+	return ast.WalkContinue, nil
+}
+
+func (l *ListItem) renderListBold(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		w.WriteString(`<span class="li-bold">`)
+	} else {
+		w.WriteString("</span>")
+	}
+	return ast.WalkContinue, nil
+}
+
+func (l *ListItem) renderTaskCheckBox(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	if node.(*east.TaskCheckBox).IsChecked {
+		w.WriteString(`<span class="task-checkbox" style="margin-right: 4px;">☑</span>`)
+	} else {
+		w.WriteString(`<span class="task-checkbox" style="margin-right: 4px;">☐</span>`)
+	}
+	return ast.WalkContinue, nil
+}
+
+// listItemTransformer 将列表项中的段落改写为 TextBlock > ListText 结构
+type listItemTransformer struct{}
+
+func (t *listItemTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	var items []ast.Node
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && n.Kind() == ast.KindListItem {
+			items = append(items, n)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, item := range items {
+		for child := item.FirstChild(); child != nil; child = child.NextSibling() {
+			if child.Kind() != ast.KindParagraph && child.Kind() != ast.KindTextBlock {
+				continue
+			}
+			wrapper := &ListText{}
+			for inline := child.FirstChild(); inline != nil; {
+				next := inline.NextSibling()
+				wrapper.AppendChild(wrapper, inline)
+				inline = next
+			}
+			first := wrapper.FirstChild()
+			if first != nil && first.Kind() == east.KindTaskCheckBox {
+				first = first.NextSibling()
+			}
+			if em, ok := first.(*ast.Emphasis); ok && em.Level == 2 {
+				bold := &ListBold{}
+				for inline := em.FirstChild(); inline != nil; {
+					next := inline.NextSibling()
+					bold.AppendChild(bold, inline)
+					inline = next
+				}
+				wrapper.ReplaceChild(wrapper, em, bold)
+			}
+
+			block := ast.NewTextBlock()
+			block.SetLines(child.Lines())
+			block.AppendChild(block, wrapper)
+			item.ReplaceChild(item, child, block)
+			child = block
+		}
+	}
+}
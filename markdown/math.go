@@ -0,0 +1,280 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+	"sync"
+
+	"github.com/hankmor/mymedia/tools/wechat-preview/texmath"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindMathInline 行内公式节点类型
+var KindMathInline = ast.NewNodeKind("MathInline")
+
+// MathInline 行内公式 $...$，段落中的 $$...$$ 也解析为该节点 (Display 为 true)
+type MathInline struct {
+	ast.BaseInline
+	Formula string
+	Display bool
+}
+
+// Kind 实现 ast.Node
+func (n *MathInline) Kind() ast.NodeKind { return KindMathInline }
+
+// Dump 实现 ast.Node
+func (n *MathInline) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Formula": n.Formula}, nil)
+}
+
+// KindMathBlock 块级公式节点类型
+var KindMathBlock = ast.NewNodeKind("MathBlock")
+
+// MathBlock 块级公式 $$ ... $$，公式内容保存在 Lines 中
+type MathBlock struct {
+	ast.BaseBlock
+}
+
+// Kind 实现 ast.Node
+func (n *MathBlock) Kind() ast.NodeKind { return KindMathBlock }
+
+// IsRaw 实现 ast.Node，公式内容不再做行内解析
+func (n *MathBlock) IsRaw() bool { return true }
+
+// Dump 实现 ast.Node
+func (n *MathBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// Math 数学公式扩展
+// 解析 $...$ 与 $$...$$，在渲染时用 texmath 生成 PNG 写入 AssetStore，
+// 预览时图片由本地服务提供，发布时再随其他图片一起上传到图床。
+type Math struct {
+	store    *AssetStore
+	fontSize float64
+
+	mu    sync.Mutex
+	cache map[string]*mathImage // 渲染结果缓存，key 为资源名
+}
+
+type mathImage struct {
+	name                 string
+	width, height, depth float64
+}
+
+// NewMath 创建数学公式扩展
+func NewMath(store *AssetStore) *Math {
+	return &Math{
+		store:    store,
+		fontSize: 16,
+		cache:    make(map[string]*mathImage),
+	}
+}
+
+// Extend 实现 goldmark.Extender
+func (m *Math) Extend(md goldmark.Markdown) {
+	md.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(&mathBlockParser{}, 90)),
+		parser.WithInlineParsers(util.Prioritized(&mathInlineParser{}, 90)),
+	)
+	md.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(m, 100),
+	))
+}
+
+// RegisterFuncs 实现 renderer.NodeRenderer
+func (m *Math) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindMathInline, m.renderInline)
+	reg.Register(KindMathBlock, m.renderBlock)
+}
+
+func (m *Math) renderInline(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*MathInline)
+	if n.Display {
+		// 段落内的 $$...$$ 不能输出块级标签，使用 display: block 的 span
+		w.WriteString(m.displayHTML(n.Formula, "span"))
+	} else {
+		w.WriteString(m.inlineHTML(n.Formula))
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+func (m *Math) renderBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	var formula strings.Builder
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		formula.Write(line.Value(source))
+	}
+	w.WriteString(m.displayHTML(formula.String(), "section"))
+	w.WriteString("\n")
+	return ast.WalkSkipChildren, nil
+}
+
+// inlineHTML 行内公式：按公式深度下移，使公式基线与正文基线对齐
+func (m *Math) inlineHTML(formula string) string {
+	formula = strings.TrimSpace(formula)
+	img, err := m.image(formula, false)
+	if err != nil {
+		return fmt.Sprintf(`<code class="math-error" title="%s" style="color: #c0392b; background: #fdecea;">$%s$</code>`,
+			html.EscapeString(err.Error()), html.EscapeString(formula))
+	}
+	return fmt.Sprintf(`<img class="math-inline" src="%s" alt="%s" style="display: inline; width: %.1fpx; height: %.1fpx; vertical-align: -%.1fpx; margin: 0; border-radius: 0; box-shadow: none;" />`,
+		m.store.URL(img.name), html.EscapeString(formula), img.width, img.height, img.depth)
+}
+
+// displayHTML 块级公式：居中显示，过宽时横向滚动，tag 为外层容器标签
+func (m *Math) displayHTML(formula, tag string) string {
+	formula = strings.TrimSpace(formula)
+	img, err := m.image(formula, true)
+	if err != nil {
+		return fmt.Sprintf(`<%s class="math-error" style="display: block; margin: 16px 0; padding: 8px 12px; color: #c0392b; background: #fdecea; border-radius: 4px; font-size: 14px;">公式渲染失败: %s<br /><code>%s</code></%s>`,
+			tag, html.EscapeString(err.Error()), html.EscapeString(formula), tag)
+	}
+	return fmt.Sprintf(`<%s class="math-block" style="display: block; margin: 16px 0; text-align: center; overflow-x: auto;"><img src="%s" alt="%s" style="display: inline-block; width: %.1fpx; height: %.1fpx; max-width: none; margin: 0; border-radius: 0; box-shadow: none;" /></%s>`,
+		tag, m.store.URL(img.name), html.EscapeString(formula), img.width, img.height, tag)
+}
+
+// image 渲染公式并写入缓存目录，相同公式只渲染一次
+func (m *Math) image(formula string, display bool) (*mathImage, error) {
+	mode := "inline"
+	if display {
+		mode = "display"
+	}
+	name := m.store.Name("math", mode+":"+formula, ".png")
+
+	m.mu.Lock()
+	cached, ok := m.cache[name]
+	m.mu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	rendered, err := texmath.Render(formula, texmath.Options{FontSize: m.fontSize, Display: display})
+	if err != nil {
+		return nil, err
+	}
+	if err := m.store.Save(name, rendered.PNG); err != nil {
+		return nil, err
+	}
+
+	img := &mathImage{name: name, width: rendered.Width, height: rendered.Height, depth: rendered.Depth}
+	m.mu.Lock()
+	m.cache[name] = img
+	m.mu.Unlock()
+	return img, nil
+}
+
+// mathInlineParser 解析 $...$ 与 $$...$$
+type mathInlineParser struct{}
+
+func (p *mathInlineParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (p *mathInlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	display := len(line) > 1 && line[1] == '$'
+	open := 1
+	if display {
+		open = 2
+	}
+	// $ 后紧跟空白时不视为公式，避免误伤 "$5 and $10" 这类金额
+	if len(line) <= open || line[open] == ' ' || line[open] == '\t' || line[open] == '\n' {
+		return nil
+	}
+
+	for i := open; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '$':
+			if display {
+				if i+1 < len(line) && line[i+1] == '$' {
+					block.Advance(i + 2)
+					return &MathInline{Formula: string(line[open:i]), Display: true}
+				}
+				continue
+			}
+			// 结束符前不能是空白，后面不能紧跟数字
+			if line[i-1] == ' ' || (i+1 < len(line) && line[i+1] >= '0' && line[i+1] <= '9') {
+				continue
+			}
+			block.Advance(i + 1)
+			return &MathInline{Formula: string(line[open:i])}
+		}
+	}
+	return nil
+}
+
+// mathBlockParser 解析独占行的 $$ ... $$
+type mathBlockParser struct{}
+
+func (p *mathBlockParser) Trigger() []byte {
+	return []byte{'$'}
+}
+
+func (p *mathBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || !bytes.HasPrefix(line[pos:], []byte("$$")) {
+		return nil, parser.NoChildren
+	}
+
+	node := &MathBlock{}
+	start := segment.Start + pos + 2
+	rest := util.TrimRightSpace(line[pos+2:])
+
+	// 单行形式 $$ x $$
+	if len(rest) >= 2 && bytes.HasSuffix(rest, []byte("$$")) {
+		node.Lines().Append(text.NewSegment(start, start+len(rest)-2))
+		reader.Advance(segment.Len() - 1)
+		return node, parser.Close
+	}
+	// 行内还有其他内容 (如 $$x$$ 之后还有文字) 时交给行内解析
+	if bytes.Contains(rest, []byte("$$")) {
+		return nil, parser.NoChildren
+	}
+	if len(rest) > 0 {
+		node.Lines().Append(text.NewSegment(start, start+len(rest)))
+	}
+	return node, parser.NoChildren
+}
+
+func (p *mathBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, segment := reader.PeekLine()
+	trimmed := util.TrimRightSpace(line)
+	if bytes.HasSuffix(trimmed, []byte("$$")) {
+		if content := len(trimmed) - 2; content > 0 {
+			node.Lines().Append(text.NewSegment(segment.Start, segment.Start+content))
+		}
+		reader.Advance(segment.Len() - 1)
+		return parser.Close
+	}
+	node.Lines().Append(segment)
+	reader.Advance(segment.Len() - 1)
+	return parser.Continue | parser.NoChildren
+}
+
+func (p *mathBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (p *mathBlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (p *mathBlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
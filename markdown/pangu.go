@@ -0,0 +1,170 @@
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+
+	"github.com/hankmor/mymedia/tools/wechat-preview/pangu"
+)
+
+// Pangu 中文排版修正：中英文之间加空格、中文标点全角化、... 改为 ……
+// 只处理普通文本节点，代码、链接、原始 HTML 与公式保持原样。
+// 默认是否开启由 enabled 决定，单篇文章可通过 SetPangu 覆盖 (frontmatter pangu: false)。
+type Pangu struct {
+	enabled bool
+}
+
+// NewPangu 创建中文排版修正扩展
+func NewPangu(enabled bool) *Pangu {
+	return &Pangu{enabled: enabled}
+}
+
+var panguKey = parser.NewContextKey()
+
+// SetPangu 设置本次转换是否进行中文排版修正
+func SetPangu(pc parser.Context, enabled bool) {
+	pc.Set(panguKey, enabled)
+}
+
+// Extend 实现 goldmark.Extender
+func (p *Pangu) Extend(md goldmark.Markdown) {
+	// 先于其它转换执行，目录等读取到的标题文字与正文一致
+	md.Parser().AddOptions(
+		parser.WithASTTransformers(util.Prioritized(p, 50)),
+	)
+}
+
+// Transform 实现 parser.ASTTransformer
+func (p *Pangu) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	enabled := p.enabled
+	if v, ok := pc.Get(panguKey).(bool); ok {
+		enabled = v
+	}
+	if !enabled {
+		return
+	}
+
+	source := reader.Source()
+	for _, fix := range panguFixes(doc, source, nil) {
+		value := fix.text.Segment.Value(source)
+		if bytes.Equal(value, fix.value) {
+			continue
+		}
+		// 换行标记留在原文本节点上，修正后的文字以 String 节点插在它前面
+		parent := fix.text.Parent()
+		str := ast.NewString(fix.value)
+		parent.InsertBefore(parent, fix.text, str)
+		fix.text.Segment = fix.text.Segment.WithStart(fix.text.Segment.Stop)
+	}
+}
+
+// panguFix 一个文本节点的修正结果
+type panguFix struct {
+	text  *ast.Text
+	value []byte
+}
+
+// panguFixes 计算各文本节点修正后的内容
+// 同一行中被强调等标记隔开的相邻文本，在边界处同样补充空格；skip 返回 true 的文本保持原样
+func panguFixes(doc ast.Node, source []byte, skip func(seg text.Segment) bool) []*panguFix {
+	var fixes []*panguFix
+	var prev *panguFix // 同一行内的上一个文本节点，遇到代码、链接、换行或块边界时清空
+
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n.Kind() {
+		case ast.KindCodeSpan, ast.KindLink, ast.KindAutoLink, ast.KindImage, ast.KindRawHTML,
+			ast.KindCodeBlock, ast.KindFencedCodeBlock, ast.KindHTMLBlock, KindMathInline, KindMathBlock:
+			prev = nil
+			return ast.WalkSkipChildren, nil
+		}
+
+		t, ok := n.(*ast.Text)
+		if !ok {
+			if n.Type() == ast.TypeBlock || !n.HasChildren() {
+				prev = nil
+			}
+			return ast.WalkContinue, nil
+		}
+		if t.IsRaw() || (skip != nil && skip(t.Segment)) {
+			prev = nil
+			return ast.WalkContinue, nil
+		}
+
+		value := string(t.Segment.Value(source))
+		var left rune
+		if prev != nil && len(prev.value) > 0 {
+			left, _ = utf8.DecodeLastRune(prev.value)
+		}
+		fix := &panguFix{text: t, value: []byte(pangu.FixAfter(left, value))}
+		// 进入强调等标记时，边界空格放在标记外侧，避免 "** English**" 失去强调效果
+		if prev != nil && len(fix.value) > 0 && fix.value[0] == ' ' && !strings.HasPrefix(value, " ") && depth(t) > depth(prev.text) {
+			fix.value = fix.value[1:]
+			prev.value = append(prev.value, ' ')
+		}
+		fixes = append(fixes, fix)
+
+		prev = fix
+		if t.SoftLineBreak() || t.HardLineBreak() {
+			prev = nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return fixes
+}
+
+// depth 节点在文档树中的深度
+func depth(n ast.Node) int {
+	d := 0
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		d++
+	}
+	return d
+}
+
+// panguParser 修正源文件时使用的解析器，只需识别不应修改的代码、链接、公式等语法
+var panguParser = goldmark.New(
+	goldmark.WithExtensions(extension.GFM, extension.Footnote, NewMath(nil)),
+).Parser()
+
+// shortcodePattern 源文件中的 Hugo 短代码，解析器会把它拆成多个文本节点，修正时整体跳过
+var shortcodePattern = regexp.MustCompile(`\{\{[<%].*?[%>]\}\}`)
+
+// FixPangu 对 Markdown 源文本做中文排版修正，只改写普通文本，其余内容原样保留
+func FixPangu(source []byte) []byte {
+	doc := panguParser.Parse(text.NewReader(source))
+	shortcodes := shortcodePattern.FindAllIndex(source, -1)
+	inShortcode := func(seg text.Segment) bool {
+		for _, loc := range shortcodes {
+			if seg.Start < loc[1] && seg.Stop > loc[0] {
+				return true
+			}
+		}
+		return false
+	}
+
+	var out bytes.Buffer
+	last := 0
+	for _, fix := range panguFixes(doc, source, inShortcode) {
+		seg := fix.text.Segment
+		if bytes.Equal(seg.Value(source), fix.value) || seg.Start < last {
+			continue
+		}
+		out.Write(source[last:seg.Start])
+		out.Write(fix.value)
+		last = seg.Stop
+	}
+	out.Write(source[last:])
+	return out.Bytes()
+}
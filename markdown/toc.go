@@ -0,0 +1,206 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Heading 文章标题树节点
+type Heading struct {
+	Level    int        `json:"level"`
+	Text     string     `json:"text"`
+	ID       string     `json:"id"`
+	Number   string     `json:"number,omitempty"` // 编号，例如 "2.1"、"一、"，未开启编号时为空
+	Children []*Heading `json:"children,omitempty"`
+}
+
+// TOCOptions 目录选项
+type TOCOptions struct {
+	MinLevel  int  // 收录的最小标题级别，默认 2 (文章 H1 为标题，已移除)
+	MaxLevel  int  // 收录的最大标题级别，默认 3
+	Numbering bool // 是否在目录项前添加 1.1 形式的编号
+}
+
+// KindTOCBlock 目录节点类型
+var KindTOCBlock = ast.NewNodeKind("TOCBlock")
+
+// TOCBlock 目录，由 [TOC] 标记生成
+type TOCBlock struct {
+	ast.BaseBlock
+	Entries []*Heading // 按文档顺序排列的目录项
+	Links   bool       // 是否输出页内链接，发布到微信时不输出
+}
+
+// Kind 实现 ast.Node
+func (n *TOCBlock) Kind() ast.NodeKind { return KindTOCBlock }
+
+// Dump 实现 ast.Node
+func (n *TOCBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Entries": strconv.Itoa(len(n.Entries))}, nil)
+}
+
+// TOC 目录扩展
+// 收集文章标题树 (可通过 Headings 获取)，并将独占一段的 [TOC] 替换为目录。
+// 微信中页内锚点无法跳转，发布时目录渲染为不带链接的列表，本地预览与导出时可点击跳转。
+type TOC struct {
+	options TOCOptions
+}
+
+// NewTOC 创建目录扩展
+func NewTOC(options TOCOptions) *TOC {
+	if options.MinLevel <= 0 {
+		options.MinLevel = 2
+	}
+	if options.MaxLevel < options.MinLevel {
+		options.MaxLevel = max(options.MinLevel, 3)
+	}
+	return &TOC{options: options}
+}
+
+// Extend 实现 goldmark.Extender
+func (t *TOC) Extend(md goldmark.Markdown) {
+	md.Parser().AddOptions(
+		parser.WithASTTransformers(util.Prioritized(t, 100)),
+	)
+	md.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(t, 100),
+	))
+}
+
+var headingsKey = parser.NewContextKey()
+
+// Headings 获取转换后的标题树，需要在 Convert 时传入 parser.WithContext(pc)
+func Headings(pc parser.Context) []*Heading {
+	headings, _ := pc.Get(headingsKey).([]*Heading)
+	return headings
+}
+
+// Transform 实现 parser.ASTTransformer
+func (t *TOC) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+
+	var roots, entries []*Heading
+	var stack []*Heading
+	counters := make([]int, t.options.MaxLevel+1)
+	var markers []*ast.Paragraph
+
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		switch node := n.(type) {
+		case *ast.Heading:
+			// 正文标题已编号时使用相同编号
+			h := &Heading{Level: node.Level, Text: nodeText(node, source), Number: headingNumber(node)}
+			if id, ok := node.AttributeString("id"); ok {
+				if id, ok := id.([]byte); ok {
+					h.ID = string(id)
+				}
+			}
+
+			// 按级别挂到最近的上级标题下
+			for len(stack) > 0 && stack[len(stack)-1].Level >= h.Level {
+				stack = stack[:len(stack)-1]
+			}
+			if len(stack) == 0 {
+				roots = append(roots, h)
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, h)
+			}
+			stack = append(stack, h)
+
+			if h.Level >= t.options.MinLevel && h.Level <= t.options.MaxLevel {
+				if h.Number == "" && t.options.Numbering {
+					counters[h.Level]++
+					for l := h.Level + 1; l < len(counters); l++ {
+						counters[l] = 0
+					}
+					var parts []string
+					for l := t.options.MinLevel; l <= h.Level; l++ {
+						parts = append(parts, strconv.Itoa(counters[l]))
+					}
+					h.Number = strings.Join(parts, ".")
+				}
+				entries = append(entries, h)
+			}
+		case *ast.Paragraph:
+			if isTOCMarker(node, source) {
+				markers = append(markers, node)
+			}
+		}
+	}
+	pc.Set(headingsKey, roots)
+
+	for _, marker := range markers {
+		block := &TOCBlock{Entries: entries, Links: TargetOf(pc) != TargetPublish}
+		marker.Parent().ReplaceChild(marker.Parent(), marker, block)
+	}
+}
+
+// isTOCMarker 段落内容是否只有 [TOC]
+func isTOCMarker(p *ast.Paragraph, source []byte) bool {
+	if p.Lines().Len() != 1 {
+		return false
+	}
+	line := p.Lines().At(0)
+	return strings.EqualFold(string(bytes.TrimSpace(line.Value(source))), "[TOC]")
+}
+
+// nodeText 节点下的纯文本
+func nodeText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := c.(type) {
+		case *ast.Text:
+			b.Write(t.Segment.Value(source))
+		case *ast.String:
+			b.Write(t.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return b.String()
+}
+
+// RegisterFuncs 实现 renderer.NodeRenderer
+func (t *TOC) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindTOCBlock, t.render)
+}
+
+func (t *TOC) render(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	n := node.(*TOCBlock)
+	if !entering || len(n.Entries) == 0 {
+		return ast.WalkContinue, nil
+	}
+
+	w.WriteString(`<section class="toc" style="margin: 20px 0; padding: 12px 16px; border-radius: 4px; background: #f8f8f8;">`)
+	w.WriteString(`<p class="toc-title" style="margin: 0 0 8px; font-size: 16px; font-weight: bold; color: #333;">目录</p>`)
+	for _, h := range n.Entries {
+		label := html.EscapeString(h.Text)
+		if h.Number != "" {
+			// "一、" "（一）" 等以标点结尾的编号后不加空格
+			if strings.HasSuffix(h.Number, "、") || strings.HasSuffix(h.Number, "）") {
+				label = h.Number + label
+			} else {
+				label = h.Number + " " + label
+			}
+		}
+		if n.Links && h.ID != "" {
+			label = fmt.Sprintf(`<a href="#%s" style="color: #555; border-bottom: none;">%s</a>`, html.EscapeString(h.ID), label)
+		}
+		fmt.Fprintf(w, `<p class="toc-item" style="margin: 0; padding-left: %.1fem; font-size: 14px; line-height: 1.8; color: #555;">%s</p>`,
+			float64(h.Level-t.options.MinLevel)*1.5, label)
+	}
+	w.WriteString("</section>\n")
+	return ast.WalkSkipChildren, nil
+}
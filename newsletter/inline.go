@@ -0,0 +1,202 @@
+package newsletter
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// 邮件客户端会忽略 <style> 中的样式 (Gmail 等)，因此将样式表中的规则写入各元素的 style 属性。
+// 只支持由标签名与类名组成的选择器及后代组合，伪类、属性选择器与 @media 等规则只在浏览器中生效，予以忽略。
+
+// compound 复合选择器，例如 pre、code.inline、.li-text
+type compound struct {
+	tag     string
+	classes []string
+}
+
+func (c compound) match(n *html.Node) bool {
+	if c.tag != "" && c.tag != n.Data {
+		return false
+	}
+	for _, class := range c.classes {
+		if !hasClass(n, class) {
+			return false
+		}
+	}
+	return true
+}
+
+// declaration 一条样式声明
+type declaration struct {
+	prop, value string
+	important   bool
+}
+
+// rule 一条样式规则，selector 为空时匹配作用域元素本身
+type rule struct {
+	selector    []compound
+	decls       []declaration
+	specificity int
+}
+
+// match 最后一个复合选择器匹配元素，其余依次匹配 root 之下的祖先元素
+func (r rule) match(n, root *html.Node) bool {
+	if n == root || len(r.selector) == 0 {
+		return n == root && len(r.selector) == 0
+	}
+	last := len(r.selector) - 1
+	if !r.selector[last].match(n) {
+		return false
+	}
+	i := last - 1
+	for p := n.Parent; p != nil && p != root && i >= 0; p = p.Parent {
+		if r.selector[i].match(p) {
+			i--
+		}
+	}
+	return i < 0
+}
+
+var reComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+
+// unsupported 邮件中没有意义的属性
+var unsupported = map[string]bool{"transition": true, "cursor": true}
+
+// parseCSS 解析样式表；scope 不为空时只保留以 scope 开头的选择器，并去掉 scope 部分
+func parseCSS(css, scope string) []rule {
+	css = reComment.ReplaceAllString(css, "")
+	var rules []rule
+	for {
+		open := strings.IndexByte(css, '{')
+		if open < 0 {
+			break
+		}
+		// 找到配对的右括号，跳过 @media 等嵌套的规则块
+		end, depth := -1, 0
+		for i := open; i < len(css) && end < 0; i++ {
+			switch css[i] {
+			case '{':
+				depth++
+			case '}':
+				if depth--; depth == 0 {
+					end = i
+				}
+			}
+		}
+		if end < 0 {
+			break
+		}
+		head, body := strings.TrimSpace(css[:open]), css[open+1:end]
+		css = css[end+1:]
+		if strings.HasPrefix(head, "@") {
+			continue
+		}
+		decls := parseDeclarations(body)
+		for _, s := range strings.Split(head, ",") {
+			if selector, ok := parseSelector(strings.TrimSpace(s), scope); ok {
+				r := rule{selector: selector, decls: decls}
+				for _, c := range selector {
+					if c.tag != "" {
+						r.specificity++
+					}
+					r.specificity += 10 * len(c.classes)
+				}
+				rules = append(rules, r)
+			}
+		}
+	}
+	return rules
+}
+
+func parseSelector(s, scope string) ([]compound, bool) {
+	if scope != "" {
+		if s == scope {
+			return nil, true
+		}
+		var ok bool
+		if s, ok = strings.CutPrefix(s, scope+" "); !ok {
+			return nil, false
+		}
+	}
+	if s == "" || strings.ContainsAny(s, ":>+~[#*") {
+		return nil, false
+	}
+	var selector []compound
+	for _, part := range strings.Fields(s) {
+		names := strings.Split(part, ".")
+		c := compound{tag: strings.ToLower(names[0])}
+		for _, class := range names[1:] {
+			if class == "" {
+				return nil, false
+			}
+			c.classes = append(c.classes, class)
+		}
+		selector = append(selector, c)
+	}
+	return selector, true
+}
+
+// parseDeclarations 解析 "color: red; margin: 0 !important" 形式的声明
+func parseDeclarations(s string) []declaration {
+	var decls []declaration
+	for _, item := range strings.Split(s, ";") {
+		prop, value, ok := strings.Cut(item, ":")
+		prop, value = strings.ToLower(strings.TrimSpace(prop)), strings.TrimSpace(value)
+		if !ok || prop == "" || value == "" || unsupported[prop] {
+			continue
+		}
+		value, important := strings.CutSuffix(value, "!important")
+		decls = append(decls, declaration{prop: prop, value: strings.TrimSpace(value), important: important})
+	}
+	return decls
+}
+
+// inline 将规则写入 root 及其后代元素的 style 属性
+// 优先级从低到高：样式表 (按选择器权重与出现顺序)、元素原有的内联样式、样式表中的 !important 声明。
+func inline(root *html.Node, rules []rule) {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].specificity < rules[j].specificity
+	})
+	walk(root, func(n *html.Node) {
+		var normal, important []declaration
+		for _, r := range rules {
+			if !r.match(n, root) {
+				continue
+			}
+			for _, d := range r.decls {
+				if d.important {
+					important = append(important, d)
+				} else {
+					normal = append(normal, d)
+				}
+			}
+		}
+		if len(normal) == 0 && len(important) == 0 {
+			return
+		}
+		decls := append(normal, parseDeclarations(attr(n, "style"))...)
+		setAttr(n, "style", formatStyle(append(decls, important...)))
+	})
+}
+
+// formatStyle 合并声明，同一属性以后出现的为准并移到末尾，保证简写属性 (border) 与分项属性 (border-bottom) 的覆盖顺序
+func formatStyle(decls []declaration) string {
+	var merged []declaration
+	for _, d := range decls {
+		for i, m := range merged {
+			if m.prop == d.prop {
+				merged = append(merged[:i], merged[i+1:]...)
+				break
+			}
+		}
+		merged = append(merged, d)
+	}
+	parts := make([]string, len(merged))
+	for i, d := range merged {
+		parts[i] = d.prop + ": " + d.value + ";"
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,179 @@
+// Package newsletter 将文章生成为邮件 (Newsletter)，并通过 SMTP 发送
+// 邮件客户端只支持有限的 HTML：版式使用表格，样式全部内联到元素的 style 属性，
+// 图片必须是图床上的绝对地址；邮件同时附带纯文本版本，供不显示 HTML 的客户端使用。
+package newsletter
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/hankmor/mymedia/tools/wechat-preview/render"
+)
+
+// Options 邮件配置
+type Options struct {
+	CSS     string // 文章样式表，其中 .article-content 下的规则内联到正文元素
+	URL     string // 文章的线上地址，生成「阅读原文」按钮，为空时省略
+	Summary string // 摘要，作为收件箱列表中的预览文字
+	Color   string // 按钮等元素的主色
+}
+
+// Message 生成的邮件
+type Message struct {
+	Subject string
+	HTML    string   // 表格版式、样式内联的 HTML
+	Text    string   // 纯文本版本
+	Local   []string // 未上传到图床的图片地址，邮件中无法显示
+}
+
+// emailCSS 邮件专用的样式，在文章样式表之后内联；!important 的声明优先于元素原有的内联样式
+const emailCSS = `
+/* 邮件客户端大多不支持横向滚动，代码自动换行 */
+pre code { white-space: pre-wrap !important; word-break: break-all !important; overflow-x: visible !important; }
+img { height: auto !important; max-width: 100% !important; box-shadow: none !important; }
+`
+
+// blockTags 邮件客户端 (尤其是 Outlook) 不认识的 HTML5 区块元素，转为 div
+var blockTags = map[atom.Atom]bool{atom.Section: true, atom.Figure: true, atom.Figcaption: true}
+
+const fontFamily = `-apple-system, BlinkMacSystemFont, 'PingFang SC', 'Hiragino Sans GB', 'Microsoft YaHei', 'Helvetica Neue', Arial, sans-serif`
+
+var page = template.Must(template.New("email").Parse(`<!DOCTYPE html>
+<html lang="zh-CN" xmlns="http://www.w3.org/1999/xhtml">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <meta http-equiv="X-UA-Compatible" content="IE=edge">
+    <title>{{ .Title }}</title>
+</head>
+
+<body style="margin: 0; padding: 0; background: #f4f4f4;">
+    {{ with .Summary }}<div style="display: none; max-height: 0; overflow: hidden; mso-hide: all;">{{ . }}</div>{{ end }}
+    <table role="presentation" width="100%" cellpadding="0" cellspacing="0" border="0" bgcolor="#f4f4f4" style="background: #f4f4f4;">
+        <tr>
+            <td align="center" style="padding: 24px 12px;">
+                <table role="presentation" width="640" cellpadding="0" cellspacing="0" border="0" bgcolor="#ffffff" style="width: 100%; max-width: 640px; background: #ffffff; border-radius: 8px;">
+                    <tr>
+                        <td style="padding: 32px 32px 0; font-family: {{ .Font }};">
+                            <h1 style="margin: 0; font-size: 24px; font-weight: bold; line-height: 1.4; color: #2c3e50;">{{ .Title }}</h1>
+                        </td>
+                    </tr>
+                    <tr>
+                        <td style="padding: 8px 32px 24px; font-family: {{ .Font }}; font-size: 16px; line-height: 1.75; color: #333333;">
+                            {{ .Content }}
+                        </td>
+                    </tr>
+                    {{- if .URL }}
+                    <tr>
+                        <td align="center" style="padding: 0 32px 32px; font-family: {{ .Font }};">
+                            <a href="{{ .URL }}" style="display: inline-block; padding: 10px 28px; border-radius: 6px; background: {{ .Color }}; color: #ffffff; font-size: 15px; text-decoration: none;">阅读原文</a>
+                        </td>
+                    </tr>
+                    {{- end }}
+                </table>
+            </td>
+        </tr>
+    </table>
+</body>
+
+</html>
+`))
+
+// Build 将发布目标渲染的文章 (本地图片已上传到图床) 生成为邮件
+func Build(doc *render.Document, opts Options) (*Message, error) {
+	root := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := html.ParseFragment(strings.NewReader(doc.HTML), root)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range nodes {
+		root.AppendChild(n)
+	}
+
+	inline(root, append(parseCSS(opts.CSS, ".article-content"), parseCSS(emailCSS, "")...))
+
+	msg := &Message{Subject: doc.Title}
+	walk(root, func(n *html.Node) {
+		if blockTags[n.DataAtom] {
+			n.Data, n.DataAtom = "div", atom.Div
+		}
+		if n.DataAtom != atom.Img {
+			return
+		}
+		src := attr(n, "src")
+		switch {
+		case strings.HasPrefix(src, "//"):
+			setAttr(n, "src", "https:"+src)
+		case !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://"):
+			msg.Local = append(msg.Local, src)
+		}
+		setAttr(n, "border", "0")
+	})
+
+	msg.Text = plainText(root)
+	if opts.URL != "" {
+		msg.Text += "\n\n阅读原文: " + opts.URL
+	}
+	msg.Text = doc.Title + "\n\n" + msg.Text + "\n"
+
+	var content strings.Builder
+	if err := html.Render(&content, root); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	err = page.Execute(&buf, map[string]any{
+		"Title":   doc.Title,
+		"Summary": opts.Summary,
+		"URL":     opts.URL,
+		"Color":   template.CSS(opts.Color),
+		"Font":    template.CSS(fontFamily),
+		"Content": template.HTML(content.String()),
+	})
+	if err != nil {
+		return nil, err
+	}
+	msg.HTML = buf.String()
+	return msg, nil
+}
+
+// walk 深度优先遍历元素节点
+func walk(n *html.Node, fn func(n *html.Node)) {
+	if n.Type == html.ElementNode {
+		fn(n)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, fn)
+	}
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func setAttr(n *html.Node, key, val string) {
+	for i, a := range n.Attr {
+		if a.Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
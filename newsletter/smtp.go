@@ -0,0 +1,154 @@
+package newsletter
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTP 发信服务器
+// 465 端口使用隐式 TLS，其他端口在服务器支持时自动 STARTTLS；
+// 未加密的连接只允许向 localhost 发送认证信息，本地调试用的 SMTP 服务可不设置用户名。
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string // 发件人，例如 "Hank <hank@example.com>"
+}
+
+// Send 将邮件逐个发送给收件人，每封邮件只包含一个收件人
+func (s SMTP) Send(msg *Message, to []string) error {
+	from, err := mail.ParseAddress(s.From)
+	if err != nil {
+		return fmt.Errorf("发件人地址无效: %s: %w", s.From, err)
+	}
+	for _, rcpt := range to {
+		addr, err := mail.ParseAddress(rcpt)
+		if err != nil {
+			return fmt.Errorf("收件人地址无效: %s: %w", rcpt, err)
+		}
+		data, err := msg.Bytes(from.String(), addr.String())
+		if err != nil {
+			return err
+		}
+		if err := s.send(from.Address, addr.Address, data); err != nil {
+			return fmt.Errorf("发送到 %s 失败: %w", addr.Address, err)
+		}
+	}
+	return nil
+}
+
+func (s SMTP) send(from, to string, data []byte) error {
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	if s.Port != 465 {
+		return smtp.SendMail(addr, auth, from, []string{to}, data)
+	}
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: s.Host})
+	if err != nil {
+		return err
+	}
+	c, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if auth != nil {
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// Bytes 编码为 multipart/alternative 邮件：纯文本在前，HTML 在后，客户端优先显示最后一个能够显示的版本
+func (m *Message) Bytes(from, to string) ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct{ typ, content string }{
+		{"text/plain", m.Text},
+		{"text/html", m.HTML},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.typ + "; charset=UTF-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	header := [][2]string{
+		{"From", from},
+		{"To", to},
+		{"Subject", mime.BEncoding.Encode("UTF-8", m.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", messageID(from)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": mw.Boundary()})},
+	}
+	for _, h := range header {
+		fmt.Fprintf(&buf, "%s: %s\r\n", h[0], h[1])
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+// messageID 随机生成的 Message-ID，域名取自发件人地址
+func messageID(from string) string {
+	domain := "localhost"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if _, d, ok := strings.Cut(addr.Address, "@"); ok {
+			domain = d
+		}
+	}
+	b := make([]byte, 12)
+	rand.Read(b)
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(b), domain)
+}
@@ -0,0 +1,225 @@
+package newsletter
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// 纯文本版本：段落之间空一行，标题加 # 前缀，列表使用 - 与 1. 标记，
+// 代码缩进四个空格，引用加 > 前缀，链接在文字后用括号写出地址，图片写为 [图片: 说明]。
+
+// textBlocks 按块输出的元素，其余元素视为行内内容
+var textBlocks = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Figure: true, atom.Figcaption: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Blockquote: true, atom.Pre: true,
+	atom.Table: true, atom.Hr: true, atom.Details: true, atom.Summary: true,
+}
+
+// plainText 将正文转换为纯文本
+func plainText(root *html.Node) string {
+	return strings.Join(blocks(root), "\n\n")
+}
+
+// blocks 子节点的文本，每个块级元素或一段连续的行内内容为一块
+func blocks(n *html.Node) []string {
+	var out []string
+	var line strings.Builder
+	flush := func() {
+		if s := trimLines(line.String()); s != "" {
+			out = append(out, s)
+		}
+		line.Reset()
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || !textBlocks[c.DataAtom] {
+			inlineText(&line, c)
+			continue
+		}
+		flush()
+		if s := block(c); s != "" {
+			out = append(out, s)
+		}
+	}
+	flush()
+	return out
+}
+
+func block(n *html.Node) string {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		return strings.Repeat("#", level) + " " + text(n)
+	case atom.Ul, atom.Ol:
+		return list(n)
+	case atom.Pre:
+		return prefix(strings.TrimRight(preText(n), "\n"), "    ")
+	case atom.Blockquote:
+		return prefix(strings.Join(blocks(n), "\n\n"), "> ")
+	case atom.Table:
+		return table(n)
+	case atom.Hr:
+		return "----------"
+	}
+	// 代码块的语言标签只在 HTML 中显示
+	if hasClass(n, "code-header") {
+		return ""
+	}
+	return strings.Join(blocks(n), "\n\n")
+}
+
+// list 列表，条目中的后续行与子列表按标记宽度缩进
+func list(n *html.Node) string {
+	num := 1
+	if start, err := strconv.Atoi(attr(n, "start")); err == nil {
+		num = start
+	}
+	var lines []string
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d. ", num)
+			num++
+		}
+		body := prefix(strings.Join(blocks(li), "\n"), strings.Repeat(" ", len(marker)))
+		lines = append(lines, marker+strings.TrimLeft(body, " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// table 每行一条，单元格之间用 | 分隔，嵌套表格的内容并入所在单元格
+func table(n *html.Node) string {
+	var rows []string
+	walk(n, func(tr *html.Node) {
+		if tr.DataAtom != atom.Tr || closest(tr, atom.Table) != n {
+			return
+		}
+		var cells []string
+		for td := tr.FirstChild; td != nil; td = td.NextSibling {
+			if td.DataAtom == atom.Td || td.DataAtom == atom.Th {
+				cells = append(cells, text(td))
+			}
+		}
+		if len(cells) > 0 {
+			rows = append(rows, strings.Join(cells, " | "))
+		}
+	})
+	return strings.Join(rows, "\n")
+}
+
+// closest 最近的指定类型的祖先元素
+func closest(n *html.Node, a atom.Atom) *html.Node {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.DataAtom == a {
+			return p
+		}
+	}
+	return nil
+}
+
+// text 元素内的全部文字，合并为一行
+func text(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		inlineText(&b, c)
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func inlineText(b *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		// 首尾的空白折叠为一个空格，保留与相邻元素之间的间隔
+		if strings.IndexFunc(n.Data, isSpace) == 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(strings.Join(strings.FieldsFunc(n.Data, isSpace), " "))
+		if len(n.Data) > 0 && isSpace(rune(n.Data[len(n.Data)-1])) {
+			b.WriteByte(' ')
+		}
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+	switch n.DataAtom {
+	case atom.Script, atom.Style:
+	case atom.Br:
+		b.WriteByte('\n')
+	case atom.Img:
+		b.WriteString("[图片: " + cmp.Or(attr(n, "alt"), "无说明") + "]")
+	case atom.A:
+		label, href := text(n), attr(n, "href")
+		b.WriteString(label)
+		if (strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://")) && href != label {
+			b.WriteString(" (" + href + ")")
+		}
+	default:
+		if textBlocks[n.DataAtom] { // 行内内容中嵌套的块级元素单独成行
+			b.WriteString("\n" + block(n) + "\n")
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			inlineText(b, c)
+		}
+		if hasClass(n, "task-checkbox") {
+			b.WriteByte(' ')
+		}
+	}
+}
+
+// preText 代码块原样保留换行，高亮后的代码使用 <br> 与不换行空格
+func preText(n *html.Node) string {
+	var b strings.Builder
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			b.WriteString(strings.ReplaceAll(n.Data, "\u00a0", " "))
+		case n.DataAtom == atom.Br:
+			b.WriteByte('\n')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(n)
+	return b.String()
+}
+
+// isSpace HTML 中折叠的空白，不含不换行空格
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f'
+}
+
+// trimLines 去掉每行首尾的空白与空行
+func trimLines(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(strings.ReplaceAll(line, "\u00a0", " ")); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// prefix 为每行添加前缀，空行只保留前缀中的非空白部分
+func prefix(s, p string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = strings.TrimRight(p, " ")
+		} else {
+			lines[i] = p + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,142 @@
+// Package pangu 中文排版修正
+// 在中文与英文、数字之间插入空格，将紧跟中文的半角标点替换为全角，并把中文语境中的 ... 改为 ……。
+// 文本中的 URL、邮箱与 Hugo 短代码保持原样。
+package pangu
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// protectedPattern 不做修正的片段：URL、邮箱、Hugo 短代码
+// URL 只匹配 ASCII 字符，紧跟其后的中文不计入 URL
+var protectedPattern = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s<>"'\x{80}-\x{10FFFF}]+|www\.[^\s<>"'\x{80}-\x{10FFFF}]+|[\w.+-]+@[\w-]+(\.[\w-]+)+|\{\{[<%].*?[%>]\}\}`)
+
+// fullWidth 中文语境下需要替换为全角的半角标点
+var fullWidth = map[rune]rune{
+	',': '，',
+	'.': '。',
+	';': '；',
+	':': '：',
+	'?': '？',
+	'!': '！',
+}
+
+// isFullWidth 是否为会被替换成的全角标点，其后不需要空格
+func isFullWidth(r rune) bool {
+	for _, full := range fullWidth {
+		if r == full {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCJK 是否为中日韩文字 (不含标点)
+func IsCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// isLatin 是否为需要与中文隔开的英文字母或数字
+func isLatin(r rune) bool {
+	return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// NeedSpace 相邻的两个字符之间是否需要插入空格
+func NeedSpace(left, right rune) bool {
+	if IsCJK(left) {
+		return isLatin(right)
+	}
+	if IsCJK(right) {
+		return isLatin(left) || left == '%'
+	}
+	return false
+}
+
+// Fix 修正一段文本
+func Fix(s string) string {
+	return FixAfter(0, s)
+}
+
+// FixAfter 修正紧跟在字符 left 之后的一段文本，left 为 0 表示没有前文
+// 用于被强调等标记分隔开的相邻文本，边界处的空格与标点按前文判断
+func FixAfter(left rune, s string) string {
+	if !IsCJK(left) && !isFullWidth(left) && !strings.ContainsFunc(s, IsCJK) {
+		return s
+	}
+	// 全角标点之后不再保留空格
+	if isFullWidth(left) {
+		if trimmed := strings.TrimLeft(s, " "); trimmed != "" {
+			s = trimmed
+		}
+	}
+
+	rs := []rune(s)
+	protected := make([]bool, len(rs))
+	for _, loc := range protectedPattern.FindAllStringIndex(s, -1) {
+		start := len([]rune(s[:loc[0]]))
+		end := start + len([]rune(s[loc[0]:loc[1]]))
+		for i := start; i < end; i++ {
+			protected[i] = true
+		}
+	}
+
+	out := make([]rune, 0, len(rs)+8)
+	last := func() rune {
+		if len(out) > 0 {
+			return out[len(out)-1]
+		}
+		return left
+	}
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		if protected[i] {
+			if (i == 0 || !protected[i-1]) && NeedSpace(last(), r) {
+				out = append(out, ' ')
+			}
+			out = append(out, r)
+			continue
+		}
+
+		prevCJK := IsCJK(last())
+
+		// 省略号：连续三个及以上的 . 紧邻中文时改为 ……
+		if r == '.' {
+			end := i
+			for end < len(rs) && rs[end] == '.' && !protected[end] {
+				end++
+			}
+			if end-i >= 3 {
+				if prevCJK || (end < len(rs) && IsCJK(rs[end])) {
+					out = append(out, '…', '…')
+				} else {
+					out = append(out, rs[i:end]...)
+				}
+				i = end - 1
+				continue
+			}
+		}
+
+		// 紧跟中文、后面不是英文数字的半角标点改为全角，并去掉其后的空格
+		if full, ok := fullWidth[r]; ok && prevCJK {
+			next := i + 1
+			for next < len(rs) && rs[next] == ' ' {
+				next++
+			}
+			if i+1 == len(rs) || !isLatin(rs[i+1]) {
+				out = append(out, full)
+				if next < len(rs) {
+					i = next - 1
+				}
+				continue
+			}
+		}
+
+		if NeedSpace(last(), r) {
+			out = append(out, ' ')
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
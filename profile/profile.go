@@ -0,0 +1,81 @@
+// Package profile 多平台发布配置
+// 文章除了发到公众号，还会同步到知乎、掘金、CSDN、头条号，各平台编辑器接受的内容不同：
+// 配置决定发布时输出富文本 HTML 还是 Markdown、外链如何处理以及标题使用哪个主题的装饰。
+// 图片上传、relref 线上地址与公式转图片对所有平台相同。
+package profile
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hankmor/mymedia/tools/wechat-preview/links"
+)
+
+// Format 复制到平台编辑器的内容格式
+type Format string
+
+const (
+	HTML     Format = "html"     // 渲染后的富文本，粘贴到可视化编辑器
+	Markdown Format = "markdown" // Markdown 正文，粘贴到 Markdown 编辑器
+)
+
+// Profile 发布平台配置
+type Profile struct {
+	Name   string        `json:"name"`
+	Label  string        `json:"label"`  // 工具栏中显示的平台名称
+	Format Format        `json:"format"` // 输出格式
+	Links  *links.Policy `json:"-"`      // 链接策略，nil 表示使用 LINK_POLICY 配置
+	Theme  string        `json:"-"`      // 标题装饰使用的主题，空表示使用 THEME 配置
+	Lint   bool          `json:"lint"`   // 发布前进行微信兼容性检查
+	Cover  bool          `json:"cover"`  // frontmatter 未设置 cover 时生成并上传封面
+}
+
+// clickable 外链可以点击的平台使用的链接策略：保留原链接，页内锚点与本地链接转为文字
+var clickable = mustParse("#=plain;local=plain;*=keep")
+
+var (
+	// WeChat 微信公众号：HTML，外链按 LINK_POLICY 转为文末引用等
+	WeChat = &Profile{Name: "wechat", Label: "微信公众号", Format: HTML, Lint: true, Cover: true}
+	// Zhihu 知乎：HTML，外链可以点击，保留原链接，不转为文末引用
+	Zhihu = &Profile{Name: "zhihu", Label: "知乎", Format: HTML, Links: clickable}
+	// Juejin 掘金：Markdown 编辑器，图片为 CDN 地址，relref 为博客地址
+	Juejin = &Profile{Name: "juejin", Label: "掘金", Format: Markdown}
+	// CSDN 与掘金相同，使用 Markdown 编辑器
+	CSDN = &Profile{Name: "csdn", Label: "CSDN", Format: Markdown}
+	// Toutiao 头条号：HTML，编辑器只保留一级标题，标题使用 toutiao 主题；外链不可点击，按 LINK_POLICY 处理
+	Toutiao = &Profile{Name: "toutiao", Label: "头条号", Format: HTML, Theme: "toutiao"}
+	// Email 邮件 (Newsletter)：HTML，外链保留；由 send 命令使用，不在工具栏中列出
+	Email = &Profile{Name: "email", Label: "邮件", Format: HTML, Links: clickable}
+)
+
+// profiles 按工具栏中的显示顺序排列，第一个为默认配置
+var profiles = []*Profile{WeChat, Zhihu, Juejin, CSDN, Toutiao}
+
+// All 所有发布平台配置
+func All() []*Profile {
+	return profiles
+}
+
+// Get 按名称获取配置，空名称为微信公众号
+func Get(name string) (*Profile, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return WeChat, nil
+	}
+	names := make([]string, len(profiles))
+	for i, p := range profiles {
+		if p.Name == name {
+			return p, nil
+		}
+		names[i] = p.Name
+	}
+	return nil, fmt.Errorf("未知的发布平台: %s (可选 %s)", name, strings.Join(names, "、"))
+}
+
+func mustParse(rules string) *links.Policy {
+	policy, err := links.Parse(rules)
+	if err != nil {
+		panic(err)
+	}
+	return policy
+}
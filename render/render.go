@@ -0,0 +1,134 @@
+// Package render 文章渲染管线
+// 预览页、API 与发布共用同一条按阶段排列的管线：
+// 规范化 → Frontmatter → 移除标题 → 短代码 → Markdown → HTML 处理 → 资源改写，
+// 各阶段根据渲染目标 (预览、发布、导出) 调整输出，新的处理步骤只需注册一次即可对所有入口生效。
+package render
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/yuin/goldmark/parser"
+
+	"github.com/hankmor/mymedia/tools/wechat-preview/links"
+	"github.com/hankmor/mymedia/tools/wechat-preview/markdown"
+	"github.com/hankmor/mymedia/tools/wechat-preview/profile"
+	"github.com/hankmor/mymedia/tools/wechat-preview/sanitize"
+	"github.com/hankmor/mymedia/tools/wechat-preview/services"
+	"github.com/hankmor/mymedia/tools/wechat-preview/shortcode"
+)
+
+// 渲染目标
+const (
+	Preview = markdown.TargetPreview // 本地预览
+	Publish = markdown.TargetPublish // 发布到微信
+	Export  = markdown.TargetExport  // 导出为独立文件
+)
+
+// Phase 管线阶段，同一阶段内的处理按注册顺序执行
+type Phase int
+
+const (
+	PhaseNormalize   Phase = iota // 统一换行、去掉 BOM
+	PhaseFrontmatter              // 解析并移除 Frontmatter
+	PhaseTitle                    // 移除 H1 标题
+	PhaseShortcodes               // 展开 Hugo 短代码
+	PhaseMarkdown                 // Markdown 转 HTML
+	PhaseHTML                     // HTML 后处理
+	PhaseAssets                   // 图片等资源地址改写
+)
+
+// Document 渲染中的文章，各阶段依次读写其中的字段
+type Document struct {
+	Path   string          // 文章文件路径
+	Title  string          // 文章标题
+	Series string          // 所属系列，文章目录下直接存放的文章为空
+	Target markdown.Target // 渲染目标
+	Source string          // 原始文件内容
+
+	Frontmatter map[string]string // Frontmatter 中 "key: value" 形式的简单字段
+	Body        string            // 移除 Frontmatter 与标题后的 Markdown，未经短代码处理，便于编辑
+	Markdown    string            // 当前的 Markdown
+	HTML        string            // 渲染结果
+
+	Context    parser.Context          // Markdown 转换使用的解析上下文，Markdown 之前的阶段可写入单篇文章的选项
+	Headings   []*markdown.Heading     // 标题树
+	Shortcodes *shortcode.Result       // 短代码输出，Markdown 阶段转换后还原
+	Publish    *services.PublishResult // 发布目标下的图片上传结果
+	Links      []links.Result          // 发布目标下各链接的处理结果
+	Sanitized  []sanitize.Issue        // HTML 清理移除 (或 warn 模式下发现) 的内容
+	Assets     []Asset                 // 导出目标下随文章打包的图片
+	Profile    *profile.Profile        // 发布平台，nil 与微信公众号相同
+}
+
+// Asset 导出时随文章打包的本地图片
+type Asset struct {
+	Path string // 导出后的相对路径，例如 images/demo.png
+	File string // 本地文件
+}
+
+// URL 图片在导出的 HTML 与 Markdown 中的地址 (对中文、空格等字符编码)
+func (a Asset) URL() string {
+	return (&url.URL{Path: a.Path}).String()
+}
+
+// NewDocument 创建待渲染的文章
+func NewDocument(path, title, source string, target markdown.Target) *Document {
+	return &Document{
+		Path:        path,
+		Title:       title,
+		Target:      target,
+		Source:      source,
+		Markdown:    source,
+		Frontmatter: make(map[string]string),
+		Context:     markdown.NewContext(target),
+	}
+}
+
+// Stage 管线中的一个处理步骤
+type Stage struct {
+	Name  string
+	Phase Phase
+	Run   func(doc *Document) error
+}
+
+// Pipeline 渲染管线
+type Pipeline struct {
+	stages []Stage
+}
+
+// New 创建管线
+func New(stages ...Stage) *Pipeline {
+	p := &Pipeline{}
+	p.Use(stages...)
+	return p
+}
+
+// Use 注册处理步骤，按阶段排序，同一阶段内保持注册顺序
+func (p *Pipeline) Use(stages ...Stage) *Pipeline {
+	p.stages = append(p.stages, stages...)
+	sort.SliceStable(p.stages, func(i, j int) bool {
+		return p.stages[i].Phase < p.stages[j].Phase
+	})
+	return p
+}
+
+// Stages 按执行顺序返回各步骤名称
+func (p *Pipeline) Stages() []string {
+	names := make([]string, len(p.stages))
+	for i, s := range p.stages {
+		names[i] = s.Name
+	}
+	return names
+}
+
+// Render 依次执行各步骤，遇到错误时停止
+func (p *Pipeline) Render(doc *Document) error {
+	for _, s := range p.stages {
+		if err := s.Run(doc); err != nil {
+			return fmt.Errorf("%s: %w", s.Name, err)
+		}
+	}
+	return nil
+}
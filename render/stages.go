@@ -0,0 +1,471 @@
+package render
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+
+	"github.com/hankmor/mymedia/tools/wechat-preview/cover"
+	"github.com/hankmor/mymedia/tools/wechat-preview/links"
+	"github.com/hankmor/mymedia/tools/wechat-preview/markdown"
+	"github.com/hankmor/mymedia/tools/wechat-preview/profile"
+	"github.com/hankmor/mymedia/tools/wechat-preview/sanitize"
+	"github.com/hankmor/mymedia/tools/wechat-preview/services"
+	"github.com/hankmor/mymedia/tools/wechat-preview/shortcode"
+	"github.com/hankmor/mymedia/tools/wechat-preview/theme"
+)
+
+// Normalize 去掉 BOM，统一换行符为 \n
+func Normalize() Stage {
+	return Stage{Name: "normalize", Phase: PhaseNormalize, Run: func(doc *Document) error {
+		content := strings.TrimPrefix(doc.Markdown, "\ufeff")
+		doc.Markdown = strings.ReplaceAll(content, "\r\n", "\n")
+		return nil
+	}}
+}
+
+// Frontmatter 解析 YAML Frontmatter 中的简单字段并从正文中移除
+func Frontmatter() Stage {
+	return Stage{Name: "frontmatter", Phase: PhaseFrontmatter, Run: func(doc *Document) error {
+		front, body := SplitFrontmatter(doc.Markdown)
+		for key, value := range ParseFrontmatter(front) {
+			doc.Frontmatter[key] = value
+		}
+		doc.Markdown = body
+		return nil
+	}}
+}
+
+// SplitFrontmatter 拆分 Frontmatter 与正文，front 包含首尾的 --- 行，没有 Frontmatter 时为空
+func SplitFrontmatter(content string) (front, body string) {
+	if !strings.HasPrefix(strings.TrimSpace(content), "---") {
+		return "", content
+	}
+
+	lines := strings.Split(content, "\n")
+	start, end := -1, -1
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "---" {
+			if start == -1 {
+				start = i
+			} else {
+				end = i
+				break
+			}
+		}
+	}
+	if start == -1 || end == -1 {
+		return "", content
+	}
+	return strings.Join(lines[:end+1], "\n") + "\n", strings.Join(lines[end+1:], "\n")
+}
+
+// ParseFrontmatter 读取 Frontmatter 中 "key: value" 形式的顶层字段
+func ParseFrontmatter(front string) map[string]string {
+	values := make(map[string]string)
+	for _, line := range strings.Split(front, "\n") {
+		line = strings.TrimRight(line, "\r")
+		// 缩进行属于嵌套结构，不作为顶层字段
+		if line == "" || line[0] == ' ' || line[0] == '\t' || line == "---" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		values[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), "\"'")
+	}
+	return values
+}
+
+// FrontmatterSwitch 读取 Frontmatter 中的布尔开关 (例如 pangu: false)，设置了时通过 set 写入解析上下文
+func FrontmatterSwitch(key string, set func(pc parser.Context, enabled bool)) Stage {
+	return Stage{Name: "frontmatter-" + key, Phase: PhaseFrontmatter, Run: func(doc *Document) error {
+		value, ok := doc.Frontmatter[key]
+		if !ok {
+			return nil
+		}
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			log.Printf("Warning: %s frontmatter %s 应为 true 或 false: %q\n", doc.Path, key, value)
+			return nil
+		}
+		set(doc.Context, enabled)
+		return nil
+	}}
+}
+
+// FrontmatterValue 读取 Frontmatter 中的字段，设置了时通过 set 写入解析上下文，取值无效时记录警告并忽略
+func FrontmatterValue(key string, set func(pc parser.Context, value string) error) Stage {
+	return Stage{Name: "frontmatter-" + key, Phase: PhaseFrontmatter, Run: func(doc *Document) error {
+		value, ok := doc.Frontmatter[key]
+		if !ok {
+			return nil
+		}
+		if err := set(doc.Context, value); err != nil {
+			log.Printf("Warning: %s frontmatter %s: %v\n", doc.Path, key, err)
+		}
+		return nil
+	}}
+}
+
+// ProfileTheme 发布平台指定了主题时 (例如头条号)，标题装饰改用该主题
+func ProfileTheme() Stage {
+	return Stage{Name: "profile-theme", Phase: PhaseFrontmatter, Run: func(doc *Document) error {
+		if doc.Profile != nil && doc.Profile.Theme != "" {
+			markdown.SetHeadingTheme(doc.Context, theme.Get(doc.Profile.Theme))
+		}
+		return nil
+	}}
+}
+
+// TOCMarker Frontmatter 设置 toc: true 且正文没有目录标记时，在正文开头插入 [TOC]
+// 需要注册在 Shortcodes 之前，[TOC] 不计入 Document.Body
+func TOCMarker() Stage {
+	return Stage{Name: "toc", Phase: PhaseShortcodes, Run: func(doc *Document) error {
+		if doc.Frontmatter["toc"] != "true" {
+			return nil
+		}
+		lower := strings.ToLower(doc.Markdown)
+		if strings.Contains(lower, "[toc]") || strings.Contains(lower, "{{< toc") {
+			return nil
+		}
+		doc.Markdown = "[TOC]\n\n" + doc.Markdown
+		return nil
+	}}
+}
+
+// RemoveTitle 移除正文中的第一个 H1 标题 (文章标题由页面或微信编辑器单独展示)
+func RemoveTitle() Stage {
+	return Stage{Name: "title", Phase: PhaseTitle, Run: func(doc *Document) error {
+		lines := strings.Split(doc.Markdown, "\n")
+		var newLines []string
+		removed := false
+		for _, line := range lines {
+			if !removed && strings.HasPrefix(strings.TrimSpace(line), "# ") {
+				removed = true
+				continue
+			}
+			newLines = append(newLines, line)
+		}
+		doc.Markdown = strings.Join(newLines, "\n")
+		doc.Body = doc.Markdown
+		return nil
+	}}
+}
+
+// Shortcodes 展开 Hugo 短代码，HTML 输出以占位符代替，Markdown 阶段转换后还原
+func Shortcodes(engine *shortcode.Engine) Stage {
+	return Stage{Name: "shortcodes", Phase: PhaseShortcodes, Run: func(doc *Document) error {
+		doc.Shortcodes = engine.Process(doc.Markdown, shortcode.Page{Title: doc.Title, Path: doc.Path, Target: doc.Target})
+		doc.Markdown = doc.Shortcodes.Markdown
+		return nil
+	}}
+}
+
+// Markdown 将 Markdown 转换为 HTML，并还原短代码输出、收集标题树
+func Markdown(md goldmark.Markdown) Stage {
+	return Stage{Name: "markdown", Phase: PhaseMarkdown, Run: func(doc *Document) error {
+		var buf strings.Builder
+		if err := md.Convert([]byte(doc.Markdown), &buf, parser.WithContext(doc.Context)); err != nil {
+			return err
+		}
+		doc.HTML = buf.String()
+		if doc.Shortcodes != nil {
+			doc.HTML = doc.Shortcodes.Restore(doc.HTML)
+		}
+		doc.Headings = markdown.Headings(doc.Context)
+		return nil
+	}}
+}
+
+// Sanitize 按微信白名单检查渲染结果中的原始 HTML，问题记录在 doc.Sanitized
+// 需在其它 HTML 处理之前注册，只检查文章内容，不检查后续步骤生成的标记。
+func Sanitize(mode sanitize.Mode) Stage {
+	return Stage{Name: "sanitize", Phase: PhaseHTML, Run: func(doc *Document) error {
+		doc.HTML, doc.Sanitized = sanitize.Sanitize(doc.HTML, mode)
+		if len(doc.Sanitized) > 0 {
+			verb := "已移除"
+			if mode == sanitize.Warn {
+				verb = "发现"
+			}
+			log.Printf("Warning: %s: %s微信不支持的 HTML: %s", doc.Path, verb, sanitize.Summary(doc.Sanitized))
+		}
+		return nil
+	}}
+}
+
+var reFigure = regexp.MustCompile(`(?s)<figure class="figure"[^>]*>.*?</figure>`)
+
+// FigureNumbers 为 figure 图片按顺序添加 "图 1：" 编号，没有说明文字的图片只显示编号
+// enabled 为默认值，frontmatter figure_numbering 可按文章覆盖
+func FigureNumbers(enabled bool) Stage {
+	return Stage{Name: "figure-numbers", Phase: PhaseHTML, Run: func(doc *Document) error {
+		on := enabled
+		if value, ok := doc.Frontmatter["figure_numbering"]; ok {
+			if v, err := strconv.ParseBool(value); err == nil {
+				on = v
+			}
+		}
+		if !on {
+			return nil
+		}
+
+		n := 0
+		doc.HTML = reFigure.ReplaceAllStringFunc(doc.HTML, func(fig string) string {
+			n++
+			if strings.Contains(fig, markdown.FigcaptionOpen) {
+				return strings.Replace(fig, markdown.FigcaptionOpen, fmt.Sprintf("%s图 %d：", markdown.FigcaptionOpen, n), 1)
+			}
+			return strings.TrimSuffix(fig, "</figure>") + fmt.Sprintf("%s图 %d</figcaption></figure>", markdown.FigcaptionOpen, n)
+		})
+		return nil
+	}}
+}
+
+// LinkPolicy 发布时按链接策略处理正文中的链接，结果记录在 doc.Links；发布平台配置了链接策略时使用平台的策略
+// 二维码图片写入生成资源缓存，随后由 UploadImages 与其它图片一起上传。
+func LinkPolicy(policy *links.Policy, store *markdown.AssetStore) Stage {
+	qrcode := func(href string) (string, error) {
+		name := store.Name("qrcode", href, ".png")
+		if !store.Exists(name) {
+			data, err := links.QRCodePNG(href, 256)
+			if err != nil {
+				return "", err
+			}
+			if err := store.Save(name, data); err != nil {
+				return "", err
+			}
+		}
+		return store.URL(name), nil
+	}
+	return Stage{Name: "link-policy", Phase: PhaseHTML, Run: func(doc *Document) error {
+		if doc.Target != Publish {
+			return nil
+		}
+		p := policy
+		if doc.Profile != nil && doc.Profile.Links != nil {
+			p = doc.Profile.Links
+		}
+		doc.HTML, doc.Links = p.Apply(doc.HTML, qrcode)
+		return nil
+	}}
+}
+
+var (
+	// reImgSrc <img> 标签的 src 属性，不匹配 data-src 以及 script、iframe、source 等标签的 src
+	reImgSrc = regexp.MustCompile(`(<img\s(?:[^>]*?\s)?)src=(["'])([^"']+)["']`)
+	// reSrc 任意位置的 src 属性，用于 Markdown 源码中的短代码参数与原始 HTML
+	reSrc = regexp.MustCompile(`(\s)src=(["'])([^"']+)["']`)
+)
+
+// replaceSrc 按 re 匹配 src 属性并用 fn 改写地址，re 的三个分组依次为属性前的内容、引号、地址
+func replaceSrc(re *regexp.Regexp, s string, fn func(src string) string) string {
+	return re.ReplaceAllStringFunc(s, func(match string) string {
+		m := re.FindStringSubmatch(match)
+		return m[1] + "src=" + m[2] + fn(m[3]) + m[2]
+	})
+}
+
+// LocalImages 预览时将本地图片改写为 /_local_fs 下的地址
+// 图片路径相对文章所在目录解析，例如 ./images/foo.png、../../static/foo.png
+func LocalImages(projectRoot string, store *markdown.AssetStore) Stage {
+	return Stage{Name: "local-images", Phase: PhaseAssets, Run: func(doc *Document) error {
+		if doc.Target != Preview {
+			return nil
+		}
+		articleDir := filepath.Dir(doc.Path)
+		doc.HTML = replaceSrc(reImgSrc, doc.HTML, func(src string) string {
+			// 忽略网络图片，以及已由 /_generated 提供的渲染期生成图片
+			if remoteImage(src) {
+				return src
+			}
+			if _, ok := store.NameFromURL(src); ok {
+				return src
+			}
+
+			absPath := localPath(articleDir, src)
+			relPath, err := filepath.Rel(projectRoot, absPath)
+			if err != nil || strings.HasPrefix(relPath, "..") {
+				log.Printf("Debug: Failed to rewrite image path: %s (Root: %s)\n", src, projectRoot)
+				return src
+			}
+			// Windows 下 filepath.Rel 返回反斜杠，URL 需要正斜杠
+			return "/_local_fs/" + filepath.ToSlash(relPath)
+		})
+		return nil
+	}}
+}
+
+// remoteImage 是否为网络图片或内嵌的 data URI
+func remoteImage(src string) bool {
+	return strings.HasPrefix(src, "http") || strings.HasPrefix(src, "//") || strings.HasPrefix(src, "data:")
+}
+
+var reMarkdownImage = regexp.MustCompile(`(!\[[^\]]*\]\(\s*)(<[^>\n]+>|[^)\s]+)`)
+
+// ExportImages 导出时收集本地图片与渲染期生成的图片，地址改写为 images/ 下的相对路径，文件记录在 doc.Assets
+// 图片解析规则与 LocalImages 相同；doc.Body 中的图片地址同样改写，使导出的 Markdown 与 HTML 引用同一份图片。
+func ExportImages(store *markdown.AssetStore) Stage {
+	return Stage{Name: "export-images", Phase: PhaseAssets, Run: func(doc *Document) error {
+		if doc.Target != Export {
+			return nil
+		}
+		articleDir := filepath.Dir(doc.Path)
+		assets := make(map[string]Asset) // 本地文件 -> 导出的图片
+		used := make(map[string]bool)
+		asset := func(file string) (Asset, bool) {
+			if a, ok := assets[file]; ok {
+				return a, true
+			}
+			if _, err := os.Stat(file); err != nil {
+				log.Printf("Warning: %s: 导出时找不到图片 %s\n", doc.Path, file)
+				return Asset{}, false
+			}
+			// 不同目录下的同名图片加序号区分
+			ext := filepath.Ext(file)
+			base := strings.TrimSuffix(filepath.Base(file), ext)
+			p := "images/" + base + ext
+			for i := 2; used[p]; i++ {
+				p = fmt.Sprintf("images/%s-%d%s", base, i, ext)
+			}
+			used[p] = true
+			a := Asset{Path: p, File: file}
+			assets[file] = a
+			doc.Assets = append(doc.Assets, a)
+			return a, true
+		}
+
+		doc.HTML = replaceSrc(reImgSrc, doc.HTML, func(src string) string {
+			if remoteImage(src) {
+				return src
+			}
+			file := localPath(articleDir, src)
+			if name, ok := store.NameFromURL(src); ok {
+				file = store.Path(name)
+			}
+			a, ok := asset(file)
+			if !ok {
+				return src
+			}
+			return a.URL()
+		})
+
+		// Markdown 图片与短代码、原始 HTML 中的 src 属性，只改写 HTML 中出现过的图片
+		rewrite := func(src string) string {
+			if remoteImage(src) {
+				return src
+			}
+			if a, ok := assets[localPath(articleDir, src)]; ok {
+				return a.URL()
+			}
+			return src
+		}
+		doc.Body = reMarkdownImage.ReplaceAllStringFunc(doc.Body, func(match string) string {
+			m := reMarkdownImage.FindStringSubmatch(match)
+			if src, ok := strings.CutPrefix(m[2], "<"); ok {
+				if rewritten := rewrite(strings.TrimSuffix(src, ">")); rewritten != strings.TrimSuffix(src, ">") {
+					return m[1] + rewritten // 编码后的地址不含空格，不再需要尖括号
+				}
+				return match
+			}
+			return m[1] + rewrite(m[2])
+		})
+		doc.Body = replaceSrc(reSrc, doc.Body, rewrite)
+		return nil
+	}}
+}
+
+// localPath 解析 HTML 中本地图片地址对应的文件路径
+// Markdown 渲染时会对中文、空格等字符做 URL 编码，需要先解码
+func localPath(articleDir, src string) string {
+	if decoded, err := url.PathUnescape(src); err == nil {
+		src = decoded
+	}
+	if filepath.IsAbs(src) {
+		return filepath.Clean(src)
+	}
+	return filepath.Join(articleDir, src)
+}
+
+// UploadImages 发布时上传本地图片与渲染期生成的图片，并替换为 CDN 链接
+func UploadImages(projectRoot string, store *markdown.AssetStore) Stage {
+	return Stage{Name: "upload-images", Phase: PhaseAssets, Run: func(doc *Document) error {
+		if doc.Target != Publish {
+			return nil
+		}
+		doc.Publish = &services.PublishResult{OriginalContent: doc.Source}
+		doc.HTML = services.PublishImages(doc.HTML, doc.Path, projectRoot, store, doc.Publish)
+		// 返回的 Markdown 同样不包含 Frontmatter
+		_, doc.Publish.PublishContent = SplitFrontmatter(doc.Publish.PublishContent)
+		return nil
+	}}
+}
+
+// Covers 发布到需要封面的平台 (公众号) 时生成各尺寸的封面并上传，结果记录在 doc.Publish.Covers：
+// frontmatter 设置了本地 cover 时按 cover_focus 焦点裁剪为 2.35:1 与 1:1，否则由标题与系列名生成；
+// 网络图片封面不裁剪。生成或上传失败 (例如缺少中文字体) 不影响发布，原因记录在 doc.Publish.CoverError。
+// 需注册在 UploadImages 之后。
+func Covers(gen *cover.Generator, projectRoot string, store *markdown.AssetStore) Stage {
+	return Stage{Name: "covers", Phase: PhaseAssets, Run: func(doc *Document) error {
+		p := doc.Profile
+		if p == nil {
+			p = profile.WeChat
+		}
+		if doc.Target != Publish || !p.Cover || doc.Publish == nil {
+			return nil
+		}
+		src, err := cover.FromFrontmatter(doc.Frontmatter, filepath.Dir(doc.Path), projectRoot, cover.Info{Title: doc.Title, Series: doc.Series})
+		if err != nil {
+			doc.Publish.CoverError = fmt.Sprintf("生成封面失败: %v", err)
+			return nil
+		}
+		if src.Remote != "" {
+			doc.Publish.CoverError = fmt.Sprintf("封面为网络图片，未按比例裁剪: %s", src.Remote)
+			return nil
+		}
+		doc.Publish.Covers = make(map[string]string)
+		for _, size := range cover.Sizes {
+			name, err := src.Save(gen, store, size)
+			if err != nil {
+				doc.Publish.CoverError = fmt.Sprintf("生成封面失败: %v", err)
+				return nil
+			}
+			url, err := services.PublishGenerated(store, name)
+			if err != nil {
+				doc.Publish.CoverError = fmt.Sprintf("上传封面失败: %v", err)
+				return nil
+			}
+			doc.Publish.Covers[size.Name] = url
+		}
+		return nil
+	}}
+}
+
+var reTOCLine = regexp.MustCompile(`(?im)^[ \t]*\[toc\][ \t]*\n?`)
+
+// ProfileMarkdown 发布到使用 Markdown 编辑器的平台 (掘金、CSDN) 时生成 Markdown 正文，写入 doc.Publish.PublishContent
+// 短代码已展开：relref 为线上地址，其余短代码为 HTML；本地图片替换为 CDN 地址；[TOC] 由平台自动生成目录，予以移除。
+// 需注册在 UploadImages 之后。
+func ProfileMarkdown() Stage {
+	return Stage{Name: "profile-markdown", Phase: PhaseAssets, Run: func(doc *Document) error {
+		if doc.Target != Publish || doc.Profile == nil || doc.Profile.Format != profile.Markdown || doc.Publish == nil {
+			return nil
+		}
+		content := doc.Markdown
+		if doc.Shortcodes != nil {
+			content = doc.Shortcodes.Restore(content)
+		}
+		content = reTOCLine.ReplaceAllString(content, "")
+		doc.Publish.PublishContent = strings.TrimSpace(services.ReplaceImages(content, doc.Publish.Images)) + "\n"
+		return nil
+	}}
+}
@@ -0,0 +1,65 @@
+package sanitize
+
+// allowedTags 微信正文保留的标签，其余标签去掉标签本身、保留内容
+var allowedTags = set(
+	"p", "br", "hr", "span", "div", "section", "blockquote", "pre", "code",
+	"strong", "b", "em", "i", "u", "s", "del", "ins", "mark", "small", "sup", "sub", "kbd", "abbr",
+	"h1", "h2", "h3", "h4", "h5", "h6",
+	"ul", "ol", "li", "dl", "dt", "dd",
+	"table", "caption", "colgroup", "col", "thead", "tbody", "tfoot", "tr", "th", "td",
+	"a", "img", "figure", "figcaption",
+)
+
+// droppedTags 连同内容一起删除的标签：脚本、样式、嵌入内容与表单控件
+var droppedTags = set(
+	"script", "style", "iframe", "frame", "frameset", "object", "embed", "applet",
+	"noscript", "template", "link", "meta", "base", "title",
+	"input", "button", "textarea", "select", "option",
+	"audio", "video", "canvas", "svg", "math",
+)
+
+// globalAttrs 所有标签都可以保留的属性
+var globalAttrs = set("style", "class", "id", "title", "align", "dir", "lang")
+
+// tagAttrs 特定标签可以保留的属性
+var tagAttrs = map[string]map[string]bool{
+	"a":        set("href"),
+	"img":      set("src", "data-src", "alt", "width", "height"),
+	"td":       set("colspan", "rowspan", "valign", "width"),
+	"th":       set("colspan", "rowspan", "valign", "width"),
+	"col":      set("span", "width"),
+	"colgroup": set("span", "width"),
+	"table":    set("width", "border", "cellpadding", "cellspacing"),
+	"ol":       set("start", "type"),
+	"li":       set("value"),
+}
+
+// urlAttrs 值为地址的属性，需要检查协议
+var urlAttrs = set("href", "src", "data-src")
+
+// allowedProperties 微信编辑器保留的内联样式属性
+// position、float、z-index 等定位属性以及 @font-face 引用的字体会被微信过滤。
+var allowedProperties = set(
+	"color", "background", "background-color", "opacity",
+	"font", "font-size", "font-weight", "font-style", "font-family", "font-variant",
+	"line-height", "letter-spacing", "word-spacing", "text-align", "text-indent",
+	"text-decoration", "text-transform", "text-shadow", "vertical-align",
+	"white-space", "word-break", "word-wrap", "overflow-wrap",
+	"margin", "margin-top", "margin-right", "margin-bottom", "margin-left",
+	"padding", "padding-top", "padding-right", "padding-bottom", "padding-left",
+	"border", "border-top", "border-right", "border-bottom", "border-left",
+	"border-width", "border-style", "border-color", "border-radius", "border-collapse", "border-spacing",
+	"display", "width", "height", "min-width", "max-width", "min-height", "max-height",
+	"overflow", "overflow-x", "overflow-y", "box-sizing", "box-shadow",
+	"list-style", "list-style-type", "list-style-position",
+	"flex", "flex-direction", "flex-wrap", "justify-content", "align-items", "gap",
+	"table-layout", "user-select",
+)
+
+func set(items ...string) map[string]bool {
+	m := make(map[string]bool, len(items))
+	for _, item := range items {
+		m[item] = true
+	}
+	return m
+}
@@ -0,0 +1,258 @@
+// Package sanitize 按微信公众号支持的白名单清理文章中的 HTML
+// Markdown 中的原始 HTML 会原样输出，<script>、<iframe>、<style> 与事件属性既会在预览页执行，
+// 也会在粘贴到微信时被拒绝。清理器按标签、属性与内联样式属性的白名单检查渲染结果，
+// 可以只报告 (warn) 或直接移除 (strip)，未改动的标签按原文输出。
+package sanitize
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// Mode 清理模式
+type Mode string
+
+const (
+	Off   Mode = "off"   // 不检查
+	Warn  Mode = "warn"  // 只报告，不修改 HTML
+	Strip Mode = "strip" // 移除不支持的内容并报告
+)
+
+// ParseMode 解析清理模式，空字符串为 strip，未知模式返回 false
+func ParseMode(s string) (Mode, bool) {
+	switch m := Mode(strings.ToLower(strings.TrimSpace(s))); m {
+	case "":
+		return Strip, true
+	case Off, Warn, Strip:
+		return m, true
+	}
+	return Strip, false
+}
+
+// 问题类型
+const (
+	KindElement   = "element"   // 连同内容删除的标签
+	KindTag       = "tag"       // 去掉标签、保留内容
+	KindAttribute = "attribute" // 删除的属性
+	KindStyle     = "style"     // 删除的内联样式属性
+)
+
+// Issue 一类被移除 (或在 warn 模式下应移除) 的内容，相同的问题合并计数
+type Issue struct {
+	Kind  string `json:"kind"`
+	Name  string `json:"name"` // 标签、属性或样式属性名
+	Tag   string `json:"tag"`  // 所在标签
+	Count int    `json:"count"`
+}
+
+// String 例如 "<div onclick> ×2"
+func (i Issue) String() string {
+	var s string
+	switch i.Kind {
+	case KindElement, KindTag:
+		s = "<" + i.Name + ">"
+	case KindAttribute:
+		s = fmt.Sprintf("<%s %s>", i.Tag, i.Name)
+	default:
+		s = fmt.Sprintf("<%s style=%q>", i.Tag, i.Name)
+	}
+	if i.Count > 1 {
+		s += fmt.Sprintf(" ×%d", i.Count)
+	}
+	return s
+}
+
+// report 按出现顺序合并问题
+type report struct {
+	issues []Issue
+	index  map[Issue]int
+}
+
+func (r *report) add(kind, name, tag string) {
+	key := Issue{Kind: kind, Name: name, Tag: tag}
+	if i, ok := r.index[key]; ok {
+		r.issues[i].Count++
+		return
+	}
+	if r.index == nil {
+		r.index = make(map[Issue]int)
+	}
+	r.index[key] = len(r.issues)
+	key.Count = 1
+	r.issues = append(r.issues, key)
+}
+
+// Sanitize 检查 HTML，strip 模式返回清理后的 HTML，warn 模式返回原文
+func Sanitize(content string, mode Mode) (string, []Issue) {
+	if mode == Off {
+		return content, nil
+	}
+
+	var out bytes.Buffer
+	var r report
+	z := xhtml.NewTokenizer(strings.NewReader(content))
+	for {
+		tt := z.Next()
+		if tt == xhtml.ErrorToken {
+			break
+		}
+		raw := z.Raw()
+		switch tt {
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+			tok := z.Token()
+			name := tok.Data
+			switch {
+			case droppedTags[name]:
+				r.add(KindElement, name, "")
+				if tt == xhtml.StartTagToken && !isVoid(name) {
+					skipElement(z, name)
+				}
+				continue
+			case !allowedTags[name]:
+				r.add(KindTag, name, "")
+				continue
+			}
+			if attrs, changed := cleanAttrs(name, tok.Attr, &r); changed {
+				out.WriteString(renderTag(name, attrs, tt == xhtml.SelfClosingTagToken))
+				continue
+			}
+			out.Write(raw)
+		case xhtml.EndTagToken:
+			if name, _ := z.TagName(); allowedTags[string(name)] {
+				out.Write(raw)
+			}
+		default:
+			out.Write(raw)
+		}
+	}
+
+	// warn 模式只报告问题，HTML 保持原样
+	if mode == Warn {
+		return content, r.issues
+	}
+	return out.String(), r.issues
+}
+
+// skipElement 跳过标签内容直到对应的结束标签
+func skipElement(z *xhtml.Tokenizer, name string) {
+	for depth := 1; depth > 0; {
+		tt := z.Next()
+		if tt == xhtml.ErrorToken {
+			return
+		}
+		if tn, _ := z.TagName(); string(tn) == name {
+			switch tt {
+			case xhtml.StartTagToken:
+				depth++
+			case xhtml.EndTagToken:
+				depth--
+			}
+		}
+	}
+}
+
+// isVoid 没有结束标签的元素
+func isVoid(name string) bool {
+	switch name {
+	case "link", "meta", "base", "input", "embed", "col", "br", "hr", "img":
+		return true
+	}
+	return false
+}
+
+// cleanAttrs 过滤属性与内联样式，返回保留的属性以及是否有改动
+func cleanAttrs(tag string, attrs []xhtml.Attribute, r *report) ([]xhtml.Attribute, bool) {
+	changed := false
+	kept := attrs[:0:0]
+	for _, a := range attrs {
+		key := strings.ToLower(a.Key)
+		switch {
+		case !globalAttrs[key] && !tagAttrs[tag][key]:
+			r.add(KindAttribute, key, tag)
+			changed = true
+			continue
+		case urlAttrs[key] && !safeURL(tag, a.Val):
+			r.add(KindAttribute, key, tag)
+			changed = true
+			continue
+		case key == "style":
+			style, ok := cleanStyle(tag, a.Val, r)
+			if !ok {
+				changed = true
+				if style == "" {
+					continue
+				}
+				a.Val = style
+			}
+		}
+		kept = append(kept, a)
+	}
+	return kept, changed
+}
+
+// safeURL 禁止 javascript: 等可执行协议，data: 只允许用于图片
+func safeURL(tag, val string) bool {
+	v := strings.ToLower(strings.Join(strings.Fields(val), ""))
+	switch {
+	case strings.HasPrefix(v, "javascript:"), strings.HasPrefix(v, "vbscript:"):
+		return false
+	case strings.HasPrefix(v, "data:"):
+		return tag == "img" && strings.HasPrefix(v, "data:image/")
+	}
+	return true
+}
+
+// cleanStyle 过滤内联样式中微信不支持的属性，返回清理后的样式以及是否未做改动
+func cleanStyle(tag, style string, r *report) (string, bool) {
+	ok := true
+	var kept []string
+	for _, decl := range strings.Split(style, ";") {
+		decl = strings.TrimSpace(decl)
+		if decl == "" {
+			continue
+		}
+		prop, value, _ := strings.Cut(decl, ":")
+		prop = strings.ToLower(strings.TrimSpace(prop))
+		lower := strings.ToLower(value)
+		if !allowedProperties[prop] || strings.Contains(lower, "expression(") || strings.Contains(lower, "url(") {
+			r.add(KindStyle, prop, tag)
+			ok = false
+			continue
+		}
+		kept = append(kept, decl)
+	}
+	if ok {
+		return style, true
+	}
+	if len(kept) == 0 {
+		return "", false
+	}
+	return strings.Join(kept, "; ") + ";", false
+}
+
+// renderTag 输出改写后的开始标签
+func renderTag(name string, attrs []xhtml.Attribute, selfClosing bool) string {
+	var b strings.Builder
+	b.WriteString("<" + name)
+	for _, a := range attrs {
+		fmt.Fprintf(&b, ` %s="%s"`, a.Key, html.EscapeString(a.Val))
+	}
+	if selfClosing {
+		b.WriteString(" /")
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// Summary 问题列表的简短描述，例如 "<script>, <div onclick> ×2"
+func Summary(issues []Issue) string {
+	items := make([]string, len(issues))
+	for i, issue := range issues {
+		items[i] = issue.String()
+	}
+	return strings.Join(items, ", ")
+}
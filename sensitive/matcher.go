@@ -0,0 +1,124 @@
+package sensitive
+
+import (
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Matcher Aho-Corasick 多模式匹配，一次扫描即可找出所有词，英文不区分大小写
+type Matcher struct {
+	nodes []acNode
+	words []Word
+}
+
+type acNode struct {
+	next   map[rune]int
+	fail   int
+	output []int // 以该节点结尾的词 (含沿失败链可达的词)
+}
+
+// Hit 一处命中，Start、End 为文本中的字节偏移
+type Hit struct {
+	Start, End int
+	Word       *Word
+}
+
+// NewMatcher 由词表构建匹配器，空词忽略
+func NewMatcher(words []Word) *Matcher {
+	m := &Matcher{nodes: []acNode{{}}, words: words}
+	for i, w := range words {
+		cur := 0
+		for _, r := range w.Text {
+			r = unicode.ToLower(r)
+			child, ok := m.nodes[cur].next[r]
+			if !ok {
+				if m.nodes[cur].next == nil {
+					m.nodes[cur].next = make(map[rune]int)
+				}
+				child = len(m.nodes)
+				m.nodes[cur].next[r] = child
+				m.nodes = append(m.nodes, acNode{})
+			}
+			cur = child
+		}
+		if cur != 0 {
+			m.nodes[cur].output = append(m.nodes[cur].output, i)
+		}
+	}
+
+	// 按层次遍历建立失败指针
+	queue := make([]int, 0, len(m.nodes))
+	for _, child := range m.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[cur].next {
+			fail := m.nodes[cur].fail
+			for fail != 0 && !m.has(fail, r) {
+				fail = m.nodes[fail].fail
+			}
+			if f, ok := m.nodes[fail].next[r]; ok && f != child {
+				m.nodes[child].fail = f
+			}
+			m.nodes[child].output = append(m.nodes[child].output, m.nodes[m.nodes[child].fail].output...)
+			queue = append(queue, child)
+		}
+	}
+	return m
+}
+
+func (m *Matcher) has(node int, r rune) bool {
+	_, ok := m.nodes[node].next[r]
+	return ok
+}
+
+// Empty 词表是否为空
+func (m *Matcher) Empty() bool {
+	return m == nil || len(m.nodes) == 1
+}
+
+// Find 查找文本中的词，重叠时取最靠左、最长的一个
+func (m *Matcher) Find(text string) []Hit {
+	if m.Empty() {
+		return nil
+	}
+	var hits []Hit
+	var starts []int // 已扫描字符的起始偏移
+	cur := 0
+	for i, r := range text {
+		starts = append(starts, i)
+		lower := unicode.ToLower(r)
+		for cur != 0 && !m.has(cur, lower) {
+			cur = m.nodes[cur].fail
+		}
+		cur = m.nodes[cur].next[lower] // 根节点没有对应的边时回到根节点
+		for _, w := range m.nodes[cur].output {
+			n := utf8.RuneCountInString(m.words[w].Text)
+			hits = append(hits, Hit{Start: starts[len(starts)-n], End: i + utf8.RuneLen(r), Word: &m.words[w]})
+		}
+	}
+	return leftmostLongest(hits)
+}
+
+// leftmostLongest 去掉重叠的命中，优先保留起点靠前、较长的词
+func leftmostLongest(hits []Hit) []Hit {
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Start != hits[j].Start {
+			return hits[i].Start < hits[j].Start
+		}
+		return hits[i].End > hits[j].End
+	})
+	var kept []Hit
+	end := 0
+	for _, h := range hits {
+		if h.Start < end {
+			continue
+		}
+		kept = append(kept, h)
+		end = h.End
+	}
+	return kept
+}
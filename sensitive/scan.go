@@ -0,0 +1,270 @@
+package sensitive
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+	xhtml "golang.org/x/net/html"
+
+	"github.com/hankmor/mymedia/tools/wechat-preview/render"
+)
+
+// AllowKey 单篇文章放行词语的 Frontmatter 字段
+const AllowKey = "sensitive_allow"
+
+// scannedFields 参与扫描的 Frontmatter 字段
+var scannedFields = map[string]bool{"title": true, "description": true, "summary": true}
+
+// Match 一处命中
+type Match struct {
+	Word     string   `json:"word"` // 原文中的写法
+	Category string   `json:"category"`
+	Severity Severity `json:"severity"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Context  string   `json:"context"` // 命中位置前后的文字
+}
+
+// Scanner 敏感词扫描器
+type Scanner struct {
+	matcher *Matcher
+}
+
+// NewScanner 由词表创建扫描器
+func NewScanner(words []Word) *Scanner {
+	return &Scanner{matcher: NewMatcher(words)}
+}
+
+// Empty 是否没有配置词表
+func (s *Scanner) Empty() bool {
+	return s == nil || s.matcher.Empty()
+}
+
+// find 查找命中并过滤放行的词
+func (s *Scanner) find(text string, allow map[string]bool) []Hit {
+	hits := s.matcher.Find(text)
+	kept := hits[:0]
+	for _, h := range hits {
+		if !allow[strings.ToLower(h.Word.Text)] {
+			kept = append(kept, h)
+		}
+	}
+	return kept
+}
+
+// mdParser 扫描使用的解析器，只需区分文本、代码与链接等语法
+var mdParser = goldmark.New(goldmark.WithExtensions(extension.GFM, extension.Footnote)).Parser()
+
+// Scan 扫描文章源文件 (含 Frontmatter)，按出现顺序返回命中
+// 扫描范围为标题、摘要等 Frontmatter 字段，正文文本节点、代码块与 HTML 块；链接地址不扫描。
+func (s *Scanner) Scan(source string) []Match {
+	if s.Empty() {
+		return nil
+	}
+	front, body := render.SplitFrontmatter(source)
+	allow := Allowlist(front)
+
+	var matches []Match
+	collect := func(u *unit) {
+		for _, h := range s.find(string(u.text), allow) {
+			offset, end := u.offsets[h.Start], u.offsets[h.End-1]+1 // 跨越强调等标记时 end 包含标记
+			line, col := position(source, offset)
+			matches = append(matches, Match{
+				Word:     string(u.text[h.Start:h.End]),
+				Category: h.Word.Category,
+				Severity: h.Word.Severity,
+				Line:     line,
+				Column:   col,
+				Context:  context(source, offset, end),
+			})
+		}
+	}
+
+	// Frontmatter 中的标题与摘要
+	offset := 0
+	for _, line := range strings.SplitAfter(front, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if ok && scannedFields[strings.TrimSpace(key)] && !strings.HasPrefix(line, " ") {
+			u := &unit{}
+			u.add(source, offset+len(key)+1, offset+len(key)+1+len(value))
+			collect(u)
+		}
+		offset += len(line)
+	}
+
+	// 正文按块拼接文本，强调等标记分隔开的词同样能命中
+	src := []byte(body)
+	doc := mdParser.Parse(text.NewReader(src))
+	var units []*unit
+	blocks := make(map[ast.Node]*unit)
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Text:
+			block := n.Parent()
+			for block.Type() != ast.TypeBlock {
+				block = block.Parent()
+			}
+			u, ok := blocks[block]
+			if !ok {
+				u = &unit{}
+				blocks[block] = u
+				units = append(units, u)
+			}
+			u.add(source, len(front)+n.Segment.Start, len(front)+n.Segment.Stop)
+		case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock:
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				seg := lines.At(i)
+				u := &unit{}
+				u.add(source, len(front)+seg.Start, len(front)+seg.Stop)
+				units = append(units, u)
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, u := range units {
+		collect(u)
+	}
+	return matches
+}
+
+// unit 一段连续扫描的文本，记录每个字节在源文件中的偏移
+type unit struct {
+	text    []byte
+	offsets []int
+}
+
+func (u *unit) add(source string, start, stop int) {
+	u.text = append(u.text, source[start:stop]...)
+	for i := start; i < stop; i++ {
+		u.offsets = append(u.offsets, i)
+	}
+}
+
+// position 字节偏移对应的行列，从 1 开始，列按字符计
+func position(s string, offset int) (line, col int) {
+	lineStart := strings.LastIndex(s[:offset], "\n") + 1
+	return strings.Count(s[:offset], "\n") + 1, utf8.RuneCountInString(s[lineStart:offset]) + 1
+}
+
+// context 命中位置所在行前后各若干个字符
+func context(s string, start, end int) string {
+	const width = 12
+	lineStart := strings.LastIndex(s[:start], "\n") + 1
+	lineEnd := len(s)
+	if i := strings.IndexByte(s[end:], '\n'); i >= 0 {
+		lineEnd = end + i
+	}
+	before := []rune(s[lineStart:start])
+	after := []rune(s[end:lineEnd])
+	prefix, suffix := "", ""
+	if len(before) > width {
+		before, prefix = before[len(before)-width:], "…"
+	}
+	if len(after) > width {
+		after, suffix = after[:width], "…"
+	}
+	return strings.TrimSpace(prefix + string(before) + s[start:end] + string(after) + suffix)
+}
+
+// Allowlist 解析 Frontmatter 中放行的词语，支持 "a, b"、"[a, b]" 与 YAML 列表写法
+func Allowlist(front string) map[string]bool {
+	allow := make(map[string]bool)
+	add := func(s string) {
+		s = strings.Trim(strings.TrimSpace(s), `"'`)
+		if s != "" {
+			allow[strings.ToLower(s)] = true
+		}
+	}
+
+	lines := strings.Split(front, "\n")
+	for i, line := range lines {
+		value, ok := strings.CutPrefix(line, AllowKey+":")
+		if !ok {
+			continue
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+			for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '，' }) {
+				add(item)
+			}
+			continue
+		}
+		for _, item := range lines[i+1:] {
+			item = strings.TrimSpace(item)
+			v, ok := strings.CutPrefix(item, "- ")
+			if !ok {
+				break
+			}
+			add(v)
+		}
+	}
+	return allow
+}
+
+// severityLabels 预览高亮提示中的级别说明
+var severityLabels = map[Severity]string{Error: "禁止", Warning: "建议修改", Info: "提示"}
+
+// Highlight 预览时用 <span class="sensitive-word"> 标出命中的词 (渲染管线步骤)
+// 只改写文本，不跨标签匹配；复制与发布前由前端去掉标记，发布目标不做处理。
+func (s *Scanner) Highlight() render.Stage {
+	return render.Stage{Name: "sensitive-highlight", Phase: render.PhaseHTML, Run: func(doc *render.Document) error {
+		if doc.Target != render.Preview || s.Empty() {
+			return nil
+		}
+		front, _ := render.SplitFrontmatter(doc.Source)
+		doc.HTML = s.highlight(doc.HTML, Allowlist(front))
+		return nil
+	}}
+}
+
+func (s *Scanner) highlight(content string, allow map[string]bool) string {
+	var out bytes.Buffer
+	rawText := false // <script>、<style> 中的内容不是正文
+	z := xhtml.NewTokenizer(strings.NewReader(content))
+	for {
+		tt := z.Next()
+		if tt == xhtml.ErrorToken {
+			break
+		}
+		raw := z.Raw()
+		if tt == xhtml.StartTagToken || tt == xhtml.EndTagToken {
+			name, _ := z.TagName()
+			if tag := string(name); tag == "script" || tag == "style" {
+				rawText = tt == xhtml.StartTagToken
+			}
+		}
+		if tt != xhtml.TextToken || rawText {
+			out.Write(raw)
+			continue
+		}
+		text := html.UnescapeString(string(raw))
+		hits := s.find(text, allow)
+		if len(hits) == 0 {
+			out.Write(raw)
+			continue
+		}
+		last := 0
+		for _, h := range hits {
+			out.WriteString(html.EscapeString(text[last:h.Start]))
+			fmt.Fprintf(&out, `<span class="sensitive-word sensitive-%s" title="%s">%s</span>`,
+				h.Word.Severity,
+				html.EscapeString(fmt.Sprintf("敏感词 · %s · %s", h.Word.Category, severityLabels[h.Word.Severity])),
+				html.EscapeString(text[h.Start:h.End]))
+			last = h.End
+		}
+		out.WriteString(html.EscapeString(text[last:]))
+	}
+	return out.String()
+}
@@ -0,0 +1,116 @@
+// Package sensitive 敏感词与违禁词扫描
+// 微信会对含敏感词的文章静默限流或拦截，团队维护的词表 (可从表格导出为 CSV) 按类别与级别分级。
+// 扫描使用 Aho-Corasick 多模式匹配，覆盖正文文本节点、代码块以及标题、摘要等 Frontmatter 字段；
+// 单篇文章可在 Frontmatter 的 sensitive_allow 中放行特定词语。
+package sensitive
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Severity 命中级别
+type Severity string
+
+const (
+	Error   Severity = "error"   // 必须修改
+	Warning Severity = "warning" // 建议修改
+	Info    Severity = "info"    // 仅提示
+)
+
+// severityAliases 词表中级别的写法，表格中常用中文或 high/medium/low
+var severityAliases = map[string]Severity{
+	"error": Error, "high": Error, "高": Error, "禁止": Error,
+	"warning": Warning, "warn": Warning, "medium": Warning, "中": Warning,
+	"info": Info, "low": Info, "低": Info, "提示": Info,
+}
+
+// Word 词表中的一个词
+type Word struct {
+	Text     string   `json:"text"`
+	Category string   `json:"category"`
+	Severity Severity `json:"severity"`
+}
+
+// ParseSeverity 解析级别，空值或未知写法视为 warning
+func ParseSeverity(s string) Severity {
+	if v, ok := severityAliases[strings.ToLower(strings.TrimSpace(s))]; ok {
+		return v
+	}
+	return Warning
+}
+
+// LoadFiles 读取多个词表文件
+func LoadFiles(paths ...string) ([]Word, error) {
+	var words []Word
+	for _, p := range paths {
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, err
+		}
+		list, err := Load(f, p)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		words = append(words, list...)
+	}
+	return words, nil
+}
+
+// Load 读取词表，name 为文件名，决定格式并作为默认类别
+//
+// .csv 每行为 "词,类别,级别"，类别与级别可省略，首行为 word/词 开头的表头时跳过；
+// 其它文件每行一个词，# 开头的行为注释。
+func Load(r io.Reader, name string) ([]Word, error) {
+	category := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	if strings.EqualFold(filepath.Ext(name), ".csv") {
+		return loadCSV(r, category)
+	}
+
+	var words []Word
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, Word{Text: line, Category: category, Severity: Warning})
+	}
+	return words, scanner.Err()
+}
+
+func loadCSV(r io.Reader, category string) ([]Word, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var words []Word
+	for i, record := range records {
+		field := func(n int) string {
+			if n < len(record) {
+				return strings.TrimSpace(record[n])
+			}
+			return ""
+		}
+		text := strings.TrimPrefix(field(0), "\ufeff") // Excel 导出的 CSV 带 BOM
+		if text == "" || (i == 0 && (strings.EqualFold(text, "word") || text == "词" || text == "敏感词")) {
+			continue
+		}
+		w := Word{Text: text, Category: field(1), Severity: ParseSeverity(field(2))}
+		if w.Category == "" {
+			w.Category = category
+		}
+		words = append(words, w)
+	}
+	return words, nil
+}
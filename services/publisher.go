@@ -3,12 +3,15 @@ package services
 import (
 	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
 
 	"github.com/hankmor/mymedia/tools/wechat-preview/config"
+	"github.com/hankmor/mymedia/tools/wechat-preview/markdown"
 )
 
 // PublishResult 发布结果
@@ -17,104 +20,116 @@ type PublishResult struct {
 	PublishContent  string
 	UploadedImages  []string
 	Errors          []string
+	Images          map[string]string // Markdown 中书写的本地图片路径 -> CDN 地址
+	Covers          map[string]string // 生成的封面：尺寸名称 (wide、square) -> CDN 地址
+	CoverError      string            // 封面生成或上传失败的原因，不影响正文发布
 }
 
-// PublishArticle 处理文章发布逻辑
-// 1. 扫描 markdown 中的本地图片
-// 2. 上传到 GitHub
-// 3. 替换链接
-func PublishArticle(postPath string, projectRoot string) (*PublishResult, error) {
-	contentBytes, err := os.ReadFile(postPath)
-	if err != nil {
-		return nil, err
-	}
-	content := string(contentBytes)
-
-	// 正则匹配图片: !\[.*\]\((.*)\)
-	re := regexp.MustCompile(`!\[(.*?)\]\((.*?)\)`)
-	matches := re.FindAllStringSubmatch(content, -1)
-
-	log.Printf("Debug: Scanning article %s, found %d matches\n", postPath, len(matches))
-
+// PublishImages 上传渲染结果中的本地图片与渲染期生成的图片 (公式等)，并替换为 CDN 链接
+// 本地图片相对文章所在目录解析，远程路径为相对项目根目录的路径；生成图片上传到 generated 目录。
+// 上传结果与错误追加到 result 中，result.PublishContent 为替换链接后的原始 Markdown。
+func PublishImages(htmlContent, postPath, projectRoot string, store *markdown.AssetStore, result *PublishResult) string {
+	re := regexp.MustCompile(`\bsrc=["']([^"']+)["']`)
 	uploader := &GitHubUploader{}
-	result := &PublishResult{
-		OriginalContent: content,
-	}
 
 	// 替换映射表 (Local -> Remote)
 	urlMap := make(map[string]string)
-
-	// 去重处理
-	uniquePaths := make(map[string]bool)
-
-	for _, match := range matches {
-		if len(match) < 3 {
+	// Markdown 中书写的本地路径 -> Remote
+	sourceMap := make(map[string]string)
+	// 同一文件可能以编码与未编码两种地址出现，按文件去重 (失败时记为空)
+	uploaded := make(map[string]string)
+	mdDir := filepath.Dir(postPath)
+
+	for _, match := range re.FindAllStringSubmatch(htmlContent, -1) {
+		src := match[1]
+		if _, done := urlMap[src]; done {
 			continue
 		}
-		imgURL := match[2]
-
-		log.Printf("Debug: Found image link: %s\n", imgURL)
 
 		// 忽略网络图片
-		if strings.HasPrefix(imgURL, "http") {
-			log.Printf("Debug: Skipping remote image: %s\n", imgURL)
+		if strings.HasPrefix(src, "http") || strings.HasPrefix(src, "//") || strings.HasPrefix(src, "data:") {
 			continue
 		}
 
-		// 处理带 title 的情况
-		parts := strings.Split(imgURL, " ")
-		cleanPath := parts[0]
-
-		// 解析本地绝对路径
-		mdDir := filepath.Dir(postPath)
-		absPath := filepath.Join(mdDir, cleanPath)
-
-		log.Printf("Debug: Resolving local path. MD Dir: %s, Rel: %s -> Abs: %s\n", mdDir, cleanPath, absPath)
+		var absPath, remotePath, label string
+		if name, ok := store.NameFromURL(src); ok {
+			absPath = store.Path(name)
+			remotePath = path.Join("generated", name)
+			label = name
+		} else {
+			// Markdown 渲染时会对中文、空格等字符做 URL 编码
+			cleanPath := src
+			if decoded, err := url.PathUnescape(src); err == nil {
+				cleanPath = decoded
+			}
+			absPath = filepath.Join(mdDir, cleanPath)
+			label = cleanPath
+
+			log.Printf("Debug: Resolving local path. MD Dir: %s, Rel: %s -> Abs: %s\n", mdDir, cleanPath, absPath)
+
+			// 确保文件存在
+			if _, err := os.Stat(absPath); os.IsNotExist(err) {
+				result.Errors = append(result.Errors, fmt.Sprintf("Image not found: %s", cleanPath))
+				urlMap[src] = src
+				continue
+			}
+
+			// 远程路径为相对项目根目录的路径 (e.g. 02-openclaw/images/foo.png)
+			rel, _ := filepath.Rel(projectRoot, absPath)
+			remotePath = filepath.ToSlash(rel)
+		}
 
-		// 确保文件存在
-		if _, err := os.Stat(absPath); os.IsNotExist(err) {
-			log.Printf("Debug: File not found at %s\n", absPath)
-			result.Errors = append(result.Errors, fmt.Sprintf("Image not found: %s", cleanPath))
-			continue
+		// 如果配置了 GitHubPathPrefix，直接拼接在最前面
+		if config.AppConfig.GitHubPathPrefix != "" {
+			remotePath = path.Join(config.AppConfig.GitHubPathPrefix, remotePath)
 		}
 
-		if uniquePaths[cleanPath] {
-			continue
+		cdnURL, done := uploaded[absPath]
+		if !done {
+			var err error
+			cdnURL, err = uploader.Upload(absPath, remotePath)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("Upload failed for %s: %v", label, err))
+			} else {
+				result.UploadedImages = append(result.UploadedImages, cdnURL)
+			}
+			uploaded[absPath] = cdnURL
 		}
-		uniquePaths[cleanPath] = true
-
-		// 构造远程路径
-		// 由于 rootPath 就是 postsDir，所以 remotePath 就是相对于 posts 目录的路径 (e.g. 02-openclaw/images/foo.png)
-		remotePath, _ := filepath.Rel(projectRoot, absPath)
-        
-        // 如果配置了 GitHubPathPrefix，直接拼接在最前面
-        // e.g. <prefix>/02-openclaw/...
-        if config.AppConfig.GitHubPathPrefix != "" {
-            remotePath = filepath.Join(config.AppConfig.GitHubPathPrefix, remotePath)
-        }
-
-		// 同步上传
-		cdnURL, err := uploader.Upload(absPath, remotePath)
-
-		if err != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("Upload failed for %s: %v", cleanPath, err))
-		} else {
-			urlMap[cleanPath] = cdnURL
-			result.UploadedImages = append(result.UploadedImages, cdnURL)
+		if cdnURL == "" {
+			urlMap[src] = src
+			continue
 		}
+		urlMap[src] = cdnURL
+		sourceMap[label] = cdnURL
 	}
 
-	// 替换内容
-	newContent := content
 	for local, remote := range urlMap {
-		// 简单替换可能误伤，但既然我们是用正则提取出来的，应该问题不大
-		// 更严谨的做法是按 index 替换
+		htmlContent = strings.ReplaceAll(htmlContent, `src="`+local+`"`, `src="`+remote+`"`)
+		htmlContent = strings.ReplaceAll(htmlContent, `src='`+local+`'`, `src='`+remote+`'`)
+	}
+
+	// 同步替换 Markdown 中的图片链接，供复制 Markdown 使用
+	result.Images = sourceMap
+	result.PublishContent = ReplaceImages(result.OriginalContent, sourceMap)
+	return htmlContent
+}
 
-		// 注意：如果要替换 `(./images/foo.png)` 为 `(https://...)`
-		// 简单的 ReplaceAll 足够应对绝大多数情况
-		newContent = strings.ReplaceAll(newContent, "("+local, "("+remote)
+// PublishGenerated 上传一个不在正文中的生成图片 (例如封面) 到 generated 目录，返回 CDN 地址
+func PublishGenerated(store *markdown.AssetStore, name string) (string, error) {
+	remotePath := path.Join("generated", name)
+	if config.AppConfig.GitHubPathPrefix != "" {
+		remotePath = path.Join(config.AppConfig.GitHubPathPrefix, remotePath)
 	}
+	return (&GitHubUploader{}).Upload(store.Path(name), remotePath)
+}
 
-	result.PublishContent = newContent
-	return result, nil
+// ReplaceImages 将 Markdown 中的本地图片路径 (包括短代码、原始 HTML 中的 src 属性) 替换为 CDN 地址
+func ReplaceImages(content string, images map[string]string) string {
+	for local, remote := range images {
+		content = strings.ReplaceAll(content, "(<"+local+">", "("+remote) // 含空格的路径写在尖括号中
+		content = strings.ReplaceAll(content, "("+local, "("+remote)
+		content = strings.ReplaceAll(content, `src="`+local+`"`, `src="`+remote+`"`)
+		content = strings.ReplaceAll(content, `src='`+local+`'`, `src='`+remote+`'`)
+	}
+	return content
 }
@@ -0,0 +1,166 @@
+package shortcode
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/hankmor/mymedia/tools/wechat-preview/markdown"
+)
+
+// registerBuiltins 注册内置短代码
+// 微信不支持 iframe、外部脚本和外链跳转，嵌入类短代码统一降级为带链接文字的卡片
+func registerBuiltins(e *Engine) {
+	e.Register("figure", figure)
+	e.Register("highlight", highlight)
+	e.Register("gist", gist)
+	e.Register("youtube", youtube)
+	e.Register("notice", notice)
+	e.Register("admonition", admonition)
+	e.RegisterMarkdown("toc", toc)
+}
+
+// figure 图片与说明文字，输出与 Markdown 独立图片相同的 figure 结构
+// {{< figure src="images/a.png" title="标题" caption="说明" alt="..." width="60%" link="https://..." >}}
+func figure(c *Context) (string, error) {
+	src := c.Get("src")
+	if src == "" {
+		return "", fmt.Errorf("figure 缺少 src 参数")
+	}
+	alt := c.Get("alt")
+	if alt == "" {
+		alt = c.Get("title")
+	}
+
+	var caption strings.Builder
+	title, text := c.Get("title"), c.Get("caption")
+	if title != "" {
+		fmt.Fprintf(&caption, `<strong style="color: #666;">%s</strong>`, html.EscapeString(title))
+	}
+	if text != "" {
+		rendered, err := c.Markdownify(text)
+		if err != nil {
+			return "", err
+		}
+		if title != "" {
+			caption.WriteString("<br />")
+		}
+		caption.WriteString(rendered)
+	}
+
+	return markdown.FigureHTML(markdown.FigureImage{
+		Src:     src,
+		Alt:     alt,
+		Caption: caption.String(),
+		Width:   c.Get("width"),
+		Height:  c.Get("height"),
+		Link:    c.Get("link"),
+	}), nil
+}
+
+// highlight 代码高亮，转换为代码围栏交给代码块渲染器，与普通代码块样式一致
+// {{< highlight go "linenos=table,hl_lines=2 4-5" >}} ... {{< /highlight >}}
+func highlight(c *Context) (string, error) {
+	language := c.Get(0)
+	if language == "" {
+		language = c.Get("lang")
+	}
+
+	info := language
+	if options := c.Get(1); options != "" {
+		for _, option := range strings.Split(options, ",") {
+			key, value, ok := strings.Cut(strings.TrimSpace(option), "=")
+			if !ok {
+				continue
+			}
+			info += fmt.Sprintf(` %s="%s"`, key, value)
+		}
+	}
+
+	code := c.InnerDeindent()
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return c.engine.markdownifyString(fence + info + "\n" + code + "\n" + fence + "\n")
+}
+
+// toc {{< toc >}} 输出目录标记，由 Markdown 目录扩展根据标题生成目录
+func toc(c *Context) (string, error) {
+	return "[TOC]", nil
+}
+
+// gist {{< gist user id [file] >}}
+func gist(c *Context) (string, error) {
+	user, id := c.Get(0), c.Get(1)
+	if user == "" || id == "" {
+		return "", fmt.Errorf("gist 需要 user 与 id 两个参数")
+	}
+	url := fmt.Sprintf("https://gist.github.com/%s/%s", user, id)
+	title := "GitHub Gist: " + user + "/" + id
+	if file := c.Get(2); file != "" {
+		title += " (" + file + ")"
+	}
+	return embedCard("💻", title, url, ""), nil
+}
+
+// youtube {{< youtube id >}} 或 {{< youtube id="..." title="..." >}}
+// 微信无法嵌入 YouTube 播放器，输出封面图与视频地址
+func youtube(c *Context) (string, error) {
+	id := c.Get(0)
+	if id == "" {
+		id = c.Get("id")
+	}
+	if id == "" {
+		return "", fmt.Errorf("youtube 缺少视频 id")
+	}
+	title := c.Get("title")
+	if title == "" {
+		title = "YouTube 视频"
+	}
+	url := "https://www.youtube.com/watch?v=" + id
+	cover := fmt.Sprintf("https://i.ytimg.com/vi/%s/hqdefault.jpg", id)
+	return embedCard("▶️", title, url, cover), nil
+}
+
+// embedCard 嵌入内容的降级卡片，链接以文字形式展示，便于读者复制
+func embedCard(icon, title, url, cover string) string {
+	var b strings.Builder
+	b.WriteString(`<section class="embed-card" style="margin: 20px 0; padding: 12px 16px; border: 1px solid #e5e5e5; border-radius: 6px; background: #fafafa;">`)
+	if cover != "" {
+		fmt.Fprintf(&b, `<img src="%s" alt="%s" style="display: block; width: 100%%; margin: 0 0 10px; border-radius: 4px;" />`,
+			html.EscapeString(cover), html.EscapeString(title))
+	}
+	fmt.Fprintf(&b, `<p style="margin: 0; font-size: 15px; font-weight: bold; color: #333;">%s %s</p>`, icon, html.EscapeString(title))
+	fmt.Fprintf(&b, `<p style="margin: 4px 0 0; font-size: 13px; color: #888; word-break: break-all;">%s</p>`, html.EscapeString(url))
+	b.WriteString(`</section>`)
+	return b.String()
+}
+
+// notice {{< notice tip "可选标题" >}} 正文 {{< /notice >}}
+func notice(c *Context) (string, error) {
+	kind, title := c.Get(0), c.Get(1)
+	if c.IsNamedParams() {
+		kind, title = c.Get("type"), c.Get("title")
+	}
+	return noticeBox(c, kind, title)
+}
+
+// admonition {{< admonition type=tip title="标题" open=true >}} 正文 {{< /admonition >}}
+// 参数与 notice 相同；open 仅影响网页折叠，微信中始终展开
+func admonition(c *Context) (string, error) {
+	return notice(c)
+}
+
+// noticeBox 与 Markdown 中的 > [!TIP] 提示框共用样式，配色来自主题
+func noticeBox(c *Context, kind, title string) (string, error) {
+	// 正文按块级内容渲染，单段落也保留 <p>，与多段落时的间距一致
+	body := html.EscapeString(c.InnerDeindent())
+	if c.engine.markdownify != nil {
+		var err error
+		if body, err = c.engine.markdownify(c.InnerDeindent()); err != nil {
+			return "", err
+		}
+	}
+	return markdown.CalloutOpen(c.engine.theme, kind, title) + body + markdown.CalloutClose, nil
+}
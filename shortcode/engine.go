@@ -0,0 +1,321 @@
+// Package shortcode Hugo 短代码引擎
+// 支持 {{< >}} 与 {{% %}} 两种定界符、成对与单标签写法、命名与位置参数，
+// 内置面向微信排版的 figure、highlight、gist、youtube、notice、admonition 等实现，
+// 并可加载项目 layouts/shortcodes 目录下的 Go 模板作为自定义短代码。
+package shortcode
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hankmor/mymedia/tools/wechat-preview/markdown"
+	"github.com/hankmor/mymedia/tools/wechat-preview/theme"
+)
+
+// Func 短代码实现，返回 HTML (或 {{% %}} 形式下的 Markdown)
+type Func func(c *Context) (string, error)
+
+// Page 短代码所在的文章
+type Page struct {
+	Title  string
+	Path   string          // 文章文件路径
+	Target markdown.Target // 渲染目标，例如 relref 在预览时指向本地页面、发布时指向线上地址
+}
+
+// Engine 短代码引擎
+type Engine struct {
+	funcs       map[string]Func
+	markdown    map[string]bool // 输出始终写入 Markdown 的短代码
+	templates   map[string]*template.Template
+	markdownify func(string) (string, error)
+	theme       *theme.Theme
+}
+
+// New 创建短代码引擎并注册内置短代码
+// markdownify 用于将短代码内容渲染为 HTML (例如 notice 的正文)，t 为提示框等内置短代码使用的主题
+func New(markdownify func(string) (string, error), t *theme.Theme) *Engine {
+	e := &Engine{
+		funcs:       make(map[string]Func),
+		markdown:    make(map[string]bool),
+		templates:   make(map[string]*template.Template),
+		markdownify: markdownify,
+		theme:       t,
+	}
+	registerBuiltins(e)
+	return e
+}
+
+// Register 注册短代码，同名时覆盖已有实现
+func (e *Engine) Register(name string, fn Func) {
+	e.funcs[name] = fn
+	delete(e.markdown, name)
+}
+
+// RegisterMarkdown 注册输出 Markdown 的短代码，无论使用哪种定界符，输出都直接写入 Markdown
+// 用于需要交给 Markdown 扩展继续处理的标记，例如 toc 输出的 [TOC]
+func (e *Engine) RegisterMarkdown(name string, fn Func) {
+	e.funcs[name] = fn
+	e.markdown[name] = true
+}
+
+// LoadTemplates 加载目录下的 Go 模板作为自定义短代码
+// 文件名 (不含扩展名) 即短代码名，子目录中的模板以 "dir/name" 引用；
+// 与内置短代码同名时优先使用模板，与 Hugo 的覆盖规则一致。目录不存在时忽略。
+func (e *Engine) LoadTemplates(dir string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".html" {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		name := strings.TrimSuffix(filepath.ToSlash(rel), ".html")
+
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		tmpl, err := template.New(name).Funcs(e.funcMap()).Parse(string(src))
+		if err != nil {
+			// 单个模板出错不影响其他短代码
+			log.Printf("⚠️  Warning: skip shortcode template %s: %v", rel, err)
+			return nil
+		}
+		e.templates[name] = tmpl
+		return nil
+	})
+}
+
+// Result 短代码处理结果
+// {{< >}} 的输出是最终 HTML，不能交给 Markdown 解析器，因此先用占位符代替，
+// 渲染出 HTML 后再调用 Restore 还原；{{% %}} 的输出直接写入 Markdown。
+type Result struct {
+	Markdown string
+	outputs  []string
+}
+
+// Process 处理内容中的所有短代码
+func (e *Engine) Process(content string, page Page) *Result {
+	r := &Result{}
+	r.Markdown = e.render(parse(content), nil, page, r)
+	return r
+}
+
+// Restore 将 HTML 中的占位符替换为短代码输出
+func (r *Result) Restore(htmlContent string) string {
+	// 外层短代码的输出可能包含内层的占位符，按生成顺序倒序替换
+	for i := len(r.outputs) - 1; i >= 0; i-- {
+		token := placeholder(i)
+		// 独占一段的占位符会被包裹在 <p> 中，块级输出需要去掉这层段落
+		htmlContent = strings.ReplaceAll(htmlContent, "<p>"+token+"</p>", r.outputs[i])
+		htmlContent = strings.ReplaceAll(htmlContent, token, r.outputs[i])
+	}
+	return htmlContent
+}
+
+// placeholder 占位符只包含字母数字和连字符，不会被 Markdown 转义或改写
+func placeholder(i int) string {
+	return fmt.Sprintf("WPSHORTCODE-%d-WPSHORTCODE", i)
+}
+
+// render 依次渲染文本与短代码
+func (e *Engine) render(items []item, parent *Context, page Page, r *Result) string {
+	var b strings.Builder
+	ordinal := 0
+	for _, it := range items {
+		if it.sc == nil {
+			b.WriteString(it.text)
+			continue
+		}
+		c := &Context{
+			Name:       it.sc.name,
+			Ordinal:    ordinal,
+			Parent:     parent,
+			Page:       page,
+			positional: it.sc.positional,
+			named:      it.sc.named,
+			markdown:   it.sc.markdown,
+			engine:     e,
+		}
+		ordinal++
+		if it.sc.paired {
+			c.inner = e.render(it.sc.inner, c, page, r)
+		}
+
+		output, err := e.call(c)
+		if err != nil {
+			output = errorHTML(it.sc.raw, err)
+		}
+		if (it.sc.markdown || e.markdownOutput(c.Name)) && err == nil {
+			b.WriteString(output)
+			continue
+		}
+		b.WriteString(placeholder(len(r.outputs)))
+		r.outputs = append(r.outputs, output)
+	}
+	return b.String()
+}
+
+// markdownOutput 短代码输出是否直接写入 Markdown (同名模板优先，不受影响)
+func (e *Engine) markdownOutput(name string) bool {
+	_, overridden := e.templates[name]
+	return e.markdown[name] && !overridden
+}
+
+// call 执行短代码，模板优先于注册的实现
+func (e *Engine) call(c *Context) (string, error) {
+	if tmpl, ok := e.templates[c.Name]; ok {
+		var b strings.Builder
+		if err := tmpl.Execute(&b, c); err != nil {
+			return "", err
+		}
+		// 模板文件末尾的换行会被 Markdown 当作硬换行，去掉首尾空白
+		return strings.TrimSpace(b.String()), nil
+	}
+	if fn, ok := e.funcs[c.Name]; ok {
+		return fn(c)
+	}
+	return "", fmt.Errorf("未知短代码 %s", c.Name)
+}
+
+// errorHTML 短代码执行失败时的提示，保留原标签便于定位
+func errorHTML(raw string, err error) string {
+	return fmt.Sprintf(`<span class="shortcode-error" style="color: #c0392b; background: #fdecea; font-size: 14px;">短代码错误: %s <code>%s</code></span>`,
+		html.EscapeString(err.Error()), html.EscapeString(raw))
+}
+
+// Context 短代码执行上下文，方法命名与 Hugo 模板中的 .Get / .Inner 等保持一致
+type Context struct {
+	Name    string
+	Ordinal int      // 在同级短代码中的序号，从 0 开始
+	Parent  *Context // 外层短代码，顶层为 nil
+	Page    Page
+
+	positional []string
+	named      map[string]string
+	inner      string
+	markdown   bool
+	engine     *Engine
+}
+
+// Get 按位置 (int) 或名称 (string) 获取参数，不存在时返回空字符串
+func (c *Context) Get(key any) string {
+	switch k := key.(type) {
+	case int:
+		if k >= 0 && k < len(c.positional) {
+			return c.positional[k]
+		}
+	case string:
+		return c.named[k]
+	}
+	return ""
+}
+
+// Default 获取命名参数，不存在时返回 def
+func (c *Context) Default(key, def string) string {
+	if v, ok := c.named[key]; ok {
+		return v
+	}
+	return def
+}
+
+// Params 所有参数：命名参数返回 map，位置参数返回 slice
+func (c *Context) Params() any {
+	if c.IsNamedParams() {
+		return c.named
+	}
+	return c.positional
+}
+
+// IsNamedParams 是否使用命名参数
+func (c *Context) IsNamedParams() bool {
+	return len(c.named) > 0
+}
+
+// Inner 成对短代码之间的内容 (内层短代码已展开)
+func (c *Context) Inner() template.HTML {
+	return template.HTML(c.inner)
+}
+
+// InnerDeindent 去掉首尾空行后的内容
+func (c *Context) InnerDeindent() string {
+	return strings.Trim(c.inner, "\r\n")
+}
+
+// IsMarkdown 是否为 {{% %}} 形式调用
+func (c *Context) IsMarkdown() bool {
+	return c.markdown
+}
+
+// Markdownify 将 Markdown 渲染为 HTML，单个段落时去掉外层 <p>
+func (c *Context) Markdownify(s string) (string, error) {
+	return c.engine.markdownifyString(s)
+}
+
+func (e *Engine) markdownifyString(s string) (string, error) {
+	if e.markdownify == nil {
+		return html.EscapeString(s), nil
+	}
+	out, err := e.markdownify(s)
+	if err != nil {
+		return "", err
+	}
+	out = strings.TrimSpace(out)
+	if strings.HasPrefix(out, "<p>") && strings.HasSuffix(out, "</p>") && strings.Count(out, "<p>") == 1 {
+		out = strings.TrimSuffix(strings.TrimPrefix(out, "<p>"), "</p>")
+	}
+	return out, nil
+}
+
+// funcMap 自定义短代码模板可用的函数，覆盖 Hugo 模板中最常用的部分
+func (e *Engine) funcMap() template.FuncMap {
+	return template.FuncMap{
+		"markdownify": func(s any) (template.HTML, error) {
+			out, err := e.markdownifyString(fmt.Sprint(s))
+			return template.HTML(out), err
+		},
+		"safeHTML":     func(s any) template.HTML { return template.HTML(fmt.Sprint(s)) },
+		"safeHTMLAttr": func(s any) template.HTMLAttr { return template.HTMLAttr(fmt.Sprint(s)) },
+		"safeCSS":      func(s any) template.CSS { return template.CSS(fmt.Sprint(s)) },
+		"safeURL":      func(s any) template.URL { return template.URL(fmt.Sprint(s)) },
+		"relURL":       func(s any) string { return fmt.Sprint(s) },
+		"absURL":       func(s any) string { return fmt.Sprint(s) },
+		"default": func(def, v any) any {
+			if v == nil || fmt.Sprint(v) == "" {
+				return def
+			}
+			return v
+		},
+		"lower":     func(s any) string { return strings.ToLower(fmt.Sprint(s)) },
+		"upper":     func(s any) string { return strings.ToUpper(fmt.Sprint(s)) },
+		"trim":      func(s any, cutset string) string { return strings.Trim(fmt.Sprint(s), cutset) },
+		"replace":   func(s any, old, new string) string { return strings.ReplaceAll(fmt.Sprint(s), old, new) },
+		"split":     func(s any, sep string) []string { return strings.Split(fmt.Sprint(s), sep) },
+		"in":        func(s any, sub string) bool { return strings.Contains(fmt.Sprint(s), sub) },
+		"hasPrefix": func(s any, prefix string) bool { return strings.HasPrefix(fmt.Sprint(s), prefix) },
+		"ref":       e.refFunc("ref"),
+		"relref":    e.refFunc("relref"),
+	}
+}
+
+// refFunc 在模板中调用已注册的 ref / relref 实现
+func (e *Engine) refFunc(name string) func(page any, path string) (string, error) {
+	return func(page any, path string) (string, error) {
+		fn, ok := e.funcs[name]
+		if !ok {
+			return path, nil
+		}
+		c := &Context{Name: name, positional: []string{path}, named: map[string]string{}, engine: e}
+		if p, ok := page.(*Context); ok {
+			c.Page = p.Page
+		}
+		return fn(c)
+	}
+}
@@ -0,0 +1,307 @@
+package shortcode
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// call 一次短代码调用
+type call struct {
+	name       string
+	markdown   bool // {{% %}} 形式，输出按 Markdown 处理
+	positional []string
+	named      map[string]string
+	paired     bool // 存在对应的结束标签
+	inner      []item
+	raw        string // 开始标签原文，用于错误提示
+}
+
+// item 解析结果中的一段：普通文本或一次短代码调用
+type item struct {
+	text string
+	sc   *call
+}
+
+// tag 扫描得到的单个标签
+type tag struct {
+	name       string
+	markdown   bool
+	closing    bool // {{< /name >}}
+	selfClosed bool // {{< name />}}
+	positional []string
+	named      map[string]string
+	raw        string
+}
+
+// parse 将内容解析为文本与短代码调用组成的树
+// 开始标签后存在同名结束标签时视为成对短代码，否则视为单标签短代码
+func parse(content string) []item {
+	type frame struct {
+		sc    *call
+		items []item
+	}
+	stack := []*frame{{}}
+	top := func() *frame { return stack[len(stack)-1] }
+
+	// collapse 将栈顶未闭合的短代码降级为单标签，其后内容归还给上一层
+	collapse := func() {
+		f := top()
+		stack = stack[:len(stack)-1]
+		parent := top()
+		parent.items = append(parent.items, item{sc: f.sc})
+		parent.items = append(parent.items, f.items...)
+	}
+
+	for len(content) > 0 {
+		start := indexOpen(content)
+		if start < 0 {
+			top().items = append(top().items, item{text: content})
+			break
+		}
+		if start > 0 {
+			top().items = append(top().items, item{text: content[:start]})
+		}
+		content = content[start:]
+
+		// {{</* name */>}} 为转义写法，原样输出去掉注释标记后的标签
+		if literal, n, ok := scanEscaped(content); ok {
+			top().items = append(top().items, item{text: literal})
+			content = content[n:]
+			continue
+		}
+
+		t, n, err := scanTag(content)
+		if err != nil {
+			// 不是合法标签，按普通文本处理
+			top().items = append(top().items, item{text: content[:3]})
+			content = content[3:]
+			continue
+		}
+		content = content[n:]
+
+		switch {
+		case t.closing:
+			match := -1
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].sc.name == t.name {
+					match = i
+					break
+				}
+			}
+			if match < 0 {
+				top().items = append(top().items, item{text: t.raw})
+				continue
+			}
+			for len(stack)-1 > match {
+				collapse()
+			}
+			f := top()
+			stack = stack[:len(stack)-1]
+			f.sc.paired = true
+			f.sc.inner = f.items
+			top().items = append(top().items, item{sc: f.sc})
+		case t.selfClosed:
+			top().items = append(top().items, item{sc: t.call()})
+		default:
+			stack = append(stack, &frame{sc: t.call()})
+		}
+	}
+
+	for len(stack) > 1 {
+		collapse()
+	}
+	return stack[0].items
+}
+
+func (t *tag) call() *call {
+	return &call{
+		name:       t.name,
+		markdown:   t.markdown,
+		positional: t.positional,
+		named:      t.named,
+		raw:        t.raw,
+	}
+}
+
+// indexOpen 查找下一个 {{< 或 {{%
+func indexOpen(s string) int {
+	offset := 0
+	for {
+		i := strings.Index(s[offset:], "{{")
+		if i < 0 {
+			return -1
+		}
+		i += offset
+		if i+2 < len(s) && (s[i+2] == '<' || s[i+2] == '%') {
+			return i
+		}
+		offset = i + 2
+	}
+}
+
+// delimiters 返回标签的开始与结束定界符
+func delimiters(s string) (open, close string) {
+	if strings.HasPrefix(s, "{{%") {
+		return "{{%", "%}}"
+	}
+	return "{{<", ">}}"
+}
+
+// scanEscaped 识别 {{</* ... */>}} 写法，返回去掉注释标记后的文本
+func scanEscaped(s string) (string, int, bool) {
+	open, close := delimiters(s)
+	rest := strings.TrimLeft(s[len(open):], " \t")
+	if !strings.HasPrefix(rest, "/*") {
+		return "", 0, false
+	}
+	// 从 /* 之后查找，避免 /*/ 中的 * 被同时当作开始与结束标记
+	end := strings.Index(rest[2:], "*/")
+	if end < 0 {
+		return "", 0, false
+	}
+	end += 2
+	after := strings.TrimLeft(rest[end+2:], " \t")
+	if !strings.HasPrefix(after, close) {
+		return "", 0, false
+	}
+	n := len(s) - len(after) + len(close)
+	return open + rest[2:end] + close, n, true
+}
+
+// scanTag 解析一个完整的标签，返回标签与消耗的字节数
+func scanTag(s string) (*tag, int, error) {
+	open, close := delimiters(s)
+	t := &tag{markdown: open == "{{%", named: make(map[string]string)}
+	pos := len(open)
+
+	skipSpace := func() {
+		for pos < len(s) && isSpace(s[pos]) {
+			pos++
+		}
+	}
+
+	skipSpace()
+	if pos < len(s) && s[pos] == '/' {
+		t.closing = true
+		pos++
+		skipSpace()
+	}
+
+	nameStart := pos
+	for pos < len(s) && isNameChar(s[pos]) {
+		pos++
+	}
+	// name/>}} 的 / 属于自闭合标记
+	if pos > nameStart && s[pos-1] == '/' && strings.HasPrefix(s[pos:], close) {
+		pos--
+	}
+	t.name = s[nameStart:pos]
+	if t.name == "" {
+		return nil, 0, fmt.Errorf("missing shortcode name")
+	}
+
+	for {
+		skipSpace()
+		if pos >= len(s) {
+			return nil, 0, fmt.Errorf("unterminated shortcode %q", t.name)
+		}
+		if strings.HasPrefix(s[pos:], close) {
+			pos += len(close)
+			break
+		}
+		if strings.HasPrefix(s[pos:], "/"+close) {
+			t.selfClosed = true
+			pos += len(close) + 1
+			break
+		}
+		if t.closing {
+			return nil, 0, fmt.Errorf("closing shortcode %q takes no arguments", t.name)
+		}
+
+		value, n, err := scanValue(s[pos:], close)
+		if err != nil {
+			return nil, 0, err
+		}
+		pos += n
+		// key=value 形式的命名参数
+		if pos < len(s) && s[pos] == '=' && isIdentifier(value) {
+			pos++
+			if pos >= len(s) {
+				return nil, 0, fmt.Errorf("unterminated shortcode %q", t.name)
+			}
+			v, n, err := scanValue(s[pos:], close)
+			if err != nil {
+				return nil, 0, err
+			}
+			pos += n
+			t.named[value] = v
+			continue
+		}
+		t.positional = append(t.positional, value)
+	}
+
+	if len(t.positional) > 0 && len(t.named) > 0 {
+		return nil, 0, fmt.Errorf("shortcode %q mixes named and positional parameters", t.name)
+	}
+	t.raw = s[:pos]
+	return t, pos, nil
+}
+
+// scanValue 读取一个参数值：双引号字符串 (支持转义)、反引号原始字符串或裸词
+func scanValue(s, close string) (string, int, error) {
+	switch s[0] {
+	case '"':
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				if i+1 < len(s) {
+					i++
+					b.WriteByte(s[i])
+				}
+			case '"':
+				return b.String(), i + 1, nil
+			default:
+				b.WriteByte(s[i])
+			}
+		}
+		return "", 0, fmt.Errorf("unterminated string")
+	case '`':
+		end := strings.IndexByte(s[1:], '`')
+		if end < 0 {
+			return "", 0, fmt.Errorf("unterminated raw string")
+		}
+		return s[1 : end+1], end + 2, nil
+	}
+
+	i := 0
+	for i < len(s) && !isSpace(s[i]) && s[i] != '=' && !strings.HasPrefix(s[i:], close) && !strings.HasPrefix(s[i:], "/"+close) {
+		i++
+	}
+	if i == 0 {
+		return "", 0, fmt.Errorf("unexpected %q", s[0])
+	}
+	return s[:i], i, nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// isNameChar 短代码名允许 ASCII 字母、数字、- _ 以及子目录分隔符 /
+func isNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '/'
+}
+
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
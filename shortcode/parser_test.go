@@ -0,0 +1,143 @@
+package shortcode
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// dump 将解析结果格式化为便于比较的文本：文本段加引号，短代码写作 name(参数)[内容]
+func dump(items []item) string {
+	var b strings.Builder
+	for _, it := range items {
+		if it.sc == nil {
+			fmt.Fprintf(&b, "%q", it.text)
+			continue
+		}
+		b.WriteString(it.sc.name)
+		var args []string
+		args = append(args, it.sc.positional...)
+		keys := make([]string, 0, len(it.sc.named))
+		for k := range it.sc.named {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			args = append(args, k+"="+it.sc.named[k])
+		}
+		fmt.Fprintf(&b, "(%s)", strings.Join(args, ","))
+		if it.sc.paired {
+			fmt.Fprintf(&b, "[%s]", dump(it.sc.inner))
+		}
+	}
+	return b.String()
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"text", "plain", `"plain"`},
+		{"single", `a {{< figure src="x.png" >}} b`, `"a "figure(src=x.png)" b"`},
+		{"self closed", `{{< br />}}`, `br()`},
+		{"paired", `{{< note >}}hi{{< /note >}}`, `note()["hi"]`},
+		{"nested", `{{< a >}}{{< b x >}}y{{< /b >}}{{< /a >}}`, `a()[b(x)["y"]]`},
+		{"unclosed inner", `{{< a >}}{{< b >}}c{{< /a >}}`, `a()[b()"c"]`},
+		{"stray closing", `x{{< /a >}}`, `"x""{{< /a >}}"`},
+		{"markdown delimiters", `{{% tip %}}**t**{{% /tip %}}`, `tip()["**t**"]`},
+		{"unterminated", `{{< figure src="x"`, `"{{<"" figure src=\"x\""`},
+		{"trailing equals", `{{< figure src=`, `"{{<"" figure src="`},
+		{"escaped", `{{</* figure */>}}`, `"{{< figure >}}"`},
+		{"escaped overlap", `{{</*/>}}`, `"{{<""/*/>}}"`},
+		{"escaped overlap markdown", `{{%/*/%}}`, `"{{%""/*/%}}"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dump(parse(tt.in)); got != tt.want {
+				t.Errorf("parse(%q) = %s, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanTag(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string // 成功时的 name(参数)，失败时为空
+		n       int
+		closing bool
+	}{
+		{in: `{{< figure src="a b" >}}rest`, want: "figure(src=a b)", n: 24},
+		{in: "{{< code `x y` 2 >}}", want: "code(x y,2)", n: 20},
+		{in: `{{< /note >}}`, want: "note()", n: 13, closing: true},
+		{in: `{{< br/>}}`, want: "br()", n: 10},
+		{in: `{{<`},
+		{in: `{{< >}}`},
+		{in: `{{< figure`},
+		{in: `{{< figure src=`},
+		{in: `{{< figure src="x`},
+		{in: "{{< figure src=`x"},
+		{in: `{{< /note x >}}`},
+		{in: `{{< figure a src=b >}}`},
+	}
+	for _, tt := range tests {
+		tg, n, err := scanTag(tt.in)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("scanTag(%q) = %+v, want error", tt.in, tg)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("scanTag(%q) error: %v", tt.in, err)
+			continue
+		}
+		got := dump([]item{{sc: tg.call()}})
+		if got != tt.want || n != tt.n || tg.closing != tt.closing {
+			t.Errorf("scanTag(%q) = %s, %d, closing=%v, want %s, %d, closing=%v", tt.in, got, n, tg.closing, tt.want, tt.n, tt.closing)
+		}
+	}
+}
+
+func TestScanEscaped(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		n    int
+		ok   bool
+	}{
+		{`{{</* figure src="x" */>}} tail`, `{{< figure src="x" >}}`, 26, true},
+		{`{{% /* tip */ %}}`, `{{% tip %}}`, 17, true},
+		{`{{</**/>}}`, `{{<>}}`, 10, true},
+		{`{{</*/>}}`, "", 0, false},
+		{`{{%/*/%}}`, "", 0, false},
+		{`{{</* figure`, "", 0, false},
+		{`{{</* figure */`, "", 0, false},
+		{`{{< figure >}}`, "", 0, false},
+	}
+	for _, tt := range tests {
+		got, n, ok := scanEscaped(tt.in)
+		if got != tt.want || n != tt.n || ok != tt.ok {
+			t.Errorf("scanEscaped(%q) = %q, %d, %v, want %q, %d, %v", tt.in, got, n, ok, tt.want, tt.n, tt.ok)
+		}
+	}
+}
+
+// FuzzParse 任意输入都不应 panic
+func FuzzParse(f *testing.F) {
+	for _, s := range []string{
+		`{{< figure src="x.png" >}}`,
+		`{{< note >}}hi{{< /note >}}`,
+		`{{% tip %}}t{{% /tip %}}`,
+		`{{</* figure */>}}`,
+		`{{</*/>}}`,
+		`{{< figure src=`,
+		"{{< code `x` >}}",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		parse(s)
+	})
+}
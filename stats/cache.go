@@ -0,0 +1,55 @@
+package stats
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// Cache 按文件修改时间缓存统计结果，文件未改动时不再重新解析
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]entry
+}
+
+type entry struct {
+	modTime time.Time
+	stats   Stats
+}
+
+// NewCache 创建缓存
+func NewCache() *Cache {
+	return &Cache{entries: make(map[string]entry)}
+}
+
+// Get 返回文件的统计，缓存的修改时间与 modTime 不一致时用 source 重新计算
+func (c *Cache) Get(path string, modTime time.Time, source []byte) Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[path]; ok && e.modTime.Equal(modTime) {
+		return e.stats
+	}
+	s := Compute(string(source))
+	c.entries[path] = entry{modTime: modTime, stats: s}
+	return s
+}
+
+// File 读取文件的统计，只有文件改动过才会重新读取
+func (c *Cache) File(path string) (Stats, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Stats{}, err
+	}
+	c.mu.Lock()
+	e, ok := c.entries[path]
+	c.mu.Unlock()
+	if ok && e.modTime.Equal(info.ModTime()) {
+		return e.stats, nil
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return Stats{}, err
+	}
+	return c.Get(path, info.ModTime(), source), nil
+}
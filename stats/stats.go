@@ -0,0 +1,181 @@
+// Package stats 文章统计
+// 计算字数、预计阅读时间以及图片、代码块、链接、标题等内容清单，用于文章列表与系列看板。
+// 字数按中文习惯统计：每个汉字 (含日文假名、韩文) 记一个字，连续的英文字母或数字记一个词。
+package stats
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+
+	"github.com/hankmor/mymedia/tools/wechat-preview/render"
+)
+
+// 阅读速度
+const (
+	CJKPerMinute   = 400 // 每分钟阅读的汉字数
+	WordsPerMinute = 200 // 每分钟阅读的英文单词数
+	CodePerMinute  = 20  // 每分钟阅读的代码行数
+	ImageSeconds   = 10  // 每张图片的浏览时间 (秒)
+)
+
+// Stats 单篇文章的统计
+type Stats struct {
+	Words          int `json:"words"`          // 字数：汉字数 + 英文单词数
+	CJK            int `json:"cjk"`            // 汉字数
+	Latin          int `json:"latin"`          // 英文单词数
+	Characters     int `json:"characters"`     // 非空白字符数 (含标点)
+	ReadingMinutes int `json:"readingMinutes"` // 预计阅读时间 (分钟)
+	Images         int `json:"images"`
+	CodeBlocks     int `json:"codeBlocks"`
+	CodeLines      int `json:"codeLines"`
+	Links          int `json:"links"`
+	Headings       int `json:"headings"`
+	HeadingDepth   int `json:"headingDepth"` // 最深的标题级别，例如 3 表示用到了 H3
+}
+
+var (
+	// reShortcode 短代码标签本身不计入字数，成对短代码之间的内容照常统计
+	reShortcode = regexp.MustCompile(`(?s)\{\{[<%]\s*/?\s*([\w.-]*).*?[%>]\}\}`)
+	reImgTag    = regexp.MustCompile(`(?i)<img\b`)
+	reLinkTag   = regexp.MustCompile(`(?i)<a\s[^>]*\bhref=`)
+)
+
+// mdParser 统计使用的解析器
+var mdParser = goldmark.New(goldmark.WithExtensions(extension.GFM, extension.Footnote)).Parser()
+
+// Compute 统计文章源文件 (含 Frontmatter)，Frontmatter 不计入字数
+func Compute(source string) Stats {
+	var s Stats
+	_, body := render.SplitFrontmatter(strings.ReplaceAll(source, "\r\n", "\n"))
+
+	// figure 短代码同样是图片
+	body = reShortcode.ReplaceAllStringFunc(body, func(tag string) string {
+		if reShortcode.FindStringSubmatch(tag)[1] == "figure" {
+			s.Images++
+		}
+		return " "
+	})
+
+	src := []byte(body)
+	var prose strings.Builder
+	ast.Walk(mdParser.Parse(text.NewReader(src)), func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			if n.Type() == ast.TypeBlock {
+				prose.WriteByte('\n')
+			}
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Heading:
+			s.Headings++
+			s.HeadingDepth = max(s.HeadingDepth, n.Level)
+		case *ast.Image:
+			s.Images++
+			return ast.WalkSkipChildren, nil // 替代文字不计入字数
+		case *ast.Link, *ast.AutoLink:
+			s.Links++
+			if _, ok := n.(*ast.AutoLink); ok {
+				return ast.WalkSkipChildren, nil
+			}
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			s.CodeBlocks++
+			s.CodeLines += n.Lines().Len()
+			return ast.WalkSkipChildren, nil
+		case *ast.HTMLBlock, *ast.RawHTML:
+			var segments *text.Segments
+			if r, ok := n.(*ast.RawHTML); ok {
+				segments = r.Segments
+			} else {
+				segments = n.Lines()
+			}
+			var raw []byte
+			for i := 0; i < segments.Len(); i++ {
+				seg := segments.At(i)
+				raw = append(raw, seg.Value(src)...)
+			}
+			s.Images += len(reImgTag.FindAll(raw, -1))
+			s.Links += len(reLinkTag.FindAll(raw, -1))
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			prose.Write(n.Segment.Value(src))
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				prose.WriteByte('\n')
+			}
+		case *ast.String:
+			prose.Write(n.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	s.count(prose.String())
+	s.ReadingMinutes = readingMinutes(s)
+	return s
+}
+
+// count 统计文本中的汉字、英文单词与字符数
+func (s *Stats) count(text string) {
+	inWord := false
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			inWord = false
+			continue
+		case isCJK(r):
+			s.CJK++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if !inWord {
+				s.Latin++
+			}
+			inWord = true
+		case r == '\'' || r == '-' || r == '_':
+			// don't、well-known 等记作一个词，不打断单词
+		default:
+			inWord = false
+		}
+		s.Characters++
+	}
+	s.Words = s.CJK + s.Latin
+}
+
+// isCJK 是否为按字计数的文字
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// readingMinutes 预计阅读时间，有内容时至少 1 分钟
+func readingMinutes(s Stats) int {
+	if s.Characters == 0 && s.CodeLines == 0 && s.Images == 0 {
+		return 0
+	}
+	seconds := s.CJK*60/CJKPerMinute + s.Latin*60/WordsPerMinute + s.CodeLines*60/CodePerMinute + s.Images*ImageSeconds
+	return max((seconds+59)/60, 1)
+}
+
+// Total 多篇文章的合计，HeadingDepth 取最大值
+type Total struct {
+	Articles int `json:"articles"`
+	Stats
+}
+
+// Add 累加一篇文章的统计
+func (t *Total) Add(s Stats) {
+	t.Articles++
+	t.Words += s.Words
+	t.CJK += s.CJK
+	t.Latin += s.Latin
+	t.Characters += s.Characters
+	t.ReadingMinutes += s.ReadingMinutes
+	t.Images += s.Images
+	t.CodeBlocks += s.CodeBlocks
+	t.CodeLines += s.CodeLines
+	t.Links += s.Links
+	t.Headings += s.Headings
+	t.HeadingDepth = max(t.HeadingDepth, s.HeadingDepth)
+}
@@ -0,0 +1,129 @@
+package texmath
+
+import (
+	"math"
+	"sync"
+	"unicode"
+
+	"github.com/go-fonts/dejavu/dejavumathtexgyre"
+	"github.com/go-fonts/dejavu/dejavusans"
+	"github.com/go-fonts/dejavu/dejavuserif"
+	"github.com/go-fonts/dejavu/dejavuserifbold"
+	"github.com/go-fonts/dejavu/dejavuserifitalic"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// fontID 内置字体编号
+type fontID int
+
+const (
+	fontRoman fontID = iota
+	fontItalic
+	fontBold
+	fontMath
+	fontSans
+	fontCount
+)
+
+var (
+	fontsOnce sync.Once
+	fonts     [fontCount]*opentype.Font
+	fontsErr  error
+
+	facesMu sync.Mutex
+	faces   = make(map[faceKey]font.Face)
+)
+
+type faceKey struct {
+	id   fontID
+	size float64
+}
+
+// loadFonts 解析内置字体，只执行一次
+func loadFonts() error {
+	fontsOnce.Do(func() {
+		data := [fontCount][]byte{
+			fontRoman:  dejavuserif.TTF,
+			fontItalic: dejavuserifitalic.TTF,
+			fontBold:   dejavuserifbold.TTF,
+			fontMath:   dejavumathtexgyre.TTF,
+			fontSans:   dejavusans.TTF,
+		}
+		for i, ttf := range data {
+			if fonts[i], fontsErr = opentype.Parse(ttf); fontsErr != nil {
+				return
+			}
+		}
+	})
+	return fontsErr
+}
+
+// face 获取指定字体与字号的 Face，按 0.25px 取整缓存
+func face(id fontID, size float64) font.Face {
+	size = math.Round(size*4) / 4
+	key := faceKey{id, size}
+
+	facesMu.Lock()
+	defer facesMu.Unlock()
+	if f, ok := faces[key]; ok {
+		return f
+	}
+	f, err := opentype.NewFace(fonts[id], &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingNone,
+	})
+	if err != nil {
+		return nil
+	}
+	faces[key] = f
+	return f
+}
+
+// hasGlyph 判断字体是否包含某字符
+func hasGlyph(id fontID, r rune) bool {
+	var buf sfnt.Buffer
+	index, err := fonts[id].GlyphIndex(&buf, r)
+	return err == nil && index != 0
+}
+
+// pickFont 为字符选择字体：字母数字与希腊字母用衬线体，符号优先用数学字体
+func pickFont(r rune, style fontStyle) fontID {
+	preferred := fontRoman
+	switch style {
+	case styleBold:
+		preferred = fontBold
+	case styleItalic:
+		preferred = fontItalic
+	case styleAuto:
+		// 拉丁字母与小写希腊字母按数学惯例使用斜体，大写希腊字母保持正体
+		if (r < unicode.MaxASCII && unicode.IsLetter(r)) || (r >= 'α' && r <= 'ω') || r == 'ϵ' || r == 'ϑ' || r == 'ϕ' || r == 'ϖ' || r == 'ϱ' {
+			preferred = fontItalic
+		}
+	}
+
+	isText := r < unicode.MaxASCII || unicode.Is(unicode.Greek, r)
+	candidates := []fontID{preferred, fontMath, fontSans}
+	if !isText {
+		candidates = []fontID{fontMath, preferred, fontSans}
+	}
+	for _, id := range candidates {
+		if hasGlyph(id, r) {
+			return id
+		}
+	}
+	return preferred
+}
+
+// measure 返回字符串的前进宽度与墨迹上下边界 (相对基线，向上为正)
+func measure(f font.Face, text string) (advance, ascent, descent float64) {
+	bounds, adv := font.BoundString(f, text)
+	return toFloat(adv), -toFloat(bounds.Min.Y), toFloat(bounds.Max.Y)
+}
+
+func toFloat(v fixed.Int26_6) float64 {
+	return float64(v) / 64
+}
@@ -0,0 +1,628 @@
+package texmath
+
+import (
+	"math"
+	"strings"
+)
+
+// box 排版盒子，坐标单位为像素，height 为基线以上高度，depth 为基线以下深度
+type box struct {
+	width, height, depth float64
+	draw                 func(c *canvas, x, y float64) // (x, y) 为盒子左侧基线位置
+}
+
+func emptyBox() *box {
+	return &box{draw: func(*canvas, float64, float64) {}}
+}
+
+// level 数学样式层级
+const (
+	levelDisplay = iota
+	levelText
+	levelScript
+	levelScriptScript
+)
+
+// state 当前排版状态
+type state struct {
+	base  float64 // 正文字号 (像素)
+	level int
+}
+
+// size 当前层级实际字号
+func (s state) size() float64 {
+	switch s.level {
+	case levelScript:
+		return s.base * 0.7
+	case levelScriptScript:
+		return s.base * 0.5
+	}
+	return s.base
+}
+
+// script 上下标层级
+func (s state) script() state {
+	if s.level < levelScript {
+		s.level = levelScript
+	} else {
+		s.level = levelScriptScript
+	}
+	return s
+}
+
+// fraction 分子分母层级
+func (s state) fraction() state {
+	if s.level < levelScriptScript {
+		s.level++
+	}
+	return s
+}
+
+// axis 数学轴高度 (分数线、定界符的垂直中心)
+func (s state) axis() float64 {
+	_, ascent, descent := measure(face(fontMath, s.size()), "+")
+	return (ascent - descent) / 2
+}
+
+// rule 线条粗细
+func (s state) rule() float64 {
+	return math.Max(1, s.size()*0.05)
+}
+
+// layout 排版任意节点
+func layout(n node, st state) *box {
+	switch n := n.(type) {
+	case nil:
+		return emptyBox()
+	case *listNode:
+		return layoutList(n.items, st)
+	case *symNode:
+		return layoutText(n.text, n.style, st.size())
+	case *scriptNode:
+		return layoutScript(n, st)
+	case *fracNode:
+		return layoutFrac(n, st)
+	case *sqrtNode:
+		return layoutSqrt(n, st)
+	case *opNode:
+		return layoutOp(n, st)
+	case *delimNode:
+		body := layout(n.body, st)
+		return wrapDelimiters(body, n.left, n.right, st)
+	case *accentNode:
+		return layoutAccent(n, st)
+	case *spaceNode:
+		return &box{width: n.em * st.size(), draw: func(*canvas, float64, float64) {}}
+	case *matrixNode:
+		return layoutMatrix(n, st)
+	}
+	return emptyBox()
+}
+
+// classOf 节点的原子类型，用于计算间距
+func classOf(n node) atomClass {
+	switch n := n.(type) {
+	case *symNode:
+		return n.class
+	case *opNode:
+		return classOp
+	case *scriptNode:
+		if n.base != nil {
+			return classOf(n.base)
+		}
+	case *fracNode, *delimNode:
+		return classInner
+	}
+	return classOrd
+}
+
+// atomSpace 相邻原子之间的间距 (em)，参考 TeX 间距表的简化版本
+func atomSpace(prev, cur atomClass, st state) float64 {
+	tight := st.level >= levelScript
+	switch {
+	case prev == classOpen || cur == classClose || cur == classPunct:
+		return 0
+	case prev == classBin || cur == classBin:
+		if tight {
+			return 0
+		}
+		return 4.0 / 18
+	case prev == classRel || cur == classRel:
+		if tight || prev == cur {
+			return 0
+		}
+		return 5.0 / 18
+	case prev == classPunct:
+		if tight {
+			return 0
+		}
+		return 3.0 / 18
+	case prev == classOp || cur == classOp:
+		return 3.0 / 18
+	case prev == classInner || cur == classInner:
+		if tight {
+			return 0
+		}
+		return 3.0 / 18
+	}
+	return 0
+}
+
+// layoutList 水平排列节点并插入原子间距
+func layoutList(items []node, st state) *box {
+	var boxes []*box
+	prev := atomClass(-1)
+	for _, item := range items {
+		switch item := item.(type) {
+		case nil:
+			continue
+		case *styleNode:
+			if item.display {
+				st.level = levelDisplay
+			} else if st.level == levelDisplay {
+				st.level = levelText
+			}
+			continue
+		case *spaceNode:
+			boxes = append(boxes, layout(item, st))
+			continue
+		}
+
+		class := classOf(item)
+		// 出现在开头、运算符或关系符之后的二元运算符按普通符号处理 (如负号)
+		if class == classBin && (prev < 0 || prev == classBin || prev == classOp || prev == classRel || prev == classOpen || prev == classPunct) {
+			class = classOrd
+		}
+		if prev >= 0 {
+			if space := atomSpace(prev, class, st); space != 0 {
+				boxes = append(boxes, &box{width: space * st.size(), draw: func(*canvas, float64, float64) {}})
+			}
+		}
+		boxes = append(boxes, layout(item, st))
+		prev = class
+	}
+	return hbox(boxes)
+}
+
+// hbox 水平拼接盒子
+func hbox(boxes []*box) *box {
+	result := &box{}
+	for _, b := range boxes {
+		result.width += b.width
+		result.height = math.Max(result.height, b.height)
+		result.depth = math.Max(result.depth, b.depth)
+	}
+	result.draw = func(c *canvas, x, y float64) {
+		for _, b := range boxes {
+			b.draw(c, x, y)
+			x += b.width
+		}
+	}
+	return result
+}
+
+// layoutText 排版文本，逐字符选择字体
+func layoutText(text string, style fontStyle, size float64) *box {
+	var boxes []*box
+	for _, r := range text {
+		id := pickFont(r, style)
+		f := face(id, size)
+		ch := string(r)
+		advance, ascent, descent := measure(f, ch)
+		boxes = append(boxes, &box{
+			width:  advance,
+			height: math.Max(ascent, 0),
+			depth:  math.Max(descent, 0),
+			draw: func(c *canvas, x, y float64) {
+				c.text(f, ch, x, y)
+			},
+		})
+	}
+	return hbox(boxes)
+}
+
+// layoutScript 排版上下标
+func layoutScript(n *scriptNode, st state) *box {
+	if op, ok := n.base.(*opNode); ok && op.limits && st.level == levelDisplay {
+		return layoutLimits(op, n.sup, n.sub, st)
+	}
+
+	base := layout(n.base, st)
+	em := st.size()
+	scriptSt := st.script()
+	xHeight := 0.45 * em
+
+	var sup, sub *box
+	var supShift, subShift float64
+	if n.sup != nil {
+		sup = layout(n.sup, scriptSt)
+		supShift = math.Max(base.height-0.35*scriptSt.size(), 0.4*em)
+		supShift = math.Max(supShift, sup.depth+0.25*xHeight)
+	}
+	if n.sub != nil {
+		sub = layout(n.sub, scriptSt)
+		subShift = math.Max(base.depth+0.05*scriptSt.size(), 0.15*em)
+		subShift = math.Max(subShift, sub.height-0.8*xHeight)
+	}
+	// 同时存在上下标时保证二者之间留有间隙
+	if sup != nil && sub != nil {
+		gap := (supShift - sup.depth) - (sub.height - subShift)
+		if minGap := 4 * st.rule(); gap < minGap {
+			subShift += minGap - gap
+		}
+	}
+
+	scriptWidth := 0.0
+	result := &box{width: base.width, height: base.height, depth: base.depth}
+	if sup != nil {
+		scriptWidth = math.Max(scriptWidth, sup.width)
+		result.height = math.Max(result.height, supShift+sup.height)
+	}
+	if sub != nil {
+		scriptWidth = math.Max(scriptWidth, sub.width)
+		result.depth = math.Max(result.depth, subShift+sub.depth)
+	}
+	result.width += scriptWidth + 0.05*em
+	result.draw = func(c *canvas, x, y float64) {
+		base.draw(c, x, y)
+		if sup != nil {
+			sup.draw(c, x+base.width, y-supShift)
+		}
+		if sub != nil {
+			sub.draw(c, x+base.width, y+subShift)
+		}
+	}
+	return result
+}
+
+// layoutLimits 显示模式下将上下限放在运算符正上方和正下方
+func layoutLimits(op *opNode, supNode, subNode node, st state) *box {
+	base := layoutOp(op, st)
+	scriptSt := st.script()
+	gap := 0.15 * st.size()
+
+	sup, sub := layout(supNode, scriptSt), layout(subNode, scriptSt)
+	width := math.Max(base.width, math.Max(sup.width, sub.width))
+
+	result := &box{width: width, height: base.height, depth: base.depth}
+	if supNode != nil {
+		result.height += gap + sup.depth + sup.height
+	}
+	if subNode != nil {
+		result.depth += gap + sub.height + sub.depth
+	}
+	result.draw = func(c *canvas, x, y float64) {
+		base.draw(c, x+(width-base.width)/2, y)
+		if supNode != nil {
+			sup.draw(c, x+(width-sup.width)/2, y-base.height-gap-sup.depth)
+		}
+		if subNode != nil {
+			sub.draw(c, x+(width-sub.width)/2, y+base.depth+gap+sub.height)
+		}
+	}
+	return result
+}
+
+// layoutOp 排版大型运算符或函数名
+func layoutOp(op *opNode, st state) *box {
+	if !op.big {
+		return layoutText(op.text, styleRoman, st.size())
+	}
+
+	// 大型运算符放大后以数学轴为中心
+	scale := 1.2
+	if st.level == levelDisplay {
+		scale = 1.7
+	}
+	if strings.ContainsAny(op.text, "∫∬∭∮") {
+		scale *= 1.25
+	}
+	f := face(fontMath, st.size()*scale)
+	advance, ascent, descent := measure(f, op.text)
+	shift := (ascent-descent)/2 - st.axis()
+	return &box{
+		width:  advance,
+		height: ascent - shift,
+		depth:  descent + shift,
+		draw: func(c *canvas, x, y float64) {
+			c.text(f, op.text, x, y+shift)
+		},
+	}
+}
+
+// layoutFrac 排版分数与二项式
+func layoutFrac(n *fracNode, st state) *box {
+	inner := st
+	switch n.display {
+	case 1:
+		inner.level = levelText
+	case -1:
+		inner.level = levelScript
+	default:
+		inner = st.fraction()
+	}
+
+	num, den := layout(n.num, inner), layout(n.den, inner)
+	em := st.size()
+	axis := st.axis()
+	thickness := st.rule()
+	if n.binom {
+		thickness = 0
+	}
+	gap := thickness
+	if st.level == levelDisplay {
+		gap = 3 * thickness
+	}
+	gap = math.Max(gap, 0.1*em)
+
+	pad := 0.12 * em
+	width := math.Max(num.width, den.width) + 2*pad
+	numShift := axis + thickness/2 + gap + num.depth
+	denShift := den.height - axis + thickness/2 + gap
+
+	result := &box{
+		width:  width,
+		height: numShift + num.height,
+		depth:  denShift + den.depth,
+		draw: func(c *canvas, x, y float64) {
+			num.draw(c, x+(width-num.width)/2, y-numShift)
+			den.draw(c, x+(width-den.width)/2, y+denShift)
+			if thickness > 0 {
+				c.rect(x+pad/2, y-axis-thickness/2, x+width-pad/2, y-axis+thickness/2)
+			}
+		},
+	}
+	if n.binom {
+		return wrapDelimiters(result, "(", ")", st)
+	}
+	return result
+}
+
+// layoutSqrt 排版根号，根号线条直接用路径绘制以便任意拉伸
+func layoutSqrt(n *sqrtNode, st state) *box {
+	body := layout(n.body, st)
+	em := st.size()
+	t := st.rule()
+	gap := t + 0.1*em
+
+	top := body.height + gap + t   // 顶线上沿到基线的距离
+	bottom := body.depth + 0.05*em // 根号最低点
+	radicalWidth := 0.55 * em
+	if total := top + bottom; total > 1.5*em {
+		radicalWidth += 0.1 * (total - 1.5*em)
+	}
+
+	// 根指数放在根号左上方
+	var index *box
+	indexOffset := 0.0
+	if n.index != nil {
+		indexSt := st
+		indexSt.level = levelScriptScript
+		index = layout(n.index, indexSt)
+		indexOffset = math.Max(0, index.width-0.45*radicalWidth)
+	}
+
+	result := &box{
+		width:  indexOffset + radicalWidth + body.width + 0.1*em,
+		height: top + 0.05*em,
+		depth:  math.Max(body.depth, bottom),
+	}
+	result.draw = func(c *canvas, x, y float64) {
+		x0 := x + indexOffset
+		mid := y - 0.45*math.Min(top, 1.2*em)
+		tickX := x0 + 0.2*radicalWidth
+		valleyX := x0 + 0.5*radicalWidth
+		peakX := x0 + radicalWidth
+		barY := y - top + t/2
+
+		c.line(x0, mid+0.08*em, tickX, mid, t)
+		c.line(tickX, mid, valleyX, y+bottom, 2*t)
+		c.line(valleyX, y+bottom, peakX, barY, t)
+		c.line(peakX-t/4, barY, x0+radicalWidth+body.width+0.1*em, barY, t)
+		body.draw(c, peakX, y)
+		if index != nil {
+			index.draw(c, x, mid-0.1*em-index.depth)
+		}
+	}
+	if index != nil {
+		result.height = math.Max(result.height, top+index.height)
+	}
+	return result
+}
+
+// layoutAccent 排版重音与上下划线
+func layoutAccent(n *accentNode, st state) *box {
+	body := layout(n.body, st)
+	em := st.size()
+	t := st.rule()
+	gap := 0.08 * em
+
+	if n.under {
+		result := &box{width: body.width, height: body.height, depth: body.depth + gap + t}
+		result.draw = func(c *canvas, x, y float64) {
+			body.draw(c, x, y)
+			c.rect(x, y+body.depth+gap, x+body.width, y+body.depth+gap+t)
+		}
+		return result
+	}
+
+	if n.accent == "" {
+		result := &box{width: body.width, height: body.height + gap + t, depth: body.depth}
+		result.draw = func(c *canvas, x, y float64) {
+			body.draw(c, x, y)
+			c.rect(x, y-body.height-gap-t, x+body.width, y-body.height-gap)
+		}
+		return result
+	}
+
+	// 字符型重音：箭头按内容宽度缩放，其余按字号居中放置
+	size := em
+	if n.accent == "→" {
+		size = math.Max(0.7*em, math.Min(body.width*1.2, 1.5*em))
+	}
+	f := face(pickFont([]rune(n.accent)[0], styleRoman), size)
+	advance, ascent, descent := measure(f, n.accent)
+	lift := body.height + gap + descent
+	result := &box{
+		width:  math.Max(body.width, advance),
+		height: lift + ascent,
+		depth:  body.depth,
+	}
+	result.draw = func(c *canvas, x, y float64) {
+		body.draw(c, x+(result.width-body.width)/2, y)
+		c.text(f, n.accent, x+(result.width-advance)/2, y-lift)
+	}
+	return result
+}
+
+// layoutMatrix 排版矩阵、cases、aligned 等环境
+func layoutMatrix(n *matrixNode, st state) *box {
+	cellSt := st
+	if n.textStyle || st.level == levelDisplay {
+		cellSt.level = levelText
+	}
+	em := st.size()
+
+	cols := 0
+	cells := make([][]*box, len(n.rows))
+	for i, row := range n.rows {
+		for _, cell := range row {
+			cells[i] = append(cells[i], layout(cell, cellSt))
+		}
+		cols = max(cols, len(row))
+	}
+
+	colWidths := make([]float64, cols)
+	rowHeights := make([]float64, len(cells))
+	rowDepths := make([]float64, len(cells))
+	for i, row := range cells {
+		rowHeights[i] = 0.7 * em
+		rowDepths[i] = 0.3 * em
+		for j, cell := range row {
+			colWidths[j] = math.Max(colWidths[j], cell.width)
+			rowHeights[i] = math.Max(rowHeights[i], cell.height)
+			rowDepths[i] = math.Max(rowDepths[i], cell.depth)
+		}
+	}
+
+	align := func(j int) byte {
+		if n.align == "" {
+			return 'c'
+		}
+		return n.align[j%len(n.align)]
+	}
+	// aligned 类环境 r/l 成对紧贴，组与组之间才有间距
+	colGap := func(j int) float64 {
+		if n.align == "rl" && j%2 == 0 {
+			return 0
+		}
+		return em
+	}
+
+	rowGap := 0.25 * em
+	total := 0.0
+	for i := range cells {
+		total += rowHeights[i] + rowDepths[i]
+		if i > 0 {
+			total += rowGap
+		}
+	}
+	width := 0.0
+	for j, w := range colWidths {
+		width += w
+		if j < cols-1 {
+			width += colGap(j)
+		}
+	}
+
+	axis := st.axis()
+	body := &box{width: width, height: total/2 + axis, depth: total/2 - axis}
+	body.draw = func(c *canvas, x, y float64) {
+		rowY := y - body.height
+		for i, row := range cells {
+			rowY += rowHeights[i]
+			cellX := x
+			for j := 0; j < cols; j++ {
+				if j < len(row) {
+					cell := row[j]
+					offset := 0.0
+					switch align(j) {
+					case 'c':
+						offset = (colWidths[j] - cell.width) / 2
+					case 'r':
+						offset = colWidths[j] - cell.width
+					}
+					cell.draw(c, cellX+offset, rowY)
+				}
+				cellX += colWidths[j] + colGap(j)
+			}
+			rowY += rowDepths[i] + rowGap
+		}
+	}
+
+	if n.left == "" && n.right == "" {
+		return body
+	}
+	padded := &box{width: body.width + 0.2*em, height: body.height, depth: body.depth}
+	padded.draw = func(c *canvas, x, y float64) { body.draw(c, x+0.1*em, y) }
+	return wrapDelimiters(padded, n.left, n.right, st)
+}
+
+// wrapDelimiters 在盒子两侧加上按内容高度伸缩的定界符
+func wrapDelimiters(body *box, left, right string, st state) *box {
+	l := layoutDelimiter(left, body, st)
+	r := layoutDelimiter(right, body, st)
+	return hbox([]*box{l, body, r})
+}
+
+// layoutDelimiter 生成覆盖指定盒子高度的定界符
+func layoutDelimiter(delim string, body *box, st state) *box {
+	if delim == "" {
+		return emptyBox()
+	}
+	em := st.size()
+	axis := st.axis()
+	half := math.Max(body.height-axis, body.depth+axis)
+	target := math.Max(2*half*1.05, em)
+
+	// 竖线直接绘制线条，避免放大字形后变粗
+	if delim == "|" || delim == "‖" {
+		t := st.rule()
+		lines := 1
+		if delim == "‖" {
+			lines = 2
+		}
+		width := 0.15*em + float64(lines)*t + float64(lines-1)*0.12*em
+		return &box{
+			width:  width + 0.1*em,
+			height: axis + target/2,
+			depth:  target/2 - axis,
+			draw: func(c *canvas, x, y float64) {
+				for i := 0; i < lines; i++ {
+					lx := x + 0.1*em + float64(i)*(t+0.12*em)
+					c.rect(lx, y-axis-target/2, lx+t, y-axis+target/2)
+				}
+			},
+		}
+	}
+
+	r := []rune(delim)[0]
+	id := pickFont(r, styleRoman)
+	_, ascent, descent := measure(face(id, em), delim)
+	inkHeight := ascent + descent
+	size := em
+	if inkHeight > 0 && target > inkHeight {
+		size = em * target / inkHeight
+	}
+	f := face(id, size)
+	advance, ascent, descent := measure(f, delim)
+	shift := (ascent-descent)/2 - axis
+	return &box{
+		width:  advance,
+		height: ascent - shift,
+		depth:  descent + shift,
+		draw: func(c *canvas, x, y float64) {
+			c.text(f, delim, x, y+shift)
+		},
+	}
+}
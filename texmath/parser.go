@@ -0,0 +1,622 @@
+package texmath
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// fontStyle 字符字体风格
+type fontStyle int
+
+const (
+	styleAuto   fontStyle = iota // 字母斜体，其余正体
+	styleRoman                   // 正体
+	styleBold                    // 粗体
+	styleItalic                  // 斜体
+)
+
+// node 公式语法树节点
+type node interface{}
+
+type (
+	// symNode 单个符号或一段文本
+	symNode struct {
+		text  string
+		class atomClass
+		style fontStyle
+	}
+	// listNode 节点序列，对应 {...}
+	listNode struct {
+		items []node
+	}
+	// scriptNode 上下标
+	scriptNode struct {
+		base, sup, sub node
+	}
+	// fracNode 分数与二项式
+	fracNode struct {
+		num, den node
+		binom    bool
+		display  int // 1 强制显示模式 (\dfrac)，-1 强制文本模式 (\tfrac)
+	}
+	// sqrtNode 根号
+	sqrtNode struct {
+		body, index node
+	}
+	// opNode 大型运算符与函数名
+	opNode struct {
+		text   string
+		big    bool // 是否为放大符号 (∑ ∫)，否则为函数名 (lim max)
+		limits bool
+	}
+	// delimNode \left ... \right
+	delimNode struct {
+		left, right string
+		body        node
+	}
+	// accentNode 重音与上下划线
+	accentNode struct {
+		body   node
+		accent string
+		under  bool
+	}
+	// spaceNode 间距
+	spaceNode struct {
+		em float64
+	}
+	// styleNode \displaystyle 等样式切换
+	styleNode struct {
+		display bool
+	}
+	// matrixNode 矩阵类环境
+	matrixNode struct {
+		rows        [][]node
+		left, right string
+		align       string // 每列对齐方式 l/c/r，循环使用
+		textStyle   bool   // cases 等环境单元格使用文本模式
+	}
+)
+
+// parser LaTeX 子集解析器
+type parser struct {
+	src []rune
+	pos int
+}
+
+// parse 解析公式为语法树
+func parse(formula string) (node, error) {
+	p := &parser{src: []rune(formula)}
+	items, err := p.parseList(func(r rune, cmd string) bool { return false })
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.src) {
+		return nil, fmt.Errorf("unexpected %q at %d", string(p.src[p.pos]), p.pos)
+	}
+	return &listNode{items: items}, nil
+}
+
+// parseList 解析节点序列，stop 返回 true 时停止 (不消费终止符)
+func (p *parser) parseList(stop func(r rune, cmd string) bool) ([]node, error) {
+	var items []node
+	for {
+		p.skipSpaces()
+		if p.pos >= len(p.src) {
+			return items, nil
+		}
+		r := p.src[p.pos]
+		if stop(r, p.peekCommand()) {
+			return items, nil
+		}
+
+		switch r {
+		case '^', '_':
+			p.pos++
+			arg, err := p.parseArgument()
+			if err != nil {
+				return nil, err
+			}
+			if err := attachScript(&items, r == '^', arg); err != nil {
+				return nil, err
+			}
+			continue
+		case '\'':
+			p.pos++
+			prime := &symNode{text: "′", class: classOrd, style: styleRoman}
+			if err := attachScript(&items, true, prime); err != nil {
+				return nil, err
+			}
+			continue
+		case '}':
+			return nil, fmt.Errorf("unbalanced } at %d", p.pos)
+		}
+
+		item, err := p.parseAtom()
+		if err != nil {
+			return nil, err
+		}
+		if item != nil {
+			items = append(items, item)
+		}
+	}
+}
+
+// attachScript 将上下标附加到前一个节点
+func attachScript(items *[]node, sup bool, arg node) error {
+	var base node
+	if n := len(*items); n > 0 {
+		base = (*items)[n-1]
+		*items = (*items)[:n-1]
+	}
+	script, ok := base.(*scriptNode)
+	if !ok {
+		script = &scriptNode{base: base}
+	}
+	if sup {
+		if script.sup != nil {
+			// 连续上标 (如 x'^2) 合并为一个上标列表
+			script.sup = &listNode{items: []node{script.sup, arg}}
+		} else {
+			script.sup = arg
+		}
+	} else {
+		if script.sub != nil {
+			return fmt.Errorf("double subscript")
+		}
+		script.sub = arg
+	}
+	*items = append(*items, script)
+	return nil
+}
+
+// parseAtom 解析单个原子
+func (p *parser) parseAtom() (node, error) {
+	r := p.src[p.pos]
+	switch {
+	case r == '{':
+		p.pos++
+		items, err := p.parseList(func(r rune, cmd string) bool { return r == '}' })
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect('}'); err != nil {
+			return nil, err
+		}
+		return &listNode{items: items}, nil
+	case r == '\\':
+		return p.parseCommand()
+	}
+
+	p.pos++
+	return charNode(r), nil
+}
+
+// charNode 普通字符对应的节点
+func charNode(r rune) node {
+	switch r {
+	case '+':
+		return &symNode{text: "+", class: classBin}
+	case '-':
+		return &symNode{text: "−", class: classBin}
+	case '*':
+		return &symNode{text: "∗", class: classBin}
+	case '=', '<', '>', ':':
+		return &symNode{text: string(r), class: classRel}
+	case '(', '[':
+		return &symNode{text: string(r), class: classOpen}
+	case ')', ']':
+		return &symNode{text: string(r), class: classClose}
+	case ',', ';':
+		return &symNode{text: string(r), class: classPunct}
+	case '~':
+		return &spaceNode{em: 1.0 / 3}
+	}
+	return &symNode{text: string(r), class: classOrd}
+}
+
+// parseCommand 解析 \ 开头的命令
+func (p *parser) parseCommand() (node, error) {
+	start := p.pos
+	name := p.readCommand()
+
+	if sym, ok := symbols[name]; ok {
+		return &symNode{text: sym.text, class: sym.class}, nil
+	}
+	if op, ok := bigOperators[name]; ok {
+		return p.withLimits(&opNode{text: op.text, big: true, limits: op.limits}), nil
+	}
+	if limits, ok := functions[name]; ok {
+		text := name
+		if name == "argmax" || name == "argmin" {
+			text = "arg " + strings.TrimPrefix(name, "arg")
+		}
+		return p.withLimits(&opNode{text: text, limits: limits}), nil
+	}
+	if em, ok := spaces[name]; ok {
+		return &spaceNode{em: em}, nil
+	}
+	if accent, ok := accents[name]; ok {
+		body, err := p.parseArgument()
+		if err != nil {
+			return nil, err
+		}
+		return &accentNode{body: body, accent: accent}, nil
+	}
+
+	switch name {
+	case "frac", "dfrac", "tfrac", "binom", "dbinom", "tbinom":
+		num, err := p.parseArgument()
+		if err != nil {
+			return nil, err
+		}
+		den, err := p.parseArgument()
+		if err != nil {
+			return nil, err
+		}
+		frac := &fracNode{num: num, den: den, binom: strings.HasSuffix(name, "binom")}
+		switch name[0] {
+		case 'd':
+			frac.display = 1
+		case 't':
+			frac.display = -1
+		}
+		return frac, nil
+	case "sqrt":
+		var index node
+		p.skipSpaces()
+		if p.pos < len(p.src) && p.src[p.pos] == '[' {
+			p.pos++
+			items, err := p.parseList(func(r rune, cmd string) bool { return r == ']' })
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(']'); err != nil {
+				return nil, err
+			}
+			index = &listNode{items: items}
+		}
+		body, err := p.parseArgument()
+		if err != nil {
+			return nil, err
+		}
+		return &sqrtNode{body: body, index: index}, nil
+	case "underline":
+		body, err := p.parseArgument()
+		if err != nil {
+			return nil, err
+		}
+		return &accentNode{body: body, under: true}, nil
+	case "text", "textrm", "mbox", "mathrm", "textit", "mathit", "textbf", "mathbf", "boldsymbol", "operatorname", "mathop":
+		return p.parseStyled(name)
+	case "mathbb", "mathcal", "mathscr":
+		arg, err := p.readGroupText()
+		if err != nil {
+			return nil, err
+		}
+		var b strings.Builder
+		for _, r := range arg {
+			if name == "mathbb" {
+				b.WriteString(mapAlphabet(r, blackboard, 0x1D538))
+			} else {
+				b.WriteString(mapAlphabet(r, calligraphic, 0x1D49C))
+			}
+		}
+		return &symNode{text: b.String(), class: classOrd, style: styleRoman}, nil
+	case "left":
+		return p.parseDelimited()
+	case "right", "end", "\\":
+		return nil, fmt.Errorf("unexpected \\%s at %d", name, start)
+	case "begin":
+		return p.parseEnvironment()
+	case "bmod", "mod":
+		return &opNode{text: "mod"}, nil
+	case "pmod":
+		arg, err := p.parseArgument()
+		if err != nil {
+			return nil, err
+		}
+		return &listNode{items: []node{
+			&spaceNode{em: 1}, &symNode{text: "(", class: classOpen},
+			&symNode{text: "mod", class: classOrd, style: styleRoman}, &spaceNode{em: 1.0 / 3},
+			arg, &symNode{text: ")", class: classClose},
+		}}, nil
+	case "displaystyle":
+		return &styleNode{display: true}, nil
+	case "textstyle", "scriptstyle":
+		return &styleNode{display: false}, nil
+	case "limits", "nolimits", "big", "Big", "bigg", "Bigg", "bigl", "bigr", "Bigl", "Bigr":
+		// 尺寸提示命令直接忽略，定界符由后续字符自身给出
+		return nil, nil
+	}
+
+	return nil, fmt.Errorf("unsupported command \\%s", name)
+}
+
+// withLimits 处理紧随运算符之后的 \limits / \nolimits
+func (p *parser) withLimits(op *opNode) node {
+	p.skipSpaces()
+	switch p.peekCommand() {
+	case "limits":
+		p.readCommand()
+		op.limits = true
+	case "nolimits":
+		p.readCommand()
+		op.limits = false
+	}
+	return op
+}
+
+// parseStyled 解析 \text{}、\mathbf{} 等改变字体的命令
+func (p *parser) parseStyled(name string) (node, error) {
+	style := styleRoman
+	switch name {
+	case "textit", "mathit":
+		style = styleItalic
+	case "textbf", "mathbf", "boldsymbol":
+		style = styleBold
+	}
+
+	// \text 系列内容按原样输出，保留空格
+	if strings.HasPrefix(name, "text") || name == "mbox" {
+		text, err := p.readGroupText()
+		if err != nil {
+			return nil, err
+		}
+		return &symNode{text: text, class: classOrd, style: style}, nil
+	}
+
+	arg, err := p.parseArgument()
+	if err != nil {
+		return nil, err
+	}
+	applyStyle(arg, style)
+	if name == "operatorname" || name == "mathop" {
+		return &opNode{text: plainText(arg)}, nil
+	}
+	return arg, nil
+}
+
+// applyStyle 递归设置符号字体
+func applyStyle(n node, style fontStyle) {
+	switch n := n.(type) {
+	case *symNode:
+		n.style = style
+	case *listNode:
+		for _, item := range n.items {
+			applyStyle(item, style)
+		}
+	case *scriptNode:
+		applyStyle(n.base, style)
+	}
+}
+
+// plainText 提取节点中的纯文本
+func plainText(n node) string {
+	switch n := n.(type) {
+	case *symNode:
+		return n.text
+	case *listNode:
+		var b strings.Builder
+		for _, item := range n.items {
+			b.WriteString(plainText(item))
+		}
+		return b.String()
+	}
+	return ""
+}
+
+// parseDelimited 解析 \left( ... \right)
+func (p *parser) parseDelimited() (node, error) {
+	left, err := p.readDelimiter()
+	if err != nil {
+		return nil, err
+	}
+	items, err := p.parseList(func(r rune, cmd string) bool { return cmd == "right" })
+	if err != nil {
+		return nil, err
+	}
+	if p.peekCommand() != "right" {
+		return nil, fmt.Errorf("missing \\right")
+	}
+	p.readCommand()
+	right, err := p.readDelimiter()
+	if err != nil {
+		return nil, err
+	}
+	return &delimNode{left: left, right: right, body: &listNode{items: items}}, nil
+}
+
+// readDelimiter 读取 \left / \right 之后的定界符，"." 表示空
+func (p *parser) readDelimiter() (string, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.src) {
+		return "", fmt.Errorf("missing delimiter")
+	}
+	r := p.src[p.pos]
+	if r != '\\' {
+		p.pos++
+		if r == '.' {
+			return "", nil
+		}
+		return string(r), nil
+	}
+	name := p.readCommand()
+	if sym, ok := symbols[name]; ok {
+		return sym.text, nil
+	}
+	return "", fmt.Errorf("unknown delimiter \\%s", name)
+}
+
+// environments 支持的矩阵类环境：左右定界符、列对齐、是否文本模式
+var environments = map[string]struct {
+	left, right, align string
+	textStyle          bool
+}{
+	"matrix":   {"", "", "c", false},
+	"pmatrix":  {"(", ")", "c", false},
+	"bmatrix":  {"[", "]", "c", false},
+	"Bmatrix":  {"{", "}", "c", false},
+	"vmatrix":  {"|", "|", "c", false},
+	"Vmatrix":  {"‖", "‖", "c", false},
+	"cases":    {"{", "", "l", true},
+	"aligned":  {"", "", "rl", false},
+	"align":    {"", "", "rl", false},
+	"align*":   {"", "", "rl", false},
+	"split":    {"", "", "rl", false},
+	"gathered": {"", "", "c", false},
+	"array":    {"", "", "c", false},
+}
+
+// parseEnvironment 解析 \begin{env} ... \end{env}
+func (p *parser) parseEnvironment() (node, error) {
+	name, err := p.readGroupText()
+	if err != nil {
+		return nil, err
+	}
+	env, ok := environments[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported environment %s", name)
+	}
+	matrix := &matrixNode{left: env.left, right: env.right, align: env.align, textStyle: env.textStyle}
+
+	// array 的列格式 {lcr}
+	if name == "array" {
+		spec, err := p.readGroupText()
+		if err != nil {
+			return nil, err
+		}
+		matrix.align = strings.Map(func(r rune) rune {
+			if r == 'l' || r == 'c' || r == 'r' {
+				return r
+			}
+			return -1
+		}, spec)
+	}
+
+	var row []node
+	for {
+		items, err := p.parseList(func(r rune, cmd string) bool {
+			return r == '&' || cmd == "\\" || cmd == "end"
+		})
+		if err != nil {
+			return nil, err
+		}
+		row = append(row, &listNode{items: items})
+
+		if p.pos >= len(p.src) {
+			return nil, fmt.Errorf("missing \\end{%s}", name)
+		}
+		if p.src[p.pos] == '&' {
+			p.pos++
+			continue
+		}
+		cmd := p.readCommand()
+		matrix.rows = append(matrix.rows, row)
+		row = nil
+		if cmd == "end" {
+			end, err := p.readGroupText()
+			if err != nil {
+				return nil, err
+			}
+			if end != name {
+				return nil, fmt.Errorf("\\begin{%s} ended by \\end{%s}", name, end)
+			}
+			break
+		}
+	}
+
+	// 去掉结尾 \\ 产生的空行
+	if n := len(matrix.rows); n > 1 {
+		last := matrix.rows[n-1]
+		if len(last) == 1 && len(last[0].(*listNode).items) == 0 {
+			matrix.rows = matrix.rows[:n-1]
+		}
+	}
+	return matrix, nil
+}
+
+// parseArgument 解析命令参数：{...} 或单个原子
+func (p *parser) parseArgument() (node, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.src) {
+		return nil, fmt.Errorf("missing argument")
+	}
+	if p.src[p.pos] == '{' {
+		return p.parseAtom()
+	}
+	if p.src[p.pos] == '\\' {
+		return p.parseCommand()
+	}
+	r := p.src[p.pos]
+	p.pos++
+	return charNode(r), nil
+}
+
+// readGroupText 读取 {...} 内的原始文本
+func (p *parser) readGroupText() (string, error) {
+	p.skipSpaces()
+	if err := p.expect('{'); err != nil {
+		return "", err
+	}
+	depth := 1
+	start := p.pos
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				text := string(p.src[start:p.pos])
+				p.pos++
+				return text, nil
+			}
+		}
+		p.pos++
+	}
+	return "", fmt.Errorf("unbalanced {")
+}
+
+// readCommand 读取 \ 之后的命令名，调用时 pos 指向 \
+func (p *parser) readCommand() string {
+	p.pos++ // 跳过 \
+	if p.pos >= len(p.src) {
+		return ""
+	}
+	start := p.pos
+	if !unicode.IsLetter(p.src[p.pos]) {
+		p.pos++
+		return string(p.src[start:p.pos])
+	}
+	for p.pos < len(p.src) && unicode.IsLetter(p.src[p.pos]) {
+		p.pos++
+	}
+	// align* 这类带星号的环境名只出现在 \begin{} 中，这里无需处理
+	return string(p.src[start:p.pos])
+}
+
+// peekCommand 查看当前位置的命令名，不消费
+func (p *parser) peekCommand() string {
+	if p.pos >= len(p.src) || p.src[p.pos] != '\\' {
+		return ""
+	}
+	pos := p.pos
+	name := p.readCommand()
+	p.pos = pos
+	return name
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *parser) expect(r rune) error {
+	if p.pos >= len(p.src) || p.src[p.pos] != r {
+		return fmt.Errorf("expected %q at %d", string(r), p.pos)
+	}
+	p.pos++
+	return nil
+}
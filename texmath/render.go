@@ -0,0 +1,142 @@
+// Package texmath 纯 Go 实现的 LaTeX 数学公式渲染器
+// 支持常用的 LaTeX 数学子集 (上下标、分数、根号、希腊字母、大型运算符、
+// \left\right、矩阵与 cases 等环境)，输出 PNG，便于在不支持 MathJax 的微信中展示。
+package texmath
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// Options 渲染选项
+type Options struct {
+	FontSize float64    // 正文字号 (CSS 像素)，默认 16
+	Scale    float64    // 输出倍率，2 表示生成二倍图以适配高分屏，默认 2
+	Display  bool       // 是否为块级公式 (显示模式)
+	Color    color.RGBA // 公式颜色，默认 #333333
+}
+
+// Image 渲染结果，尺寸均为 CSS 像素
+type Image struct {
+	PNG    []byte
+	Width  float64
+	Height float64
+	Depth  float64 // 基线以下的深度，行内公式用于 vertical-align 对齐基线
+}
+
+// renderMu 字体 Face 非并发安全，渲染过程串行执行
+var renderMu sync.Mutex
+
+// Render 将 LaTeX 公式渲染为 PNG
+func Render(formula string, opts Options) (*Image, error) {
+	if opts.FontSize <= 0 {
+		opts.FontSize = 16
+	}
+	if opts.Scale <= 0 {
+		opts.Scale = 2
+	}
+	if opts.Color == (color.RGBA{}) {
+		opts.Color = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	}
+
+	tree, err := parse(formula)
+	if err != nil {
+		return nil, err
+	}
+
+	renderMu.Lock()
+	defer renderMu.Unlock()
+
+	if err := loadFonts(); err != nil {
+		return nil, fmt.Errorf("load fonts: %w", err)
+	}
+
+	st := state{base: opts.FontSize * opts.Scale, level: levelText}
+	if opts.Display {
+		st.level = levelDisplay
+	}
+	b := layout(tree, st)
+
+	pad := math.Ceil(opts.Scale)
+	width := int(math.Ceil(b.width + 2*pad))
+	height := int(math.Ceil(b.height + b.depth + 2*pad))
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("empty formula")
+	}
+
+	c := &canvas{
+		img: image.NewRGBA(image.Rect(0, 0, width, height)),
+		src: image.NewUniform(opts.Color),
+	}
+	b.draw(c, pad, pad+b.height)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, c.img); err != nil {
+		return nil, err
+	}
+	return &Image{
+		PNG:    buf.Bytes(),
+		Width:  float64(width) / opts.Scale,
+		Height: float64(height) / opts.Scale,
+		Depth:  (b.depth + pad) / opts.Scale,
+	}, nil
+}
+
+// canvas 绘制目标
+type canvas struct {
+	img *image.RGBA
+	src image.Image
+}
+
+// text 在基线位置绘制文本
+func (c *canvas) text(f font.Face, s string, x, y float64) {
+	d := font.Drawer{
+		Dst:  c.img,
+		Src:  c.src,
+		Face: f,
+		Dot:  fixed.Point26_6{X: fixed.Int26_6(x * 64), Y: fixed.Int26_6(y * 64)},
+	}
+	d.DrawString(s)
+}
+
+// rect 绘制实心矩形，支持亚像素坐标抗锯齿
+func (c *canvas) rect(x1, y1, x2, y2 float64) {
+	c.polygon([][2]float64{{x1, y1}, {x2, y1}, {x2, y2}, {x1, y2}})
+}
+
+// line 绘制指定粗细的线段
+func (c *canvas) line(x1, y1, x2, y2, width float64) {
+	dx, dy := x2-x1, y2-y1
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return
+	}
+	// 法向量偏移半个线宽得到四边形
+	nx, ny := -dy/length*width/2, dx/length*width/2
+	c.polygon([][2]float64{{x1 + nx, y1 + ny}, {x2 + nx, y2 + ny}, {x2 - nx, y2 - ny}, {x1 - nx, y1 - ny}})
+}
+
+func (c *canvas) polygon(points [][2]float64) {
+	bounds := c.img.Bounds()
+	r := vector.NewRasterizer(bounds.Dx(), bounds.Dy())
+	r.DrawOp = draw.Over
+	for i, p := range points {
+		if i == 0 {
+			r.MoveTo(float32(p[0]), float32(p[1]))
+		} else {
+			r.LineTo(float32(p[0]), float32(p[1]))
+		}
+	}
+	r.ClosePath()
+	r.Draw(c.img, bounds, c.src, image.Point{})
+}
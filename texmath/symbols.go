@@ -0,0 +1,132 @@
+package texmath
+
+// atomClass 原子类型，决定相邻符号之间的间距 (参考 TeX 的 Ord/Op/Bin/Rel/Open/Close/Punct)
+type atomClass int
+
+const (
+	classOrd atomClass = iota
+	classOp
+	classBin
+	classRel
+	classOpen
+	classClose
+	classPunct
+	classInner
+)
+
+type symbol struct {
+	text  string
+	class atomClass
+}
+
+// symbols 无参数命令到 Unicode 字符的映射
+var symbols = map[string]symbol{
+	// 小写希腊字母
+	"alpha": {"α", classOrd}, "beta": {"β", classOrd}, "gamma": {"γ", classOrd}, "delta": {"δ", classOrd},
+	"epsilon": {"ϵ", classOrd}, "varepsilon": {"ε", classOrd}, "zeta": {"ζ", classOrd}, "eta": {"η", classOrd},
+	"theta": {"θ", classOrd}, "vartheta": {"ϑ", classOrd}, "iota": {"ι", classOrd}, "kappa": {"κ", classOrd},
+	"lambda": {"λ", classOrd}, "mu": {"μ", classOrd}, "nu": {"ν", classOrd}, "xi": {"ξ", classOrd},
+	"pi": {"π", classOrd}, "varpi": {"ϖ", classOrd}, "rho": {"ρ", classOrd}, "varrho": {"ϱ", classOrd},
+	"sigma": {"σ", classOrd}, "varsigma": {"ς", classOrd}, "tau": {"τ", classOrd}, "upsilon": {"υ", classOrd},
+	"phi": {"ϕ", classOrd}, "varphi": {"φ", classOrd}, "chi": {"χ", classOrd}, "psi": {"ψ", classOrd},
+	"omega": {"ω", classOrd},
+
+	// 大写希腊字母
+	"Gamma": {"Γ", classOrd}, "Delta": {"Δ", classOrd}, "Theta": {"Θ", classOrd}, "Lambda": {"Λ", classOrd},
+	"Xi": {"Ξ", classOrd}, "Pi": {"Π", classOrd}, "Sigma": {"Σ", classOrd}, "Upsilon": {"Υ", classOrd},
+	"Phi": {"Φ", classOrd}, "Psi": {"Ψ", classOrd}, "Omega": {"Ω", classOrd},
+
+	// 二元运算符
+	"pm": {"±", classBin}, "mp": {"∓", classBin}, "times": {"×", classBin}, "div": {"÷", classBin},
+	"cdot": {"⋅", classBin}, "ast": {"∗", classBin}, "star": {"⋆", classBin}, "circ": {"∘", classBin},
+	"bullet": {"∙", classBin}, "oplus": {"⊕", classBin}, "ominus": {"⊖", classBin}, "otimes": {"⊗", classBin},
+	"cup": {"∪", classBin}, "cap": {"∩", classBin}, "setminus": {"∖", classBin}, "wedge": {"∧", classBin},
+	"land": {"∧", classBin}, "vee": {"∨", classBin}, "lor": {"∨", classBin},
+
+	// 关系符
+	"leq": {"≤", classRel}, "le": {"≤", classRel}, "geq": {"≥", classRel}, "ge": {"≥", classRel},
+	"neq": {"≠", classRel}, "ne": {"≠", classRel}, "approx": {"≈", classRel}, "equiv": {"≡", classRel},
+	"sim": {"∼", classRel}, "simeq": {"≃", classRel}, "cong": {"≅", classRel}, "propto": {"∝", classRel},
+	"ll": {"≪", classRel}, "gg": {"≫", classRel}, "in": {"∈", classRel}, "notin": {"∉", classRel},
+	"ni": {"∋", classRel}, "subset": {"⊂", classRel}, "supset": {"⊃", classRel}, "subseteq": {"⊆", classRel},
+	"supseteq": {"⊇", classRel}, "mid": {"∣", classRel}, "parallel": {"∥", classRel}, "perp": {"⊥", classRel},
+	"to": {"→", classRel}, "rightarrow": {"→", classRel}, "leftarrow": {"←", classRel}, "gets": {"←", classRel},
+	"leftrightarrow": {"↔", classRel}, "Rightarrow": {"⇒", classRel}, "Leftarrow": {"⇐", classRel},
+	"Leftrightarrow": {"⇔", classRel}, "implies": {"⟹", classRel}, "iff": {"⟺", classRel},
+	"mapsto": {"↦", classRel}, "longrightarrow": {"⟶", classRel}, "uparrow": {"↑", classRel},
+	"downarrow": {"↓", classRel}, "coloneqq": {"≔", classRel},
+
+	// 普通符号
+	"infty": {"∞", classOrd}, "partial": {"∂", classOrd}, "nabla": {"∇", classOrd}, "forall": {"∀", classOrd},
+	"exists": {"∃", classOrd}, "nexists": {"∄", classOrd}, "emptyset": {"∅", classOrd}, "varnothing": {"∅", classOrd},
+	"neg": {"¬", classOrd}, "lnot": {"¬", classOrd}, "angle": {"∠", classOrd}, "triangle": {"△", classOrd},
+	"hbar": {"ℏ", classOrd}, "ell": {"ℓ", classOrd}, "Re": {"ℜ", classOrd}, "Im": {"ℑ", classOrd},
+	"aleph": {"ℵ", classOrd}, "prime": {"′", classOrd}, "degree": {"°", classOrd},
+	"ldots": {"…", classInner}, "dots": {"…", classInner}, "cdots": {"⋯", classInner},
+	"vdots": {"⋮", classOrd}, "ddots": {"⋱", classOrd}, "therefore": {"∴", classRel}, "because": {"∵", classRel},
+
+	// 定界符
+	"{": {"{", classOpen}, "}": {"}", classClose}, "lbrace": {"{", classOpen}, "rbrace": {"}", classClose},
+	"langle": {"⟨", classOpen}, "rangle": {"⟩", classClose}, "lfloor": {"⌊", classOpen}, "rfloor": {"⌋", classClose},
+	"lceil": {"⌈", classOpen}, "rceil": {"⌉", classClose}, "vert": {"|", classOrd}, "|": {"‖", classOrd},
+	"Vert": {"‖", classOrd}, "lvert": {"|", classOpen}, "rvert": {"|", classClose},
+
+	// 转义字符
+	"%": {"%", classOrd}, "$": {"$", classOrd}, "&": {"&", classOrd}, "#": {"#", classOrd}, "_": {"_", classOrd},
+}
+
+// bigOperators 大型运算符，limits 表示显示模式下上下限放在正上下方
+var bigOperators = map[string]struct {
+	text   string
+	limits bool
+}{
+	"sum": {"∑", true}, "prod": {"∏", true}, "coprod": {"∐", true},
+	"bigcup": {"⋃", true}, "bigcap": {"⋂", true}, "bigoplus": {"⨁", true}, "bigotimes": {"⨂", true},
+	"int": {"∫", false}, "iint": {"∬", false}, "iiint": {"∭", false}, "oint": {"∮", false},
+}
+
+// functions 函数名命令，以正体输出，limits 含义同上
+var functions = map[string]bool{
+	"sin": false, "cos": false, "tan": false, "cot": false, "sec": false, "csc": false,
+	"arcsin": false, "arccos": false, "arctan": false, "sinh": false, "cosh": false, "tanh": false,
+	"log": false, "ln": false, "lg": false, "exp": false, "arg": false, "deg": false, "dim": false,
+	"ker": false, "hom": false, "Pr": true, "det": true, "gcd": true,
+	"lim": true, "liminf": true, "limsup": true, "max": true, "min": true, "sup": true, "inf": true,
+	"argmax": true, "argmin": true,
+}
+
+// spaces 间距命令，单位 em
+var spaces = map[string]float64{
+	",": 3.0 / 18, ":": 4.0 / 18, ">": 4.0 / 18, ";": 5.0 / 18, "!": -3.0 / 18, " ": 1.0 / 3,
+	"quad": 1, "qquad": 2, "enspace": 0.5, "thinspace": 3.0 / 18,
+}
+
+// accents 重音命令对应的装饰字符，空字符串表示使用横线
+var accents = map[string]string{
+	"hat": "ˆ", "widehat": "ˆ", "tilde": "˜", "widetilde": "˜", "dot": "˙", "ddot": "¨",
+	"vec": "→", "overrightarrow": "→", "bar": "", "overline": "",
+}
+
+// blackboard \mathbb 字母映射
+var blackboard = map[rune]string{
+	'C': "ℂ", 'H': "ℍ", 'N': "ℕ", 'P': "ℙ", 'Q': "ℚ", 'R': "ℝ", 'Z': "ℤ",
+}
+
+// calligraphic \mathcal 字母映射 (Unicode 花体区间存在空洞，需逐个指定)
+var calligraphic = map[rune]string{
+	'B': "ℬ", 'E': "ℰ", 'F': "ℱ", 'H': "ℋ", 'I': "ℐ", 'L': "ℒ", 'M': "ℳ", 'R': "ℛ",
+}
+
+// mapAlphabet 将字母映射到 Unicode 数学字母区，base 为 'A' 对应的码点
+func mapAlphabet(r rune, table map[rune]string, base rune) string {
+	if s, ok := table[r]; ok {
+		return s
+	}
+	switch {
+	case r >= 'A' && r <= 'Z':
+		return string(base + (r - 'A'))
+	case r >= 'a' && r <= 'z' && base != 0:
+		return string(base + 26 + (r - 'a'))
+	}
+	return string(r)
+}
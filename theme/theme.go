@@ -0,0 +1,164 @@
+// Package theme 排版主题
+// 主题决定渲染时写入内联样式的配色 (提示框等) 与标题装饰，通过 THEME 环境变量选择。
+package theme
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Callout 提示框样式
+type Callout struct {
+	Icon       string
+	Label      string // 未指定标题时的默认标题
+	Color      string // 边框与标题颜色
+	Background string
+}
+
+// Theme 排版主题
+type Theme struct {
+	Name     string
+	Primary  string             // 主色
+	Callouts map[string]Callout // 提示框类型 -> 样式，缺失的类型使用默认主题
+	// Headings 标题装饰模板 (html/template)，标题级别 -> 模板，缺失的级别使用默认主题。
+	// 模板可用字段：.Level 级别、.ID 锚点、.Number 编号 (未开启时为空)、.Content 标题内容、.Color 主色
+	Headings map[int]string
+}
+
+// Default 默认主题，主色与预览页引用块一致
+var Default = &Theme{
+	Name:    "default",
+	Primary: "#42b983",
+	Callouts: map[string]Callout{
+		"note":      {"📝", "注意", "#448aff", "#ecf3ff"},
+		"abstract":  {"📄", "摘要", "#00b0ff", "#e5f7ff"},
+		"info":      {"ℹ️", "信息", "#00b8d4", "#e5f8fb"},
+		"todo":      {"☑️", "待办", "#00b8d4", "#e5f8fb"},
+		"tip":       {"💡", "提示", "#00bfa5", "#e5f8f6"},
+		"important": {"📌", "重要", "#7c4dff", "#f2edff"},
+		"success":   {"✅", "成功", "#00c853", "#e5f9ed"},
+		"question":  {"❓", "问题", "#64dd17", "#eff9e8"},
+		"warning":   {"⚠️", "警告", "#ff9100", "#fff4e5"},
+		"caution":   {"🚨", "当心", "#ff5252", "#ffeeee"},
+		"failure":   {"❌", "失败", "#ff5252", "#ffeeee"},
+		"danger":    {"⛔", "危险", "#ff1744", "#ffe8ec"},
+		"bug":       {"🐛", "缺陷", "#f50057", "#ffe5ee"},
+		"example":   {"📋", "示例", "#651fff", "#f0e9ff"},
+		"quote":     {"💬", "引用", "#9e9e9e", "#f5f5f5"},
+	},
+	Headings: map[int]string{
+		2: `<h2 id="{{.ID}}" style="margin: 32px 0 16px; padding-bottom: 6px; font-size: 20px; font-weight: bold; line-height: 1.4; color: #34495e; border-bottom: 2px solid {{.Color}};">` +
+			`{{if .Number}}<span style="margin-right: 8px; color: {{.Color}};">{{.Number}}</span>{{end}}{{.Content}}</h2>`,
+		3: `<h3 id="{{.ID}}" style="margin: 24px 0 12px; padding-left: 10px; font-size: 18px; font-weight: bold; line-height: 1.4; color: #34495e; border-left: 4px solid {{.Color}};">` +
+			`{{if .Number}}<span style="margin-right: 6px; color: {{.Color}};">{{.Number}}</span>{{end}}{{.Content}}</h3>`,
+		4: `<h4 id="{{.ID}}" style="margin: 18px 0 10px; font-size: 16px; font-weight: bold; color: #34495e;">` +
+			`{{if .Number}}<span style="margin-right: 6px; color: {{.Color}};">{{.Number}}</span>{{end}}{{.Content}}</h4>`,
+	},
+}
+
+// GitHub 配色与 GitHub 的 Alerts 一致
+var GitHub = &Theme{
+	Name:    "github",
+	Primary: "#0969da",
+	Callouts: map[string]Callout{
+		"note":      {"ℹ️", "注意", "#0969da", "#ddf4ff"},
+		"tip":       {"💡", "提示", "#1a7f37", "#dafbe1"},
+		"important": {"📌", "重要", "#8250df", "#fbefff"},
+		"warning":   {"⚠️", "警告", "#9a6700", "#fff8c5"},
+		"caution":   {"🚨", "当心", "#cf222e", "#ffebe9"},
+	},
+	Headings: map[int]string{
+		2: `<h2 id="{{.ID}}" style="margin: 24px 0 16px; padding-bottom: 8px; font-size: 20px; font-weight: 600; line-height: 1.25; color: #1f2328; border-bottom: 1px solid #d0d7de;">` +
+			`{{if .Number}}<span style="margin-right: 8px; color: {{.Color}};">{{.Number}}</span>{{end}}{{.Content}}</h2>`,
+		3: `<h3 id="{{.ID}}" style="margin: 24px 0 16px; font-size: 17px; font-weight: 600; line-height: 1.25; color: #1f2328;">` +
+			`{{if .Number}}<span style="margin-right: 6px; color: {{.Color}};">{{.Number}}</span>{{end}}{{.Content}}</h3>`,
+	},
+}
+
+// Toutiao 头条号：编辑器只保留一级标题，H2 输出为 h1，H3 及以下输出为加粗段落，粘贴后不丢失层级
+var Toutiao = &Theme{
+	Name:    "toutiao",
+	Primary: "#f04142",
+	Headings: map[int]string{
+		2: `<h1 id="{{.ID}}">{{if .Number}}{{.Number}} {{end}}{{.Content}}</h1>`,
+		3: `<p id="{{.ID}}"><strong>{{if .Number}}{{.Number}} {{end}}{{.Content}}</strong></p>`,
+		4: `<p id="{{.ID}}"><strong>{{if .Number}}{{.Number}} {{end}}{{.Content}}</strong></p>`,
+		5: `<p id="{{.ID}}"><strong>{{.Content}}</strong></p>`,
+		6: `<p id="{{.ID}}"><strong>{{.Content}}</strong></p>`,
+	},
+}
+
+var themes = map[string]*Theme{
+	Default.Name: Default,
+	GitHub.Name:  GitHub,
+	Toutiao.Name: Toutiao,
+}
+
+// Get 按名称获取主题，未知名称返回默认主题
+func Get(name string) *Theme {
+	if t, ok := themes[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return t
+	}
+	return Default
+}
+
+// Names 所有可用主题名
+func Names() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// calloutAliases 提示框类型别名，兼容 Obsidian、GitHub 与 Hugo 主题 (LoveIt/FixIt) 的写法
+var calloutAliases = map[string]string{
+	"summary":   "abstract",
+	"tldr":      "abstract",
+	"hint":      "tip",
+	"check":     "success",
+	"done":      "success",
+	"help":      "question",
+	"faq":       "question",
+	"attention": "warning",
+	"fail":      "failure",
+	"missing":   "failure",
+	"error":     "danger",
+	"cite":      "quote",
+}
+
+// CalloutKind 规范化提示框类型，未知类型返回 false
+func CalloutKind(kind string) (string, bool) {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	if alias, ok := calloutAliases[kind]; ok {
+		kind = alias
+	}
+	_, ok := Default.Callouts[kind]
+	return kind, ok
+}
+
+// Heading 获取标题装饰模板，缺失的级别依次使用默认主题与无装饰模板
+func (t *Theme) Heading(level int) string {
+	if tmpl, ok := t.Headings[level]; ok {
+		return tmpl
+	}
+	if tmpl, ok := Default.Headings[level]; ok {
+		return tmpl
+	}
+	// html/template 不允许在标签名中使用变量，按级别生成无装饰模板
+	return fmt.Sprintf(`<h%d id="{{.ID}}">{{if .Number}}{{.Number}} {{end}}{{.Content}}</h%d>`, level, level)
+}
+
+// Callout 获取提示框样式，未知类型按 note 处理
+func (t *Theme) Callout(kind string) Callout {
+	kind, ok := CalloutKind(kind)
+	if !ok {
+		kind = "note"
+	}
+	if style, ok := t.Callouts[kind]; ok {
+		return style
+	}
+	return Default.Callouts[kind]
+}
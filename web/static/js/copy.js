@@ -1,18 +1,31 @@
 // 处理发布（上传图片并复制）
-async function handlePublish() {
+// 兼容性检查存在错误时服务端拒绝发布，用户确认后带 force=1 重新发布
+async function handlePublish(force = false) {
     const btn = document.querySelector('.btn-publish');
     if (btn.classList.contains('loading')) return;
 
     showLoading(btn, '正在上传图片...');
     const articleId = document.getElementById('articleId').value;
+    const select = document.getElementById('publishProfile');
+    const params = new URLSearchParams();
+    if (select) params.set('profile', select.value);
+    if (force) params.set('force', '1');
+    let retry = false;
 
     try {
-        const response = await fetch(`/api/publish/${articleId}`, {
+        const response = await fetch(`/api/publish/${articleId}?${params}`, {
             method: 'POST'
         });
         const data = await response.json();
 
-        if (data.success) {
+        if (data.lint) {
+            const errors = data.lint.filter(f => f.severity === 'error')
+                .map(f => `• ${f.line ? `第 ${f.line} 行：` : ''}${f.message}`);
+            retry = confirm(`⚠️ 微信兼容性检查发现 ${errors.length} 个错误：\n\n${errors.join('\n')}\n\n仍要发布吗？`);
+            if (!retry) {
+                showNotification('已取消发布，请根据页面顶部的检查结果修改后再试。', 'warning');
+            }
+        } else if (data.success) {
             // 严格检查：如果有错误日志，则不允许视为成功，不自动复制
             if (data.logs && data.logs.length > 0) {
                 let errorMsg = '⚠️ 发布中断：检测到以下图片上传失败，请修复后再试：\n\n' + data.logs.join('\n');
@@ -21,18 +34,26 @@ async function handlePublish() {
                 return; // 终止后续操作
             }
 
+            const profile = data.profile || { label: '微信公众号', format: 'html' };
+            if (profile.format === 'markdown') {
+                // Markdown 平台 (掘金、CSDN)：复制短代码已展开、图片为 CDN 地址的 Markdown
+                await copyText(data.content.markdown);
+                let msg = `✅ 发布成功！\n`;
+                if (data.uploaded && data.uploaded.length > 0) {
+                    msg += `🚀 已上传 ${data.uploaded.length} 张图片到 GitHub\n`;
+                }
+                msg += `\nMarkdown 已复制到剪贴板，可粘贴到${profile.label}的 Markdown 编辑器。`;
+                showNotification(msg, 'success');
+                return;
+            }
+
             // 新策略：直接替换 articleContent 然后用 copyArticle 的逻辑
             const articleContent = document.getElementById('articleContent');
             const originalHTML = articleContent.innerHTML;
 
-            // 替换为 CDN 版本
+            // 替换为 CDN 版本（外链已在服务端按链接策略处理，不再做前端格式化）
             articleContent.innerHTML = data.content.html;
 
-            // 应用格式化
-            if (window.formatWechatContent) {
-                window.formatWechatContent(articleContent);
-            }
-
             // 等待浏览器完成渲染（关键）
             await new Promise(resolve => setTimeout(resolve, 100));
 
@@ -45,11 +66,8 @@ async function handlePublish() {
             document.execCommand('copy');
             selection.removeAllRanges();
 
-            // 恢复原内容
+            // 恢复原内容（originalHTML 已是格式化后的结果）
             articleContent.innerHTML = originalHTML;
-            if (window.formatWechatContent) {
-                window.formatWechatContent(articleContent);
-            }
 
             let msg = '✅ 发布成功！\n';
             if (data.uploaded && data.uploaded.length > 0) {
@@ -57,7 +75,14 @@ async function handlePublish() {
             } else {
                 msg += '📝 没有发现需要上传的图片（或已全部存在）\n';
             }
-            msg += '\n含 CDN 图片链接的内容已复制到剪贴板。';
+            msg += summarizeLinks(data.links);
+            msg += summarizeCovers(data);
+            if (data.sanitized && data.sanitized.length > 0) {
+                const count = data.sanitized.reduce((sum, issue) => sum + issue.count, 0);
+                msg += `🧹 已清理 ${count} 处微信不支持的 HTML\n`;
+                console.warn('HTML 清理:', data.sanitized);
+            }
+            msg += `\n含 CDN 图片链接的内容已复制到剪贴板，可粘贴到${profile.label}编辑器。`;
             showNotification(msg, 'success');
         } else {
             showNotification('❌ 发布失败: ' + data.error, 'error');
@@ -67,6 +92,48 @@ async function handlePublish() {
     } finally {
         hideLoading(btn, '🚀 发布/复制');
     }
+
+    if (retry) {
+        handlePublish(true);
+    }
+}
+
+// 生成封面的结果，封面需在公众号后台手动上传，地址同时输出到控制台
+function summarizeCovers(data) {
+    if (data.coverError) {
+        console.warn(data.coverError);
+        return `⚠️ ${data.coverError}\n`;
+    }
+    if (!data.covers || Object.keys(data.covers).length === 0) return '';
+    console.log('封面:', data.covers);
+    return `🖼️ 已生成并上传封面：${Object.values(data.covers).join('、')}\n`;
+}
+
+// 汇总发布时链接的处理结果，例如 "🔗 链接：2 个转为文末引用，1 个转为纯文本"
+function summarizeLinks(links) {
+    if (!links || links.length === 0) return '';
+    const labels = {
+        keep: '保留',
+        footnote: '转为文末引用',
+        plain: '转为纯文本',
+        qrcode: '转为二维码',
+        drop: '已删除'
+    };
+    const counts = {};
+    links.forEach(link => {
+        counts[link.action] = (counts[link.action] || 0) + 1;
+    });
+    const parts = Object.keys(labels)
+        .filter(action => counts[action])
+        .map(action => `${counts[action]} 个${labels[action]}`);
+    let msg = `🔗 链接：${parts.join('，')}\n`;
+
+    const failed = links.filter(link => link.error);
+    if (failed.length > 0) {
+        msg += `⚠️ ${failed.length} 个二维码生成失败，已转为纯文本\n`;
+        console.warn('二维码生成失败:', failed);
+    }
+    return msg;
 }
 
 // 辅助函数：复制 HTML 内容（复用 copyArticle 的部分逻辑，但这里不仅要复制 HTML，
@@ -104,6 +171,25 @@ async function copyToClipboard(htmlString) {
     }
 }
 
+// 复制纯文本，剪贴板 API 不可用时 (非 localhost 的 http 页面) 退回 execCommand
+async function copyText(text) {
+    if (navigator.clipboard && window.isSecureContext) {
+        await navigator.clipboard.writeText(text);
+        return;
+    }
+    const textarea = document.createElement('textarea');
+    textarea.value = text;
+    textarea.style.position = 'absolute';
+    textarea.style.left = '-9999px';
+    document.body.appendChild(textarea);
+    try {
+        textarea.select();
+        document.execCommand('copy');
+    } finally {
+        document.body.removeChild(textarea);
+    }
+}
+
 function showLoading(btn, text) {
     btn.classList.add('loading');
     btn.dataset.originalText = btn.innerText;
@@ -126,6 +212,10 @@ async function copyArticle() {
         return;
     }
 
+    // 复制前去掉敏感词高亮，复制后恢复
+    const originalHTML = content.innerHTML;
+    unwrapSensitiveWords(content);
+
     try {
         // 使用 Selection API 复制富文本（包含样式）
         const range = document.createRange();
@@ -144,9 +234,18 @@ async function copyArticle() {
         showNotification('✅ 复制成功！\n\n可直接粘贴到微信公众号后台。\n⚠️ 注意：图片需要手动上传。', 'success');
     } catch (err) {
         showNotification('❌ 复制失败\n\n' + err.message + '\n\n请尝试手动选中文章内容后按 Cmd+C 复制。', 'error');
+    } finally {
+        content.innerHTML = originalHTML;
     }
 }
 
+// 去掉预览中的敏感词高亮标记，只保留文字
+function unwrapSensitiveWords(container) {
+    container.querySelectorAll('.sensitive-word').forEach(mark => {
+        mark.replaceWith(document.createTextNode(mark.textContent));
+    });
+}
+
 // 获取内联样式（从 wechat.css 提取核心样式）
 function getInlineStyles() {
     return `
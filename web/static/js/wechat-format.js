@@ -1,4 +1,5 @@
 // 微信公众号格式化工具：自动处理外链为文末引用
+// 仅用于预览；发布时由服务端按链接策略 (LINK_POLICY) 处理链接
 document.addEventListener('DOMContentLoaded', function () {
     processLinks();
 });
@@ -15,8 +16,10 @@ function processLinks(container) {
     const links = content.querySelectorAll('a');
     if (links.length === 0) return;
 
+    // 服务端渲染的脚注列表已占用 [1]..[n]，外链引用接着编号并合并到同一列表
+    const footnotes = content.querySelector('.footnotes-section');
     const references = [];
-    let index = 1;
+    let index = footnotes ? footnotes.querySelectorAll('li').length + 1 : 1;
 
     links.forEach(link => {
         const href = link.getAttribute('href');
@@ -54,11 +57,19 @@ function processLinks(container) {
 
     // 如果有引用，在文末添加引用列表
     if (references.length > 0) {
-        appendReferences(content, references);
+        appendReferences(content, references, footnotes);
     }
 }
 
-function appendReferences(container, references) {
+function appendReferences(container, references, footnotes) {
+    // 已有脚注列表时合并进去，避免文末出现两套编号
+    if (footnotes) {
+        footnotes.querySelector('h3').textContent = '注释与引用';
+        const footnoteList = footnotes.querySelector('ul');
+        references.forEach(ref => footnoteList.appendChild(createReferenceItem(ref)));
+        return;
+    }
+
     // 创建引用容器
     const refSection = document.createElement('div');
     refSection.className = 'references-section';
@@ -79,30 +90,32 @@ function appendReferences(container, references) {
     list.style.paddingLeft = '0';
     list.style.listStyle = 'none';
 
-    references.forEach(ref => {
-        const item = document.createElement('li');
-        item.style.fontSize = '14px';
-        item.style.color = '#666';
-        item.style.marginBottom = '8px';
-        item.style.lineHeight = '1.6';
-        item.style.display = 'block'; // 覆盖 wechat.css 可能的 list-item
-
-        // 格式：[1] 链接文本: https://...
-        // 使用 span class="li-text" 包裹，防止微信编辑器自动换行
-        item.innerHTML = `
-            <span class="li-text">
-                <span style="color: #999; margin-right: 5px;">[${ref.index}]</span>
-                ${escapeHtml(ref.text)}: 
-                <span style="color: #333; word-break: break-all;">${ref.href}</span>
-            </span>
-        `;
-        list.appendChild(item);
-    });
+    references.forEach(ref => list.appendChild(createReferenceItem(ref)));
 
     refSection.appendChild(list);
     container.appendChild(refSection);
 }
 
+function createReferenceItem(ref) {
+    const item = document.createElement('li');
+    item.style.fontSize = '14px';
+    item.style.color = '#666';
+    item.style.marginBottom = '8px';
+    item.style.lineHeight = '1.6';
+    item.style.display = 'block'; // 覆盖 wechat.css 可能的 list-item
+
+    // 格式：[1] 链接文本: https://...
+    // 使用 span class="li-text" 包裹，防止微信编辑器自动换行
+    item.innerHTML = `
+        <span class="li-text">
+            <span style="color: #999; margin-right: 5px;">[${ref.index}]</span>
+            ${escapeHtml(ref.text)}: 
+            <span style="color: #333; word-break: break-all;">${ref.href}</span>
+        </span>
+    `;
+    return item;
+}
+
 function escapeHtml(text) {
     const div = document.createElement('div');
     div.textContent = text;